@@ -133,7 +133,7 @@ func runToolsCallClient(ctx context.Context, serverURL string, _ map[string]any)
 func runElicitationDefaultsClient(ctx context.Context, serverURL string, _ map[string]any) error {
 	elicitationHandler := func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
 		return &mcp.ElicitResult{
-			Action:  "accept",
+			Action:  mcp.ElicitActionAccept,
 			Content: map[string]any{},
 		}, nil
 	}
@@ -34,7 +34,7 @@ func main() {
 			// In a real application, this would prompt the user for input
 			// Here we simulate user providing configuration data
 			return &mcp.ElicitResult{
-				Action: "accept",
+				Action: mcp.ElicitActionAccept,
 				Content: map[string]any{
 					"serverEndpoint": "https://api.example.com",
 					"maxRetries":     float64(3),
@@ -68,7 +68,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	if result.Action == "accept" {
+	if result.Accepted() {
 		fmt.Printf("Configuration received: Endpoint: %v, Max Retries: %.0f, Logs: %v\n",
 			result.Content["serverEndpoint"],
 			result.Content["maxRetries"],
@@ -537,6 +537,31 @@ func (k knowledgeBase) ReadGraph(ctx context.Context, req *mcp.CallToolRequest,
 	return &res, graph, nil
 }
 
+// GraphRecord is a line of the NDJSON form of the knowledge graph, holding
+// either an entity or a relation.
+type GraphRecord struct {
+	Type string `json:"type"` // "entity" or "relation"
+	*Entity
+	*Relation
+}
+
+// GraphRecords returns the entities and then the relations of the knowledge
+// graph, for serving the graph as an NDJSON resource.
+func (k knowledgeBase) GraphRecords(ctx context.Context, req *mcp.ReadResourceRequest) ([]GraphRecord, error) {
+	graph, err := k.loadGraph()
+	if err != nil {
+		return nil, err
+	}
+	var records []GraphRecord
+	for i := range graph.Entities {
+		records = append(records, GraphRecord{Type: "entity", Entity: &graph.Entities[i]})
+	}
+	for i := range graph.Relations {
+		records = append(records, GraphRecord{Type: "relation", Relation: &graph.Relations[i]})
+	}
+	return records, nil
+}
+
 func (k knowledgeBase) SearchNodes(ctx context.Context, req *mcp.CallToolRequest, args SearchNodesArgs) (*mcp.CallToolResult, KnowledgeGraph, error) {
 	var res mcp.CallToolResult
 
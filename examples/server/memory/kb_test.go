@@ -647,3 +647,40 @@ func TestMCPResponseFormat(t *testing.T) {
 		t.Errorf("expected Content[0] to be TextContent")
 	}
 }
+
+func TestGraphRecords(t *testing.T) {
+	ctx := context.Background()
+	kb := knowledgeBase{s: &memoryStore{}}
+	if _, _, err := kb.CreateEntities(ctx, nil, CreateEntitiesArgs{
+		Entities: []Entity{{Name: "Alice", EntityType: "Person"}, {Name: "Bob", EntityType: "Person"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := kb.CreateRelations(ctx, nil, CreateRelationsArgs{
+		Relations: []Relation{{From: "Alice", To: "Bob", RelationType: "knows"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := mcp.NDJSONResourceHandler(kb.GraphRecords)
+	res, err := handler(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "memory://graph"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for r, err := range mcp.NDJSONRecords[GraphRecord](res.Contents[0]) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch r.Type {
+		case "entity":
+			got = append(got, "entity "+r.Name)
+		case "relation":
+			got = append(got, "relation "+r.From+" "+r.RelationType+" "+r.To)
+		}
+	}
+	want := []string{"entity Alice", "entity Bob", "relation Alice knows Bob"}
+	if !slices.Equal(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+}
@@ -128,6 +128,14 @@ func main() {
 		Name:        "open_nodes",
 		Description: "Retrieve specific nodes by name",
 	}, kb.OpenNodes)
+	// The graph is also available as a resource, as NDJSON, with one entity
+	// or relation per line.
+	server.AddResource(&mcp.Resource{
+		URI:         "memory://graph",
+		Name:        "graph",
+		Description: "The entire knowledge graph, as one entity or relation per line",
+		MIMEType:    mcp.NDJSONMIMEType,
+	}, mcp.NDJSONResourceHandler(kb.GraphRecords))
 
 	// Start server with appropriate transport
 	if *httpAddr != "" {
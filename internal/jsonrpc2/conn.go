@@ -172,7 +172,7 @@ func (s *inFlightState) shuttingDown(errClosing error) error {
 type incomingRequest struct {
 	*Request // the request being processed
 	ctx      context.Context
-	cancel   context.CancelFunc
+	cancel   context.CancelCauseFunc
 }
 
 // Reader abstracts the transport mechanics from the JSON RPC protocol.
@@ -361,6 +361,20 @@ func (c *Connection) Retire(ac *AsyncCall, err error) {
 	})
 }
 
+// RetireAll stops tracking all outgoing calls, reporting err as their
+// terminal error, and returns the IDs of the calls retired.
+func (c *Connection) RetireAll(err error) []ID {
+	var ids []ID
+	c.updateInFlight(func(s *inFlightState) {
+		for id, ac := range s.outgoingCalls {
+			delete(s.outgoingCalls, id)
+			ac.retire(&Response{ID: id, Error: err})
+			ids = append(ids, id)
+		}
+	})
+	return ids
+}
+
 // Async, signals that the current jsonrpc2 request may be handled
 // asynchronously to subsequent requests, when ctx is the request context.
 //
@@ -453,12 +467,34 @@ func (ac *AsyncCall) Await(ctx context.Context, result any) error {
 // will not cause any messages that have not arrived yet with that ID to be
 // cancelled.
 func (c *Connection) Cancel(id ID) {
+	c.CancelCause(id, nil)
+}
+
+// CancelCause is like [Connection.Cancel], but sets the cause of the
+// cancellation, as reported by [context.Cause], to cause. If cause is nil, it
+// is [context.Canceled].
+func (c *Connection) CancelCause(id ID, cause error) {
 	var req *incomingRequest
 	c.updateInFlight(func(s *inFlightState) {
 		req = s.incomingByID[id]
 	})
 	if req != nil {
-		req.cancel()
+		req.cancel(cause)
+	}
+}
+
+// CancelAll cancels the Contexts passed to the Handle calls for all inbound
+// messages currently in flight, as if [Connection.Cancel] had been called
+// with each of their IDs.
+func (c *Connection) CancelAll() {
+	var reqs []*incomingRequest
+	c.updateInFlight(func(s *inFlightState) {
+		for _, r := range s.incomingByID {
+			reqs = append(reqs, r)
+		}
+	})
+	for _, r := range reqs {
+		r.cancel(nil)
 	}
 }
 
@@ -554,7 +590,7 @@ func (c *Connection) readIncoming(ctx context.Context, reader Reader, preempter
 		// response either, so parked handlers have nothing useful left to do.
 		// Mirrors the equivalent cleanup on write failure.
 		for _, r := range s.incomingByID {
-			r.cancel()
+			r.cancel(nil)
 		}
 	})
 }
@@ -564,7 +600,7 @@ func (c *Connection) readIncoming(ctx context.Context, reader Reader, preempter
 func (c *Connection) acceptRequest(ctx context.Context, msg *Request, preempter Preempter) {
 	// In theory notifications cannot be cancelled, but we build them a cancel
 	// context anyway.
-	reqCtx, cancel := context.WithCancel(ctx)
+	reqCtx, cancel := context.WithCancelCause(ctx)
 	req := &incomingRequest{
 		Request: msg,
 		ctx:     reqCtx,
@@ -734,7 +770,7 @@ func (c *Connection) processResult(from any, req *incomingRequest, result any, e
 	}
 
 	// Cancel the request to free any associated resources.
-	req.cancel()
+	req.cancel(nil)
 	c.updateInFlight(func(s *inFlightState) {
 		if s.incoming == 0 {
 			panic("jsonrpc2: processResult called when incoming count is already zero")
@@ -777,7 +813,7 @@ func (c *Connection) write(ctx context.Context, msg Message) error {
 			if s.writeErr == nil {
 				s.writeErr = err
 				for _, r := range s.incomingByID {
-					r.cancel()
+					r.cancel(nil)
 				}
 			}
 		})
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 
 	internaljson "github.com/modelcontextprotocol/go-sdk/internal/json"
 )
@@ -39,6 +40,26 @@ func MakeID(v any) (ID, error) {
 	return ID{}, fmt.Errorf("%w: invalid ID type %T", ErrParse, v)
 }
 
+// DecodeID decodes the JSON form of a Request identifier.
+//
+// Unlike decoding into an any and calling [MakeID], DecodeID preserves the
+// precision of integer IDs that cannot be represented exactly by a float64,
+// such as those beyond 2^53.
+func DecodeID(data json.RawMessage) (ID, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return ID{}, nil
+	}
+	if n, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		return Int64ID(n), nil
+	}
+	var v any
+	if err := internaljson.Unmarshal(data, &v); err != nil {
+		return ID{}, fmt.Errorf("%w: invalid ID: %v", ErrParse, err)
+	}
+	return MakeID(v)
+}
+
 // Message is the interface to all jsonrpc2 message types.
 // They share no common functionality, but are a closed set of concrete types
 // that are allowed to implement this interface. The message types are *Request
@@ -176,7 +197,7 @@ func EncodeIndent(msg Message, prefix, indent string) ([]byte, error) {
 // when its value is the empty string (see go-sdk#976).
 type wireDecode struct {
 	VersionTag string          `json:"jsonrpc"`
-	ID         any             `json:"id,omitempty"`
+	ID         json.RawMessage `json:"id,omitempty"`
 	Method     json.RawMessage `json:"method"`
 	Params     json.RawMessage `json:"params,omitempty"`
 	Result     json.RawMessage `json:"result,omitempty"`
@@ -191,7 +212,7 @@ func DecodeMessage(data []byte) (Message, error) {
 	if msg.VersionTag != wireVersion {
 		return nil, fmt.Errorf("invalid message version tag %q; expected %q", msg.VersionTag, wireVersion)
 	}
-	id, err := MakeID(msg.ID)
+	id, err := DecodeID(msg.ID)
 	if err != nil {
 		return nil, err
 	}
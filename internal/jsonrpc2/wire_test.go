@@ -34,6 +34,14 @@ func TestWireMessage(t *testing.T) {
 		name:    "numerical id",
 		msg:     newCall(1, "poke", nil),
 		encoded: []byte(`{"jsonrpc":"2.0","id":1,"method":"poke"}`),
+	}, {
+		name:    "uuid id",
+		msg:     newCall("9b2f8f4e-7c1d-4b4a-9b1e-2f6d0c7a1e55", "poke", nil),
+		encoded: []byte(`{"jsonrpc":"2.0","id":"9b2f8f4e-7c1d-4b4a-9b1e-2f6d0c7a1e55","method":"poke"}`),
+	}, {
+		name:    "large numerical id",
+		msg:     newResponse(int64(1<<53+1), "pong", nil),
+		encoded: []byte(`{"jsonrpc":"2.0","id":9007199254740993,"result":"pong"}`),
 	}, {
 		// originally reported in #39719, this checks that result is not present if
 		// it is an error response
@@ -147,6 +155,36 @@ func TestDecodeIDOnlyMessageIsResponse(t *testing.T) {
 	}
 }
 
+func TestDecodeID(t *testing.T) {
+	for _, test := range []struct {
+		data string
+		want jsonrpc2.ID
+	}{
+		{``, jsonrpc2.ID{}},
+		{`null`, jsonrpc2.ID{}},
+		{`"abc"`, jsonrpc2.StringID("abc")},
+		{`"123"`, jsonrpc2.StringID("123")},
+		{`0`, jsonrpc2.Int64ID(0)},
+		{`-7`, jsonrpc2.Int64ID(-7)},
+		{`9007199254740993`, jsonrpc2.Int64ID(1<<53 + 1)},
+		{`9223372036854775807`, jsonrpc2.Int64ID(1<<63 - 1)},
+	} {
+		got, err := jsonrpc2.DecodeID(json.RawMessage(test.data))
+		if err != nil {
+			t.Errorf("DecodeID(%s) failed: %v", test.data, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("DecodeID(%s) = %v, want %v", test.data, got.Raw(), test.want.Raw())
+		}
+	}
+	for _, data := range []string{`true`, `{}`, `[1]`} {
+		if _, err := jsonrpc2.DecodeID(json.RawMessage(data)); err == nil {
+			t.Errorf("DecodeID(%s) succeeded unexpectedly", data)
+		}
+	}
+}
+
 func checkJSON(t *testing.T, got, want []byte) {
 	// compare the compact form, to allow for formatting differences
 	g := &bytes.Buffer{}
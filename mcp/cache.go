@@ -5,6 +5,13 @@
 package mcp
 
 import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -97,3 +104,208 @@ func cachedListResult[P cursorParams, R CacheableResult](cache *methodCache[R],
 	}
 	return cache.get(key)
 }
+
+// idempotencyCache holds the results of a session's tool calls by
+// idempotency key, as configured by [ServerOptions.IdempotencyKeyTTL].
+type idempotencyCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	done    chan struct{} // closed when res and err are set
+	res     *CallToolResult
+	err     error
+	expires time.Time // zero while the call is in progress
+}
+
+// do returns the cached result for key, if any. Otherwise it calls f and
+// caches its result, unless f fails. Concurrent calls with the same key wait
+// for the first to finish and share its result.
+func (c *idempotencyCache) do(ctx context.Context, clk clock, key string, f func() (*CallToolResult, error)) (*CallToolResult, error) {
+	c.mu.Lock()
+	now := clk.Now()
+	for k, e := range c.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if e, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+			return e.res, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*idempotencyEntry)
+	}
+	e := &idempotencyEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	e.res, e.err = f()
+	c.mu.Lock()
+	if e.err != nil || e.res == nil || e.res.resultType == resultTypeInputRequired {
+		// Only complete results are cached: a failed call may be retried.
+		delete(c.entries, key)
+	} else {
+		e.expires = clk.Now().Add(c.ttl)
+	}
+	c.mu.Unlock()
+	close(e.done)
+	return e.res, e.err
+}
+
+// ToolResultCache returns receiving middleware that caches the results of
+// calls to tools annotated with both [ToolAnnotations.ReadOnlyHint] and
+// [ToolAnnotations.IdempotentHint], such as a search over static data.
+// Calls to other tools are passed through.
+//
+// Results are keyed by tool name and arguments, compared as JSON values, so
+// that differences in whitespace or key order do not matter. They are shared
+// by all sessions of the server, so the middleware is unsuitable for tools
+// whose results depend on the caller; calls to tools that
+// [ServerOptions.ToolFilter] hides from a session are passed through, and
+// fail as usual. Each result is cached for ttl. At most
+// maxEntries results are kept, evicting the least recently used; if
+// maxEntries is not positive, the number of entries is unbounded. Errors,
+// error results and dry runs are not cached.
+//
+// Install it with [Server.AddReceivingMiddleware].
+func ToolResultCache(ttl time.Duration, maxEntries int) Middleware {
+	return newToolResultCache(ttl, maxEntries).middleware
+}
+
+func newToolResultCache(ttl time.Duration, maxEntries int) *toolResultCache {
+	return &toolResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		clock:      realClock{},
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// toolResultCache is the state of a [ToolResultCache] middleware.
+type toolResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+	clock      clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // values are *toolResultEntry
+	lru     *list.List               // most recently used first
+}
+
+type toolResultEntry struct {
+	key     string
+	res     *CallToolResult
+	expires time.Time
+}
+
+func (c *toolResultCache) middleware(next MethodHandler) MethodHandler {
+	return func(ctx context.Context, method string, req Request) (Result, error) {
+		call, ok := req.(*CallToolRequest)
+		if !ok || method != methodCallTool || call.Session == nil || call.Params.GetDryRun() {
+			return next(ctx, method, req)
+		}
+		server := call.Session.server
+		st, ok := server.getServerTool(call.Params.Name)
+		if !ok || st.tool.Annotations == nil || !st.tool.Annotations.ReadOnlyHint || !st.tool.Annotations.IdempotentHint {
+			return next(ctx, method, req)
+		}
+		// Let the server reject calls to tools hidden from the session,
+		// rather than serving them from the cache.
+		if !featureVisible(ctx, call.Session, server.opts.ToolFilter, st.tool) {
+			return next(ctx, method, req)
+		}
+		key, ok := toolResultKey(call.Params)
+		if !ok {
+			return next(ctx, method, req)
+		}
+		if res, ok := c.get(key); ok {
+			return res, nil
+		}
+		res, err := next(ctx, method, req)
+		if err != nil {
+			return nil, err
+		}
+		if res, ok := res.(*CallToolResult); ok && !res.IsError && res.resultType != resultTypeInputRequired {
+			c.put(key, res)
+		}
+		return res, nil
+	}
+}
+
+// toolResultKey returns the cache key for a tool call, reporting false if
+// the arguments are not valid JSON.
+func toolResultKey(params *CallToolParamsRaw) (string, bool) {
+	var args any
+	if len(params.Arguments) > 0 {
+		// Decode numbers as json.Number, which preserves their text, so that
+		// distinct numbers never share a key, as large integers would if
+		// they were rounded to float64.
+		dec := json.NewDecoder(bytes.NewReader(params.Arguments))
+		dec.UseNumber()
+		if err := dec.Decode(&args); err != nil {
+			return "", false
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return "", false // trailing data
+		}
+	}
+	// Marshaling sorts object keys, canonicalizing the arguments.
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return params.Name + "\x00" + string(data), true
+}
+
+func (c *toolResultCache) get(key string) (*CallToolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*toolResultEntry)
+	if !c.clock.Now().Before(e.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return copyToolResult(e.res), true
+}
+
+func (c *toolResultCache) put(key string, res *CallToolResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &toolResultEntry{key: key, res: copyToolResult(res), expires: c.clock.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*toolResultEntry).key)
+	}
+}
+
+// copyToolResult returns a shallow copy of res with its own Content slice and
+// Meta map, so that middleware that adds content or metadata to a result
+// does not change the cached one.
+func copyToolResult(res *CallToolResult) *CallToolResult {
+	res2 := *res
+	res2.Content = slices.Clone(res.Content)
+	res2.Meta = maps.Clone(res.Meta)
+	return &res2
+}
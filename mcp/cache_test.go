@@ -0,0 +1,116 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestToolResultCache(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	calls := map[string]int{}
+	addTool := func(name string, annotations *ToolAnnotations, isError bool) {
+		AddTool(server, &Tool{Name: name, Annotations: annotations}, func(_ context.Context, _ *CallToolRequest, args map[string]any) (*CallToolResult, any, error) {
+			calls[name]++
+			res := &CallToolResult{Content: []Content{&TextContent{Text: fmt.Sprintf("%s call %d", name, calls[name])}}}
+			if isError {
+				res.IsError = true
+			}
+			return res, nil, nil
+		})
+	}
+	addTool("search", &ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true}, false)
+	addTool("now", &ToolAnnotations{ReadOnlyHint: true}, false)
+	addTool("failing", &ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true}, true)
+
+	const ttl = time.Minute
+	cache := newToolResultCache(ttl, 2)
+	clock := newFakeClock()
+	cache.clock = clock
+	server.AddReceivingMiddleware(cache.middleware)
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	call := func(name, args string) string {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &CallToolParams{Name: name, Arguments: json.RawMessage(args)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Content[0].(*TextContent).Text
+	}
+	check := func(name, args, want string) {
+		t.Helper()
+		if got := call(name, args); got != want {
+			t.Errorf("%s(%s) = %q, want %q", name, args, got, want)
+		}
+	}
+
+	check("search", `{"q":"a","n":1}`, "search call 1")
+	// Key order and whitespace don't matter.
+	check("search", `{ "n": 1, "q": "a" }`, "search call 1")
+	check("search", `{"q":"b","n":1}`, "search call 2")
+
+	// Tools that aren't both read-only and idempotent are not cached.
+	check("now", `{}`, "now call 1")
+	check("now", `{}`, "now call 2")
+	// Nor are error results.
+	check("failing", `{}`, "failing call 1")
+	check("failing", `{}`, "failing call 2")
+
+	// The cache holds two entries, evicting the least recently used.
+	check("search", `{"q":"a","n":1}`, "search call 1") // a is now most recent
+	check("search", `{"q":"c","n":1}`, "search call 3") // evicts b
+	check("search", `{"q":"b","n":1}`, "search call 4")
+	check("search", `{"q":"c","n":1}`, "search call 3")
+
+	// Entries expire after the TTL.
+	clock.Advance(ttl)
+	check("search", `{"q":"c","n":1}`, "search call 5")
+
+	// Integers that are equal as float64 values are distinct.
+	check("search", `{"n":9007199254740993}`, "search call 6")
+	check("search", `{"n":9007199254740992}`, "search call 7")
+	check("search", `{"n":9007199254740993}`, "search call 6")
+}
+
+func TestToolResultCacheCopies(t *testing.T) {
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "search", Annotations: &ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true}}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+		return &CallToolResult{
+			Content: []Content{&TextContent{Text: "result"}},
+			Meta:    Meta{"source": "index"},
+		}, nil, nil
+	})
+	server.AddReceivingMiddleware(newToolResultCache(time.Minute, 0).middleware)
+	// Middleware outside the cache adds to each result, which must not
+	// change the cached result.
+	server.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			res, err := next(ctx, method, req)
+			if res, ok := res.(*CallToolResult); ok {
+				if len(res.Content) != 1 || res.Meta["seen"] != nil {
+					t.Errorf("result modified by earlier call: %d contents, meta %v", len(res.Content), res.Meta)
+				}
+				res.Content = append(res.Content, &TextContent{Text: "footer"})
+				res.Meta["seen"] = true
+			}
+			return res, err
+		}
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	for range 3 {
+		if _, err := cs.CallTool(context.Background(), &CallToolParams{Name: "search", Arguments: map[string]any{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
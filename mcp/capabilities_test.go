@@ -8,11 +8,13 @@ package mcp
 
 import (
 	"context"
+	"fmt"
 	"sync/atomic"
 	"testing"
 	"testing/synctest"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/jsonschema-go/jsonschema"
 )
 
@@ -182,3 +184,46 @@ func TestClientListChangedNotifications(t *testing.T) {
 		})
 	}
 }
+
+func TestServerExactCapabilities(t *testing.T) {
+	ctx := context.Background()
+	caps := &ServerCapabilities{
+		Experimental: map[string]any{"custom": map[string]any{"enabled": true}},
+		Prompts:      &PromptCapabilities{},
+	}
+	caps.AddExtension("io.example/ext", nil)
+
+	for _, exact := range []bool{false, true} {
+		t.Run(fmt.Sprintf("exact=%t", exact), func(t *testing.T) {
+			server := NewServer(testImpl, &ServerOptions{
+				Capabilities:      caps,
+				ExactCapabilities: exact,
+				CompletionHandler: func(context.Context, *CompleteRequest) (*CompleteResult, error) {
+					return &CompleteResult{}, nil
+				},
+			})
+			AddTool(server, &Tool{Name: "greet"}, sayHi)
+			cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+			defer cleanup()
+
+			want := caps.clone()
+			if !exact {
+				want.Tools = &ToolCapabilities{ListChanged: true}
+				want.Completions = &CompletionCapabilities{}
+			}
+			got := cs.InitializeResult().Capabilities
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("capabilities mismatch (-want +got):\n%s", diff)
+			}
+
+			// Suppressing the tools capability does not disable tools/list.
+			res, err := cs.ListTools(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Tools) != 1 {
+				t.Errorf("ListTools returned %d tools, want 1", len(res.Tools))
+			}
+		})
+	}
+}
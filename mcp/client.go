@@ -6,6 +6,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"iter"
@@ -19,7 +20,7 @@ import (
 	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
-	"github.com/modelcontextprotocol/go-sdk/internal/json"
+	internaljson "github.com/modelcontextprotocol/go-sdk/internal/json"
 	"github.com/modelcontextprotocol/go-sdk/internal/jsonrpc2"
 	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 )
@@ -39,6 +40,8 @@ type Client struct {
 	// serverMethodInfos) plus any custom methods registered via
 	// [AddSendingCustomMethod].
 	sendMethods map[string]methodInfo
+	// clock is the source of time for keepalive.
+	clock clock
 }
 
 // NewClient creates a new [Client].
@@ -75,6 +78,11 @@ func NewClient(impl *Implementation, options *ClientOptions) *Client {
 		sendingMethodHandler_:   defaultSendingMethodHandler,
 		receivingMethodHandler_: defaultReceivingMethodHandler[*ClientSession],
 		sendMethods:             sendMethods,
+		clock:                   realClock{},
+	}
+	// Timing middleware is innermost, so that it measures only the round trip.
+	if opts.RequestTimingHandler != nil {
+		c.AddSendingMiddleware(c.requestTimingMiddleware)
 	}
 	if opts.MultiRoundTrip == nil || !opts.MultiRoundTrip.Disabled {
 		c.AddSendingMiddleware(clientMultiRoundTripMiddleware())
@@ -94,6 +102,10 @@ type ClientOptions struct {
 	// non nil value for [ClientCapabilities.Sampling], that value overrides the
 	// inferred capability.
 	//
+	// The handler may stream its response to the server as it is generated,
+	// using progress notifications as described at
+	// [ServerSession.CreateMessageStream].
+	//
 	// Deprecated: the sampling feature is deprecated as of protocol version
 	// 2026-07-28 (SEP-2577). It remains functional during the deprecation
 	// window (at least twelve months). Migrate to calling LLM provider APIs
@@ -204,6 +216,72 @@ type ClientOptions struct {
 	// reset" guidance, letting a transient miss pass without tearing down an
 	// otherwise live session. Has no effect unless KeepAlive is non-zero.
 	KeepAliveFailureThreshold int
+	// InitializeTimeout, if non-zero, bounds the time [Client.Connect] waits
+	// for the server to complete the initialization handshake. If the
+	// handshake does not complete in time, Connect closes the session and
+	// returns an error wrapping [context.DeadlineExceeded].
+	InitializeTimeout time.Duration
+	// RequestTimingHandler, if non-nil, is called after each request the
+	// client sends completes, with the times at which the request was sent and
+	// its response arrived. It can be used to measure server latency, for
+	// example for SLO monitoring, without wrapping the transport.
+	//
+	// The times are recorded beneath all sending middleware, so they exclude
+	// time spent in middleware, but include time spent in the transport.
+	// Notifications are not reported.
+	//
+	// RequestTimingHandler is called synchronously, before the request's
+	// result is returned to the caller, so it should not block.
+	RequestTimingHandler func(context.Context, *RequestTiming)
+	// SessionEstablishedHandler, if non-nil, is called by [Client.Connect]
+	// once a session is established, with a summary of the session, before
+	// Connect returns. A session is established by the initialization
+	// handshake, by server/discover for protocol version 2026-07-28 or later,
+	// or by resuming it with [ClientSessionOptions.InitializeResult]. The
+	// server's [ServerOptions.SessionEstablishedHandler] is called in the
+	// first two cases, but not for a resumed session, which it has already
+	// reported.
+	SessionEstablishedHandler func(context.Context, *SessionInfo)
+}
+
+// RequestTiming describes the timing of a request sent by a [ClientSession].
+// See [ClientOptions.RequestTimingHandler].
+type RequestTiming struct {
+	Session  *ClientSession
+	Method   string
+	Sent     time.Time // when the request was passed to the transport
+	Received time.Time // when the response, or error, was received
+	// Err is the error returned for the request, if any. For example, it is
+	// non-nil if the server responded with a JSON-RPC error, or the request
+	// was cancelled.
+	Err error
+}
+
+// Latency reports the time between sending the request and receiving its
+// response.
+func (t *RequestTiming) Latency() time.Duration {
+	return t.Received.Sub(t.Sent)
+}
+
+// requestTimingMiddleware reports the timing of outgoing requests to
+// [ClientOptions.RequestTimingHandler].
+func (c *Client) requestTimingMiddleware(next MethodHandler) MethodHandler {
+	return func(ctx context.Context, method string, req Request) (Result, error) {
+		if strings.HasPrefix(method, "notifications/") {
+			return next(ctx, method, req)
+		}
+		sent := c.clock.Now()
+		res, err := next(ctx, method, req)
+		cs, _ := req.GetSession().(*ClientSession)
+		c.opts.RequestTimingHandler(ctx, &RequestTiming{
+			Session:  cs,
+			Method:   method,
+			Sent:     sent,
+			Received: c.clock.Now(),
+			Err:      err,
+		})
+		return res, err
+	}
 }
 
 // toolContextKeyType is the context key type for passing tool definitions
@@ -247,6 +325,14 @@ func (e unsupportedProtocolVersionError) Error() string {
 
 // ClientSessionOptions is reserved for future use.
 type ClientSessionOptions struct {
+	// InitializeResult, if set, resumes a session that was initialized
+	// earlier, for example by a previous run of the client process: Connect
+	// does not initialize the session, and uses InitializeResult, as returned
+	// by [ClientSession.InitializeResult], as the result of its
+	// initialization. The transport must connect to the existing session, as
+	// with [StreamableClientTransport.SessionID].
+	InitializeResult *InitializeResult
+
 	// protocolVersion overrides the protocol version sent in the initialize
 	// request, for testing. If empty, latestProtocolVersion is used.
 	protocolVersion string
@@ -300,15 +386,51 @@ func (c *Client) capabilities(protocolVersion string) *ClientCapabilities {
 // Connect begins an MCP session by connecting to a server over the given
 // transport. The resulting session is initialized, and ready to use.
 //
+// The session handles server-initiated requests (such as "roots/list") from
+// the moment it is connected, before the initialization handshake begins. In
+// particular, the server may send requests as soon as it receives the
+// "notifications/initialized" notification.
+//
 // Typically, it is the responsibility of the client to close the connection
 // when it is no longer needed. However, if the connection is closed by the
 // server, calls or notifications will return an error wrapping
 // [ErrConnectionClosed].
 func (c *Client) Connect(ctx context.Context, t Transport, opts *ClientSessionOptions) (cs *ClientSession, err error) {
-	cs, err = connect(ctx, t, c, (*clientSessionState)(nil), nil, c.opts.Logger)
+	// Let HTTP transports identify the client in their User-Agent.
+	connectCtx := context.WithValue(ctx, clientInfoContextKey{}, c.impl)
+	cs, err = connect(connectCtx, t, c, (*clientSessionState)(nil), nil, c.opts.Logger)
 	if err != nil {
 		return nil, err
 	}
+	if d := c.opts.InitializeTimeout; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+		session := cs
+		defer func() {
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				_ = session.Close() // Close is idempotent
+				err = fmt.Errorf("initialization did not complete within %v: %w", d, err)
+			}
+		}()
+	}
+
+	if opts != nil && opts.InitializeResult != nil {
+		res := opts.InitializeResult
+		if !slices.Contains(supportedProtocolVersions, res.ProtocolVersion) {
+			_ = cs.Close()
+			return nil, unsupportedProtocolVersionError{res.ProtocolVersion}
+		}
+		cs.state.InitializeResult = res
+		if hc, ok := cs.mcpConn.(clientConnection); ok {
+			hc.sessionUpdated(cs.state)
+		}
+		if c.opts.KeepAlive > 0 {
+			cs.startKeepalive(c.opts.KeepAlive)
+		}
+		c.sessionEstablished(ctx, cs)
+		return cs, nil
+	}
 
 	protocolVersion := latestProtocolVersion
 	if opts != nil && opts.protocolVersion != "" {
@@ -351,6 +473,7 @@ func (c *Client) Connect(ctx context.Context, t Transport, opts *ClientSessionOp
 						return nil, fmt.Errorf("opening subscriptions/listen: %w", err)
 					}
 				}
+				c.sessionEstablished(ctx, cs)
 				return cs, nil
 			}
 
@@ -359,7 +482,7 @@ func (c *Client) Connect(ctx context.Context, t Transport, opts *ClientSessionOp
 			var werr *jsonrpc.Error
 			if errors.As(err, &werr) && werr.Code == CodeUnsupportedProtocolVersion && len(werr.Data) > 0 {
 				var data UnsupportedProtocolVersionData
-				if err := json.Unmarshal(werr.Data, &data); err == nil {
+				if err := internaljson.Unmarshal(werr.Data, &data); err == nil {
 					if negotiatedVersion := negotiateMutuallySupportedVersion(data.Supported); negotiatedVersion != "" && negotiatedVersion >= protocolVersion20260728 {
 						discoverCtx = context.WithValue(ctx, protocolVersionContextKey{}, negotiatedVersion)
 						continue
@@ -392,6 +515,8 @@ func (c *Client) Connect(ctx context.Context, t Transport, opts *ClientSessionOp
 	if hc, ok := cs.mcpConn.(clientConnection); ok {
 		hc.sessionUpdated(cs.state)
 	}
+	// The session is already receiving and handling incoming messages, so the
+	// server may begin making requests as soon as it sees this notification.
 	req2 := &initializedClientRequest{Session: cs, Params: &InitializedParams{}}
 	if err := handleNotify(ctx, notificationInitialized, req2); err != nil {
 		_ = cs.Close()
@@ -401,10 +526,29 @@ func (c *Client) Connect(ctx context.Context, t Transport, opts *ClientSessionOp
 	if c.opts.KeepAlive > 0 {
 		cs.startKeepalive(c.opts.KeepAlive)
 	}
+	c.sessionEstablished(ctx, cs)
 
 	return cs, nil
 }
 
+// sessionEstablished calls the SessionEstablishedHandler, if any, for the
+// newly established session cs.
+func (c *Client) sessionEstablished(ctx context.Context, cs *ClientSession) {
+	h := c.opts.SessionEstablishedHandler
+	if h == nil {
+		return
+	}
+	res := cs.state.InitializeResult
+	h(ctx, &SessionInfo{
+		Session:            cs,
+		ProtocolVersion:    res.ProtocolVersion,
+		ClientInfo:         c.impl,
+		ClientCapabilities: c.capabilities(res.ProtocolVersion),
+		ServerInfo:         res.ServerInfo,
+		ServerCapabilities: res.Capabilities,
+	})
+}
+
 // discover sends a SEP-2575 server/discover request to probe the server for
 // stateless protocol support.
 func (c *Client) discover(ctx context.Context, cs *ClientSession) (*InitializeResult, error) {
@@ -491,6 +635,12 @@ type ClientSession struct {
 	// Unsubscribe straight to the resources/subscribe and resources/unsubscribe
 	// RPCs and leaves this map untouched.
 	resourceSubs map[string]context.CancelFunc
+
+	// resourceWatchersMu guards resourceWatchers.
+	resourceWatchersMu sync.Mutex
+	// resourceWatchers holds the active watches started by WatchResource,
+	// keyed by resource URI.
+	resourceWatchers map[string][]*resourceWatcher
 }
 
 type clientSessionState struct {
@@ -499,6 +649,84 @@ type clientSessionState struct {
 
 func (cs *ClientSession) InitializeResult() *InitializeResult { return cs.state.InitializeResult }
 
+// Instructions returns the server's instructions for using its features,
+// from [InitializeResult.Instructions], or "" if the server provided none.
+// Clients may add the instructions to the system prompt of a model using the
+// server.
+func (cs *ClientSession) Instructions() string {
+	if res := cs.state.InitializeResult; res != nil {
+		return res.Instructions
+	}
+	return ""
+}
+
+// ProtocolVersion returns the protocol version negotiated with the server,
+// from [InitializeResult.ProtocolVersion].
+func (cs *ClientSession) ProtocolVersion() string {
+	if res := cs.state.InitializeResult; res != nil {
+		return res.ProtocolVersion
+	}
+	return ""
+}
+
+// ServerInfo returns the server's implementation information, from
+// [InitializeResult.ServerInfo].
+func (cs *ClientSession) ServerInfo() *Implementation {
+	if res := cs.state.InitializeResult; res != nil {
+		return res.ServerInfo
+	}
+	return nil
+}
+
+// ServerCapabilities returns the capabilities the server advertised, from
+// [InitializeResult.Capabilities]. It never returns nil.
+//
+// The ServerSupports methods below report individual capabilities. Use them
+// to avoid requests that the server is bound to reject.
+func (cs *ClientSession) ServerCapabilities() *ServerCapabilities {
+	if res := cs.state.InitializeResult; res != nil && res.Capabilities != nil {
+		return res.Capabilities
+	}
+	return &ServerCapabilities{}
+}
+
+// ServerSupportsTools reports whether the server supports listing and calling
+// tools.
+func (cs *ClientSession) ServerSupportsTools() bool {
+	return cs.ServerCapabilities().Tools != nil
+}
+
+// ServerSupportsPrompts reports whether the server supports listing and
+// getting prompts.
+func (cs *ClientSession) ServerSupportsPrompts() bool {
+	return cs.ServerCapabilities().Prompts != nil
+}
+
+// ServerSupportsResources reports whether the server supports listing and
+// reading resources.
+func (cs *ClientSession) ServerSupportsResources() bool {
+	return cs.ServerCapabilities().Resources != nil
+}
+
+// ServerSupportsSubscribe reports whether the server supports subscribing to
+// resource updates with [ClientSession.Subscribe].
+func (cs *ClientSession) ServerSupportsSubscribe() bool {
+	r := cs.ServerCapabilities().Resources
+	return r != nil && r.Subscribe
+}
+
+// ServerSupportsCompletions reports whether the server supports argument
+// completion with [ClientSession.Complete].
+func (cs *ClientSession) ServerSupportsCompletions() bool {
+	return cs.ServerCapabilities().Completions != nil
+}
+
+// ServerSupportsLogging reports whether the server supports
+// [ClientSession.SetLoggingLevel] and sends log messages.
+func (cs *ClientSession) ServerSupportsLogging() bool {
+	return cs.ServerCapabilities().Logging != nil
+}
+
 // usesNewProtocol reports whether this session has negotiated a protocol
 // version >= 2026-07-28, which requires the SEP-2575 per-request `_meta`
 // triple on every outgoing request.
@@ -542,6 +770,33 @@ func (cs *ClientSession) ID() string {
 	return ""
 }
 
+// LastEventID returns the ID of the last event the client received on the
+// session's standalone SSE stream, or "" if there is none, or the transport
+// has no such stream. Record it along with [ClientSession.ID] to resume the
+// session later with [StreamableClientTransport.LastEventID].
+func (cs *ClientSession) LastEventID() string {
+	if c, ok := cs.mcpConn.(hasLastEventID); ok {
+		return c.LastEventID()
+	}
+	return ""
+}
+
+// CancelAll cancels all of the session's in-flight requests, without closing
+// the session.
+//
+// Requests the session sent to the server fail with an error wrapping
+// [context.Canceled], and the server is notified of their cancellation.
+// Requests from the server that are being handled have their contexts
+// cancelled; handlers that respect cancellation return promptly. New requests
+// are unaffected.
+//
+// CancelAll lets a session drain its work before [ClientSession.Close], which
+// otherwise waits for ongoing requests to return. The error, if any, reports
+// cancellation notifications that could not be sent.
+func (cs *ClientSession) CancelAll() error {
+	return cancelAll(cs.conn)
+}
+
 // Close performs a graceful close of the connection, preventing new requests
 // from being handled, and waiting for ongoing requests to return. Close then
 // terminates the connection.
@@ -560,6 +815,7 @@ func (cs *ClientSession) Close() error {
 	if cs.listenCancel != nil {
 		cs.listenCancel()
 	}
+	cs.cancelAllResourceWatchers()
 	cs.cancelAllResourceSubscriptions()
 	err := cs.conn.Close()
 
@@ -639,7 +895,7 @@ func (cs *ClientSession) registerElicitationWaiter(elicitationID string) (await
 
 // startKeepalive starts the keepalive mechanism for this client session.
 func (cs *ClientSession) startKeepalive(interval time.Duration) {
-	startKeepalive(cs, interval, cs.client.opts.KeepAliveFailureThreshold, &cs.keepaliveCancel, cs.client.opts.Logger)
+	startKeepalive(cs, cs.client.clock, interval, cs.client.opts.KeepAliveFailureThreshold, &cs.keepaliveCancel, cs.client.opts.Logger)
 }
 
 // AddRoots adds the given roots to the client,
@@ -732,6 +988,10 @@ func (c *Client) createMessage(ctx context.Context, req *CreateMessageWithToolsR
 	}
 	if c.opts.CreateMessageHandler != nil {
 		// Downconvert the request for the basic handler.
+		if len(req.Params.Tools) > 0 {
+			c.opts.Logger.Warn("CreateMessageHandler cannot see sampling tools; use CreateMessageWithToolsHandler",
+				"tools", len(req.Params.Tools))
+		}
 		baseParams, err := req.Params.toBase()
 		if err != nil {
 			return nil, err
@@ -796,7 +1056,7 @@ func urlElicitationMiddleware() Middleware {
 				Elicitations []*ElicitParams `json:"elicitations"`
 			}
 			if rpcErr.Data != nil {
-				if err := json.Unmarshal(rpcErr.Data, &errorData); err != nil {
+				if err := internaljson.Unmarshal(rpcErr.Data, &errorData); err != nil {
 					return nil, fmt.Errorf("failed to parse URL elicitation error data: %w", err)
 				}
 			}
@@ -877,8 +1137,11 @@ func (c *Client) elicit(ctx context.Context, req *ElicitRequest) (*ElicitResult,
 		if err != nil {
 			return nil, err
 		}
+		if err := checkElicitResult(res); err != nil {
+			return nil, err
+		}
 		// Validate elicitation result content against requested schema.
-		if res.Action == "accept" && schema != nil && res.Content != nil {
+		if res.Accepted() && schema != nil && res.Content != nil {
 			resolved, err := schema.Resolve(nil)
 			if err != nil {
 				return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: fmt.Sprintf("failed to resolve requested schema: %v", err)}
@@ -900,7 +1163,14 @@ func (c *Client) elicit(ctx context.Context, req *ElicitRequest) (*ElicitResult,
 			return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: "URL must be set for URL elicitation"}
 		}
 		// No schema validation for URL mode, just pass through to handler.
-		return c.opts.ElicitationHandler(ctx, req)
+		res, err := c.opts.ElicitationHandler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkElicitResult(res); err != nil {
+			return nil, err
+		}
+		return res, nil
 	default:
 		return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: fmt.Sprintf("unsupported elicitation mode: %q", mode)}
 	}
@@ -921,6 +1191,10 @@ func validateElicitSchema(wireSchema any) (*jsonschema.Schema, error) {
 		return nil, nil
 	}
 
+	if schema.Ref != "" {
+		return nil, fmt.Errorf("elicit schema uses $ref %q, but elicitation schemas must be flat: inline the referenced schema", schema.Ref)
+	}
+
 	// The root schema must be of type "object" if specified
 	if schema.Type != "" && schema.Type != "object" {
 		return nil, fmt.Errorf("elicit schema must be of type 'object', got %q", schema.Type)
@@ -944,6 +1218,11 @@ func validateElicitSchema(wireSchema any) (*jsonschema.Schema, error) {
 
 // validateElicitProperty validates a single property in an elicitation schema.
 func validateElicitProperty(propName string, propSchema *jsonschema.Schema) error {
+	// References are not followed, since an elicitation schema must be a
+	// single flat object.
+	if propSchema.Ref != "" {
+		return fmt.Errorf("elicit schema property %q uses $ref %q, but elicitation schemas must be flat: inline the referenced schema", propName, propSchema.Ref)
+	}
 	// Check if this property has nested properties (not allowed)
 	if len(propSchema.Properties) > 0 {
 		return fmt.Errorf("elicit schema property %q contains nested properties, only primitive properties are allowed", propName)
@@ -1104,7 +1383,7 @@ func validateDefaultProperty[T any](propName string, propSchema *jsonschema.Sche
 	// Validate default value if specified - must be a valid T
 	if propSchema.Default != nil {
 		var defaultValue T
-		if err := json.Unmarshal(propSchema.Default, &defaultValue); err != nil {
+		if err := internaljson.Unmarshal(propSchema.Default, &defaultValue); err != nil {
 			return fmt.Errorf("elicit schema property %q has invalid default value, must be a %T: %v", propName, defaultValue, err)
 		}
 	}
@@ -1176,6 +1455,9 @@ func (cs *ClientSession) receivingMethodInfos() map[string]methodInfo {
 func (cs *ClientSession) handle(ctx context.Context, req *jsonrpc.Request) (any, error) {
 	if req.IsCall() {
 		jsonrpc2.Async(ctx)
+		// As on the server, record the request ID so that notifications sent
+		// while handling the request can be associated with it.
+		ctx = context.WithValue(ctx, idContextKey{}, req.ID)
 	}
 	return handleReceive(ctx, cs, req)
 }
@@ -1213,11 +1495,26 @@ func newClientRequest[P Params](cs *ClientSession, params P) *ClientRequest[P] {
 }
 
 // Ping makes an MCP "ping" request to the server.
+//
+// If params is nil, the request is sent with empty params ("params": {}).
 func (cs *ClientSession) Ping(ctx context.Context, params *PingParams) error {
+	if params == nil {
+		params = &PingParams{}
+	}
 	_, err := handleSend[*emptyResult](ctx, methodPing, newClientRequest(cs, orZero[Params](params)))
 	return err
 }
 
+// PingRoundTrip pings the server, and reports the time until its response
+// arrived. Use it for health checks and latency probes.
+func (cs *ClientSession) PingRoundTrip(ctx context.Context) (time.Duration, error) {
+	start := cs.client.clock.Now()
+	if err := cs.Ping(ctx, nil); err != nil {
+		return 0, err
+	}
+	return cs.client.clock.Now().Sub(start), nil
+}
+
 // ListPrompts lists prompts that are currently available on the server.
 //
 // Results may be served from a client-side TTL cache populated by previous
@@ -1268,12 +1565,13 @@ func (cs *ClientSession) ListTools(ctx context.Context, params *ListToolsParams)
 
 // CallTool calls the tool with the given parameters.
 //
-// The params.Arguments can be any value that marshals into a JSON object.
+// The params.Arguments can be any value that marshals into a JSON object,
+// including a [json.RawMessage] holding arguments that are already serialized.
 func (cs *ClientSession) CallTool(ctx context.Context, params *CallToolParams) (*CallToolResult, error) {
 	if params == nil {
 		params = new(CallToolParams)
 	}
-	if params.Arguments == nil {
+	if raw, ok := params.Arguments.(json.RawMessage); params.Arguments == nil || ok && len(raw) == 0 {
 		// Avoid sending nil over the wire.
 		params.Arguments = map[string]any{}
 	}
@@ -1286,6 +1584,48 @@ func (cs *ClientSession) CallTool(ctx context.Context, params *CallToolParams) (
 	return handleSend[*CallToolResult](ctx, methodCallTool, newClientRequest(cs, orZero[Params](params)))
 }
 
+// maxToolFlowSteps bounds the number of continuations followed by
+// [ClientSession.CallToolFlow], guarding against flows that never end.
+const maxToolFlowSteps = 32
+
+// CallToolFlow calls a tool as with [ClientSession.CallTool], then follows the
+// continuations in its results (see [ToolContinuation]): as long as a result
+// has a continuation, CallToolFlow calls the tool it names. It returns the
+// first result without a continuation.
+//
+// If next is non-nil, it is called before each continuation is followed, with
+// the result that holds it, and returns the arguments for the next call: for
+// example, the continuation's arguments combined with input from the user.
+// If next returns an error, CallToolFlow stops and returns it along with the
+// last result. If next is nil, the continuation's arguments are used as is.
+//
+// Results with IsError set end the flow. To guard against flows that never
+// end, CallToolFlow fails after following 32 continuations.
+func (cs *ClientSession) CallToolFlow(ctx context.Context, params *CallToolParams, next func(context.Context, *CallToolResult, ToolContinuation) (any, error)) (*CallToolResult, error) {
+	res, err := cs.CallTool(ctx, params)
+	for step := 0; err == nil && !res.IsError; step++ {
+		c, ok := res.GetContinuation()
+		if !ok {
+			break
+		}
+		if step == maxToolFlowSteps {
+			return res, fmt.Errorf("tool flow did not complete after %d continuations", maxToolFlowSteps)
+		}
+		var args any
+		if c.Arguments != nil {
+			// A nil map in args would be sent as null.
+			args = c.Arguments
+		}
+		if next != nil {
+			if args, err = next(ctx, res, c); err != nil {
+				return res, err
+			}
+		}
+		res, err = cs.CallTool(ctx, &CallToolParams{Name: c.Tool, Arguments: args})
+	}
+	return res, err
+}
+
 // SetLoggingLevel sets the minimum severity level for log messages sent by
 // the server.
 //
@@ -1437,6 +1777,114 @@ func (cs *ClientSession) cancelAllResourceSubscriptions() {
 	}
 }
 
+// A resourceWatcher is a watch started by [ClientSession.WatchResource].
+type resourceWatcher struct {
+	updated chan struct{} // buffered; signaled on each update of the resource
+	cancel  context.CancelFunc
+}
+
+// WatchResource subscribes to updates of the resource with the given URI, and
+// returns a channel that receives the resource's contents, re-read with
+// [ClientSession.ReadResource], each time the server reports that it was
+// updated. Updates that arrive while a read is in progress are coalesced.
+//
+// The watch ends when ctx is done or the session is closed. The channel is
+// then closed, and the resource is unsubscribed unless another watch of the
+// same URI is still active. Errors from re-reading the resource are logged to
+// [ClientOptions.Logger] and otherwise ignored.
+//
+// Callers must receive from the channel until it is closed; an unread value
+// blocks further reads, though not other session activity.
+func (cs *ClientSession) WatchResource(ctx context.Context, uri string) (<-chan *ReadResourceResult, error) {
+	if uri == "" {
+		return nil, fmt.Errorf("WatchResource: missing URI")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &resourceWatcher{updated: make(chan struct{}, 1), cancel: cancel}
+	// Register the watcher before subscribing, so that no update is missed.
+	cs.resourceWatchersMu.Lock()
+	if cs.resourceWatchers == nil {
+		cs.resourceWatchers = make(map[string][]*resourceWatcher)
+	}
+	cs.resourceWatchers[uri] = append(cs.resourceWatchers[uri], w)
+	cs.resourceWatchersMu.Unlock()
+
+	if err := cs.Subscribe(ctx, &SubscribeParams{URI: uri}); err != nil {
+		cs.removeResourceWatcher(uri, w)
+		cancel()
+		return nil, err
+	}
+
+	out := make(chan *ReadResourceResult)
+	go func() {
+		defer close(out)
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				if cs.removeResourceWatcher(uri, w) {
+					// Unsubscribe even though ctx is done.
+					if err := cs.Unsubscribe(context.WithoutCancel(ctx), &UnsubscribeParams{URI: uri}); err != nil && !errors.Is(err, ErrConnectionClosed) {
+						cs.client.opts.Logger.Warn("WatchResource: unsubscribing", "uri", uri, "error", err)
+					}
+				}
+				return
+			case <-w.updated:
+			}
+			res, err := cs.ReadResource(ctx, &ReadResourceParams{URI: uri})
+			if err != nil {
+				if ctx.Err() == nil {
+					cs.client.opts.Logger.Warn("WatchResource: reading updated resource", "uri", uri, "error", err)
+				}
+				continue
+			}
+			select {
+			case out <- res:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
+
+// removeResourceWatcher removes w from the watchers of uri, and reports
+// whether it was the last one.
+func (cs *ClientSession) removeResourceWatcher(uri string, w *resourceWatcher) bool {
+	cs.resourceWatchersMu.Lock()
+	defer cs.resourceWatchersMu.Unlock()
+	ws := slices.DeleteFunc(cs.resourceWatchers[uri], func(x *resourceWatcher) bool { return x == w })
+	if len(ws) > 0 {
+		cs.resourceWatchers[uri] = ws
+		return false
+	}
+	delete(cs.resourceWatchers, uri)
+	return true
+}
+
+// notifyResourceWatchers signals the watchers of uri that it was updated.
+func (cs *ClientSession) notifyResourceWatchers(uri string) {
+	cs.resourceWatchersMu.Lock()
+	defer cs.resourceWatchersMu.Unlock()
+	for _, w := range cs.resourceWatchers[uri] {
+		select {
+		case w.updated <- struct{}{}:
+		default: // an update is already pending
+		}
+	}
+}
+
+// cancelAllResourceWatchers ends every watch started by WatchResource.
+// Called from Close.
+func (cs *ClientSession) cancelAllResourceWatchers() {
+	cs.resourceWatchersMu.Lock()
+	defer cs.resourceWatchersMu.Unlock()
+	for _, ws := range cs.resourceWatchers {
+		for _, w := range ws {
+			w.cancel()
+		}
+	}
+}
+
 // SubscriptionsListen opens a SEP-2575 "subscriptions/listen" stream.
 //
 // The server's first message on the stream is "notifications/subscriptions/acknowledged";
@@ -1486,6 +1934,7 @@ func (c *Client) callResourceChangedHandler(ctx context.Context, req *ResourceLi
 func (c *Client) callResourceUpdatedHandler(ctx context.Context, req *ResourceUpdatedNotificationRequest) (Result, error) {
 	if cs, ok := req.GetSession().(*ClientSession); ok && req.Params != nil {
 		cs.readResourceCache.invalidateKey(req.Params.URI)
+		cs.notifyResourceWatchers(req.Params.URI)
 	}
 	if h := c.opts.ResourceUpdatedHandler; h != nil {
 		h(ctx, req)
@@ -1533,7 +1982,25 @@ func (c *Client) callElicitationCompleteHandler(ctx context.Context, req *Elicit
 // This can be used if the client is performing a long-running task that was
 // initiated by the server.
 func (cs *ClientSession) NotifyProgress(ctx context.Context, params *ProgressNotificationParams) error {
-	return handleNotify(ctx, notificationProgress, newClientRequest(cs, orZero[Params](params)))
+	return cs.Notify(ctx, notificationProgress, orZero[Params](params))
+}
+
+// Notify sends a notification with the given method and params to the server,
+// through the client's sending middleware. Like [ServerSession.Notify], it is
+// a low-level escape hatch, here for extension notifications: clients should
+// only send them to servers that advertise the corresponding extension in
+// [ServerCapabilities.Extensions]. Servers handle notifications with unknown
+// methods with [ServerOptions.UnknownMethodHandler], or ignore them.
+//
+// Params may be nil, or any [Params] value; custom params types can embed
+// [ParamsBase].
+func (cs *ClientSession) Notify(ctx context.Context, method string, params Params) error {
+	if info, ok := cs.sendingMethodInfos()[method]; !ok {
+		ctx = context.WithValue(ctx, customNotificationContextKey{}, true)
+	} else if info.flags&notification == 0 {
+		return fmt.Errorf("%q is a request, not a notification", method)
+	}
+	return handleNotify(ctx, method, newClientRequest(cs, params))
 }
 
 // Tools provides an iterator for all tools available on the server,
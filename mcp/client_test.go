@@ -8,10 +8,18 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -1086,3 +1094,505 @@ func TestClientConnectDiscover_UnsupportedVersionNegotiation(t *testing.T) {
 		t.Errorf("InitializeResult.ProtocolVersion = %q, want %q", got, want)
 	}
 }
+
+// latencyTransport is a [Transport] that delays every message read from the
+// underlying connection, simulating a slow peer.
+type latencyTransport struct {
+	Transport
+	latency time.Duration
+}
+
+func (t *latencyTransport) Connect(ctx context.Context) (Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &latencyConn{conn, t.latency}, nil
+}
+
+type latencyConn struct {
+	Connection
+	latency time.Duration
+}
+
+func (c *latencyConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	msg, err := c.Connection.Read(ctx)
+	if err == nil {
+		time.Sleep(c.latency)
+	}
+	return msg, err
+}
+
+func TestClientInitializeTimeout(t *testing.T) {
+	for _, version := range []string{protocolVersion20251125, latestProtocolVersion} {
+		t.Run(version, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx := context.Background()
+				ct, st := NewInMemoryTransports()
+				server := NewServer(testImpl, nil)
+				ss, err := server.Connect(ctx, &latencyTransport{st, time.Second}, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer ss.Close()
+
+				client := NewClient(testImpl, &ClientOptions{InitializeTimeout: 100 * time.Millisecond})
+				_, err = client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: version})
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("Connect() error = %v, want %v", err, context.DeadlineExceeded)
+				}
+
+				// A fast enough server is unaffected by the timeout.
+				ct, st = NewInMemoryTransports()
+				ss2, err := server.Connect(ctx, &latencyTransport{st, 10 * time.Millisecond}, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer ss2.Close()
+				cs, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: version})
+				if err != nil {
+					t.Fatal(err)
+				}
+				cs.Close()
+			})
+		})
+	}
+}
+
+// TestClientReadyBeforeInitialized verifies that the client can handle
+// server-initiated requests as soon as the server receives
+// notifications/initialized.
+func TestClientReadyBeforeInitialized(t *testing.T) {
+	const iterations = 50
+
+	ctx := context.Background()
+	rootsErrs := make(chan error, iterations)
+	server := NewServer(testImpl, &ServerOptions{
+		InitializedHandler: func(ctx context.Context, req *InitializedRequest) {
+			res, err := req.Session.ListRoots(ctx, nil)
+			if err == nil && len(res.Roots) != 1 {
+				err = fmt.Errorf("got %d roots, want 1", len(res.Roots))
+			}
+			rootsErrs <- err
+		},
+	})
+	client := NewClient(testImpl, nil)
+	client.AddRoots(&Root{URI: "file:///root"})
+
+	transports := map[string]func() (client, server Transport){
+		"in-memory": func() (Transport, Transport) {
+			ct, st := NewInMemoryTransports()
+			return ct, st
+		},
+	}
+	httpServer := httptest.NewServer(NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil))
+	defer httpServer.Close()
+	transports["streamable"] = func() (Transport, Transport) {
+		return &StreamableClientTransport{Endpoint: httpServer.URL}, nil
+	}
+
+	for name, newTransports := range transports {
+		t.Run(name, func(t *testing.T) {
+			for range iterations {
+				ct, st := newTransports()
+				if st != nil {
+					ss, err := server.Connect(ctx, st, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer ss.Close()
+				}
+				cs, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := <-rootsErrs; err != nil {
+					t.Errorf("ListRoots on initialized: %v", err)
+				}
+				cs.Close()
+			}
+		})
+	}
+}
+
+func TestClientSessionAccessors(t *testing.T) {
+	for _, version := range []string{protocolVersion20251125, latestProtocolVersion} {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+			ct, st := NewInMemoryTransports()
+			impl := &Implementation{Name: "testServer", Version: "v1.2.3"}
+			server := NewServer(impl, &ServerOptions{Instructions: "use the tools wisely"})
+			ss, err := server.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+			cs, err := NewClient(testImpl, nil).Connect(ctx, ct, &ClientSessionOptions{protocolVersion: version})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+
+			if got, want := cs.Instructions(), "use the tools wisely"; got != want {
+				t.Errorf("Instructions() = %q, want %q", got, want)
+			}
+			if got := cs.ProtocolVersion(); got != version {
+				t.Errorf("ProtocolVersion() = %q, want %q", got, version)
+			}
+			if diff := cmp.Diff(impl, cs.ServerInfo()); diff != "" {
+				t.Errorf("ServerInfo() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClientSessionNotify(t *testing.T) {
+	ctx := context.Background()
+	type received struct {
+		method string
+		params string
+	}
+	got := make(chan received, 1)
+	server := NewServer(testImpl, &ServerOptions{
+		UnknownMethodHandler: func(_ context.Context, _ *ServerSession, req *jsonrpc.Request) (any, error) {
+			got <- received{req.Method, string(req.Params)}
+			return nil, nil
+		},
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	type eventParams struct {
+		ParamsBase
+		Kind string `json:"kind"`
+	}
+	if err := cs.Notify(ctx, "acme/event", &eventParams{Kind: "opened"}); err != nil {
+		t.Fatal(err)
+	}
+	want := received{"acme/event", `{"kind":"opened"}`}
+	if diff := cmp.Diff(want, <-got, cmp.AllowUnexported(received{})); diff != "" {
+		t.Errorf("server received mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := cs.Notify(ctx, methodListTools, nil); err == nil {
+		t.Error("Notify with a request method succeeded unexpectedly")
+	}
+}
+
+func TestClientRequestTiming(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "slow", InputSchema: &jsonschema.Schema{Type: "object"}}, func(context.Context, *CallToolRequest) (*CallToolResult, error) {
+		clock.Advance(time.Second)
+		return &CallToolResult{}, nil
+	})
+	var (
+		mu      sync.Mutex
+		timings []*RequestTiming
+	)
+	client := NewClient(testImpl, &ClientOptions{
+		RequestTimingHandler: func(_ context.Context, timing *RequestTiming) {
+			mu.Lock()
+			defer mu.Unlock()
+			timings = append(timings, timing)
+		},
+	})
+	client.clock = clock
+	cs, _, cleanup := basicClientServerConnection(t, client, server, nil)
+	defer cleanup()
+
+	mu.Lock()
+	timings = nil // ignore initialization
+	mu.Unlock()
+	if _, err := cs.CallTool(ctx, &CallToolParams{Name: "slow"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.GetPrompt(ctx, &GetPromptParams{Name: "missing"}); err == nil {
+		t.Fatal("GetPrompt succeeded unexpectedly")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(timings) != 2 {
+		t.Fatalf("got %d timings, want 2", len(timings))
+	}
+	if got := timings[0]; got.Method != methodCallTool || got.Session != cs || got.Err != nil || got.Latency() != time.Second {
+		t.Errorf("tools/call timing = %+v (latency %v), want method %q, latency 1s and no error", got, got.Latency(), methodCallTool)
+	}
+	if got := timings[1]; got.Method != methodGetPrompt || got.Err == nil || got.Latency() != 0 {
+		t.Errorf("prompts/get timing = %+v, want method %q, zero latency and an error", got, methodGetPrompt)
+	}
+}
+
+func TestCallToolFlow(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	type confirmArgs struct {
+		Token  string `json:"token"`
+		Answer string `json:"answer,omitempty"`
+	}
+	AddTool(server, &Tool{Name: "start"}, func(context.Context, *CallToolRequest, struct{}) (*CallToolResult, any, error) {
+		res := &CallToolResult{Content: []Content{&TextContent{Text: "started"}}}
+		res.SetContinuation(ToolContinuation{Tool: "confirm", Arguments: map[string]any{"token": "abc"}, Message: "Proceed?"})
+		return res, nil, nil
+	})
+	AddTool(server, &Tool{Name: "confirm"}, func(_ context.Context, _ *CallToolRequest, args confirmArgs) (*CallToolResult, any, error) {
+		return &CallToolResult{Content: []Content{&TextContent{Text: args.Token + ":" + args.Answer}}}, nil, nil
+	})
+	AddTool(server, &Tool{Name: "loop"}, func(context.Context, *CallToolRequest, struct{}) (*CallToolResult, any, error) {
+		res := &CallToolResult{}
+		res.SetContinuation(ToolContinuation{Tool: "loop"})
+		return res, nil, nil
+	})
+	AddTool(server, &Tool{Name: "begin"}, func(context.Context, *CallToolRequest, struct{}) (*CallToolResult, any, error) {
+		res := &CallToolResult{}
+		res.SetContinuation(ToolContinuation{Tool: "finish"})
+		return res, nil, nil
+	})
+	var finishArgs json.RawMessage
+	server.AddTool(&Tool{Name: "finish", InputSchema: &jsonschema.Schema{Type: "object"}}, func(_ context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		finishArgs = req.Params.Arguments
+		return &CallToolResult{}, nil
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	// Clients that don't follow continuations see an ordinary result.
+	res, err := cs.CallTool(ctx, &CallToolParams{Name: "start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := res.GetContinuation()
+	if !ok || c.Tool != "confirm" || c.Message != "Proceed?" || c.Arguments["token"] != "abc" {
+		t.Errorf("GetContinuation() = %+v, %t; want the confirm step", c, ok)
+	}
+
+	text := func(res *CallToolResult) string { return res.Content[0].(*TextContent).Text }
+	res, err = cs.CallToolFlow(ctx, &CallToolParams{Name: "start"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := text(res), "abc:"; got != want {
+		t.Errorf("CallToolFlow without next = %q, want %q", got, want)
+	}
+	if _, ok := res.GetContinuation(); ok {
+		t.Error("final result has a continuation")
+	}
+
+	res, err = cs.CallToolFlow(ctx, &CallToolParams{Name: "start"}, func(_ context.Context, _ *CallToolResult, c ToolContinuation) (any, error) {
+		c.Arguments["answer"] = "yes"
+		return c.Arguments, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := text(res), "abc:yes"; got != want {
+		t.Errorf("CallToolFlow with next = %q, want %q", got, want)
+	}
+
+	errStop := errors.New("stop")
+	res, err = cs.CallToolFlow(ctx, &CallToolParams{Name: "start"}, func(context.Context, *CallToolResult, ToolContinuation) (any, error) {
+		return nil, errStop
+	})
+	if !errors.Is(err, errStop) || text(res) != "started" {
+		t.Errorf("CallToolFlow stopped by next = %v, %v; want the first result and %v", res, err, errStop)
+	}
+
+	// A continuation without arguments is followed by a call without them,
+	// rather than one with null arguments.
+	if _, err := cs.CallToolFlow(ctx, &CallToolParams{Name: "begin"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(finishArgs), "{}"; got != want {
+		t.Errorf("continuation without arguments: got arguments %s, want %s", got, want)
+	}
+
+	if _, err := cs.CallToolFlow(ctx, &CallToolParams{Name: "loop"}, nil); err == nil {
+		t.Error("CallToolFlow with an endless flow succeeded unexpectedly")
+	}
+}
+
+func TestCallToolRawArguments(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	var got []string
+	server.AddTool(&Tool{Name: "echo", InputSchema: &jsonschema.Schema{Type: "object"}}, func(_ context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		got = append(got, string(req.Params.Arguments))
+		return &CallToolResult{}, nil
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	// These arguments would not survive a round trip through map[string]any:
+	// keys would be sorted and numbers converted to float64.
+	const args = `{"z":12345678901234567890,"a":1.50,"m":[1e3,"x"]}`
+	for _, raw := range []json.RawMessage{json.RawMessage(args), nil, {}} {
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "echo", Arguments: raw}); err != nil {
+			t.Fatalf("CallTool(%q): %v", raw, err)
+		}
+	}
+	want := []string{args, "{}", "{}"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("arguments received by server mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestClientSessionServerSupports(t *testing.T) {
+	type supports struct {
+		Tools, Prompts, Resources, Subscribe, Completions, Logging bool
+	}
+	check := func(cs *ClientSession) supports {
+		return supports{
+			Tools:       cs.ServerSupportsTools(),
+			Prompts:     cs.ServerSupportsPrompts(),
+			Resources:   cs.ServerSupportsResources(),
+			Subscribe:   cs.ServerSupportsSubscribe(),
+			Completions: cs.ServerSupportsCompletions(),
+			Logging:     cs.ServerSupportsLogging(),
+		}
+	}
+
+	// The zero session supports nothing.
+	if got := check(&ClientSession{}); got != (supports{}) {
+		t.Errorf("zero session: got %+v, want nothing supported", got)
+	}
+
+	tests := []struct {
+		name string
+		opts *ServerOptions
+		add  func(*Server)
+		want supports
+	}{
+		{"none", &ServerOptions{Capabilities: &ServerCapabilities{}}, nil, supports{}},
+		{
+			"tools and resources",
+			nil,
+			func(s *Server) {
+				AddTool(s, &Tool{Name: "greet"}, sayHi)
+				s.AddResource(&Resource{URI: "file:///info.txt", Name: "info"}, nil)
+			},
+			supports{Tools: true, Resources: true, Logging: true},
+		},
+		{
+			"subscribe",
+			&ServerOptions{
+				SubscribeHandler:   func(context.Context, *SubscribeRequest) error { return nil },
+				UnsubscribeHandler: func(context.Context, *UnsubscribeRequest) error { return nil },
+			},
+			func(s *Server) {
+				s.AddResource(&Resource{URI: "file:///info.txt", Name: "info"}, nil)
+			},
+			supports{Resources: true, Subscribe: true, Logging: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := NewServer(testImpl, test.opts)
+			if test.add != nil {
+				test.add(server)
+			}
+			cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+			defer cleanup()
+			if got := check(cs); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClientRequestReportProgress(t *testing.T) {
+	ctx := context.Background()
+	progress := make(chan *ProgressNotificationParams, 10)
+	server := NewServer(testImpl, &ServerOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *ProgressNotificationServerRequest) {
+			progress <- req.Params
+		},
+	})
+	client := NewClient(testImpl, &ClientOptions{
+		CreateMessageHandler: func(ctx context.Context, req *CreateMessageRequest) (*CreateMessageResult, error) {
+			if _, ok := ctx.Value(idContextKey{}).(jsonrpc.ID); !ok {
+				t.Error("handler context has no request ID")
+			}
+			for i := range 2 {
+				if err := req.ReportProgress(ctx, float64(i+1), 2, "sampling"); err != nil {
+					return nil, err
+				}
+			}
+			return &CreateMessageResult{Model: "m", Role: RoleAssistant, Content: &TextContent{Text: "hi"}}, nil
+		},
+	})
+	ct, st := NewInMemoryTransports()
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+	cs, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	params := &CreateMessageParams{MaxTokens: 10, Messages: []*SamplingMessage{{Role: RoleUser, Content: &TextContent{Text: "hello"}}}}
+	params.SetProgressToken("tok")
+	if _, err := ss.CreateMessage(ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		got := <-progress
+		want := &ProgressNotificationParams{ProgressToken: "tok", Progress: float64(i + 1), Total: 2, Message: "sampling"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("progress %d mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	// Without a progress token, ReportProgress does nothing.
+	params.SetMeta(nil)
+	if _, err := ss.CreateMessage(ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-progress:
+		t.Errorf("unexpected progress notification %+v", p)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClientSessionPingRoundTrip(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		const delay = 50 * time.Millisecond
+		server := NewServer(testImpl, nil)
+		server.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+			return func(ctx context.Context, method string, req Request) (Result, error) {
+				if method == methodPing {
+					time.Sleep(delay)
+				}
+				return next(ctx, method, req)
+			}
+		})
+		ct, st := NewInMemoryTransports()
+		if _, err := server.Connect(ctx, st, nil); err != nil {
+			t.Fatal(err)
+		}
+		var wire safeBuffer
+		cs, err := NewClient(testImpl, nil).Connect(ctx, &LoggingTransport{Transport: ct, Writer: &wire}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+
+		rtt, err := cs.PingRoundTrip(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rtt != delay {
+			t.Errorf("PingRoundTrip() = %v, want %v", rtt, delay)
+		}
+		// The ping is sent with empty params, not null or missing params.
+		if !strings.Contains(string(wire.Bytes()), `"method":"ping","params":{}`) {
+			t.Errorf("ping not sent with empty params; wire:\n%s", wire.Bytes())
+		}
+	})
+}
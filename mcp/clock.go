@@ -0,0 +1,43 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import "time"
+
+// A clock is a source of time for timeouts, keepalive and retry delays.
+//
+// Most tests control time with testing/synctest, but that is not possible
+// for tests that perform real I/O, such as those using httptest.Server. Such
+// tests can substitute a fake clock instead.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	AfterFunc(d time.Duration, f func()) timer
+	NewTicker(d time.Duration) ticker
+}
+
+// A timer is the subset of [time.Timer] created by [clock.AfterFunc].
+type timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// A ticker is a [time.Ticker] created by [clock.NewTicker].
+type ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is a clock that uses the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                            { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time    { return time.After(d) }
+func (realClock) AfterFunc(d time.Duration, f func()) timer { return time.AfterFunc(d, f) }
+func (realClock) NewTicker(d time.Duration) ticker          { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
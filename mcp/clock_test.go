@@ -0,0 +1,153 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock whose time advances only when Advance is called.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// fakeTimer is a timer or ticker created by a fakeClock.
+type fakeTimer struct {
+	c      *fakeClock
+	when   time.Time
+	active bool
+	f      func()         // for timers
+	period time.Duration  // for tickers
+	ch     chan time.Time // for tickers
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() { ch <- c.Now() })
+	return ch
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{c: c, when: c.now.Add(d), active: true, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{c: c, when: c.now.Add(d), active: true, period: d, ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return fakeTicker{t}
+}
+
+// fakeTicker adapts a periodic fakeTimer to the ticker interface.
+type fakeTicker struct{ t *fakeTimer }
+
+func (t fakeTicker) C() <-chan time.Time { return t.t.ch }
+func (t fakeTicker) Stop()               { t.t.Stop() }
+
+func (t *fakeTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	wasActive := t.active
+	t.active = true
+	t.when = t.c.now.Add(d)
+	return wasActive
+}
+
+// Advance advances the clock by d, firing timers and tickers that become due
+// in order. Timer functions are called synchronously.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if t.active && !t.when.After(target) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.now = next.when
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+			select {
+			case next.ch <- c.now:
+			default: // like time.Ticker, drop ticks for slow receivers
+			}
+			continue
+		}
+		next.active = false
+		c.mu.Unlock()
+		next.f()
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+func TestFakeClock(t *testing.T) {
+	c := newFakeClock()
+	start := c.Now()
+	var fired []string
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, "b") })
+	c.AfterFunc(time.Second, func() { fired = append(fired, "a") })
+	stopped := c.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	stopped.Stop()
+	tick := c.NewTicker(time.Second)
+	after := c.After(3 * time.Second)
+
+	c.Advance(1500 * time.Millisecond)
+	if got, want := len(fired), 1; got != want {
+		t.Fatalf("after 1.5s, %d timers fired, want %d", got, want)
+	}
+	select {
+	case <-tick.C():
+	default:
+		t.Error("ticker did not tick after 1.5s")
+	}
+	c.Advance(2 * time.Second)
+	if got, want := fired, []string{"a", "b"}; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("fired %v, want %v", got, want)
+	}
+	select {
+	case at := <-after:
+		if got, want := at.Sub(start), 3*time.Second; got != want {
+			t.Errorf("After fired at %v, want %v", got, want)
+		}
+	default:
+		t.Error("After did not fire after 3.5s")
+	}
+	if got, want := c.Now().Sub(start), 3500*time.Millisecond; got != want {
+		t.Errorf("Now() advanced by %v, want %v", got, want)
+	}
+}
@@ -55,6 +55,7 @@ func TestMain(m *testing.M) {
 var serverFuncs = map[string]func(){
 	"default":       runServer,
 	"cancelContext": runCancelContextServer,
+	"idle":          runIdleServer,
 }
 
 func runServer() {
@@ -77,6 +78,52 @@ func runCancelContextServer() {
 	}
 }
 
+func runIdleServer() {
+	server := mcp.NewServer(testImpl, nil)
+	if err := server.Run(context.Background(), &mcp.StdioTransport{IdleTimeout: 100 * time.Millisecond}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func TestStdioServerExit(t *testing.T) {
+	requireExec(t)
+
+	for _, test := range []struct {
+		server     string
+		closeStdin bool
+	}{
+		{"default", true}, // the server exits when its stdin is closed
+		{"idle", false},   // the server exits when idle, even with stdin open
+	} {
+		t.Run(test.server, func(t *testing.T) {
+			cmd := createServerCommand(t, test.server)
+			stdin, err := cmd.StdinPipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stdin.Close()
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("starting command: %v", err)
+			}
+			if test.closeStdin {
+				stdin.Close()
+			}
+
+			onExit := make(chan error, 1)
+			go func() { onExit <- cmd.Wait() }()
+			select {
+			case err := <-onExit:
+				if err != nil {
+					t.Errorf("server exited with %v, want success", err)
+				}
+			case <-time.After(5 * time.Second):
+				cmd.Process.Kill()
+				t.Fatal("server did not exit")
+			}
+		})
+	}
+}
+
 func TestServerRunContextCancel(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		server := mcp.NewServer(&mcp.Implementation{Name: "greeter", Version: "v0.0.1"}, nil)
@@ -10,6 +10,8 @@ package mcp
 import (
 	"encoding/json"
 	"fmt"
+	"iter"
+	"slices"
 
 	internaljson "github.com/modelcontextprotocol/go-sdk/internal/json"
 )
@@ -24,6 +26,24 @@ type Content interface {
 	fromWire(*wireContent)
 }
 
+// ContentsOfType returns an iterator over the elements of cs of type T, in
+// order. For example, ContentsOfType[*ImageContent](res.Content) yields the
+// images in a result.
+func ContentsOfType[T Content](cs []Content) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, c := range cs {
+			if t, ok := c.(T); ok && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// TextContents returns an iterator over the text content in cs, in order.
+func TextContents(cs []Content) iter.Seq[*TextContent] {
+	return ContentsOfType[*TextContent](cs)
+}
+
 // TextContent is a textual content.
 type TextContent struct {
 	Text        string
@@ -47,6 +67,17 @@ func (c *TextContent) MarshalJSON() ([]byte, error) {
 	return json.Marshal(wire)
 }
 
+// contentMIMETypeMetaKey is the _meta key for the MIME type of a
+// [TextContent].
+const contentMIMETypeMetaKey = MetaKeyPrefix + "mimeType"
+
+// MIMEType returns the MIME type of the text, recorded in the content's _meta,
+// or "" if it is unspecified. See also [Tool.SetContentMIMEType].
+func (c *TextContent) MIMEType() string {
+	mimeType, _ := c.Meta[contentMIMETypeMetaKey].(string)
+	return mimeType
+}
+
 func (c *TextContent) fromWire(wire *wireContent) {
 	c.Text = wire.Text
 	c.Meta = wire.Meta
@@ -297,6 +328,24 @@ func (c *ToolResultContent) fromWire(wire *wireContent) {
 	// Content is handled separately in contentFromWire due to nested content
 }
 
+// ToolResultFromCallResult returns a [ToolResultContent] for the tool use
+// with the given ID, holding the content, structured content and error
+// status of res. It is useful for feeding the result of a tool call back
+// into the next turn of a sampling loop.
+//
+// Deprecated: the sampling feature is deprecated as of protocol version
+// 2026-07-28 (SEP-2577). It remains functional during the deprecation window
+// (at least twelve months). See
+// https://modelcontextprotocol.io/seps/2577-deprecate-roots-sampling-and-logging.
+func ToolResultFromCallResult(toolUseID string, res *CallToolResult) *ToolResultContent {
+	return &ToolResultContent{
+		ToolUseID:         toolUseID,
+		Content:           slices.Clone(res.Content),
+		StructuredContent: res.StructuredContent,
+		IsError:           res.IsError,
+	}
+}
+
 // ResourceContents contains the contents of a specific resource or
 // sub-resource.
 type ResourceContents struct {
@@ -7,6 +7,7 @@ package mcp_test
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -243,3 +244,31 @@ func TestContentUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestContentsOfType(t *testing.T) {
+	image := &mcp.ImageContent{Data: []byte("a"), MIMEType: "image/png"}
+	contents := []mcp.Content{
+		&mcp.TextContent{Text: "one"},
+		image,
+		&mcp.TextContent{Text: "two"},
+		&mcp.ResourceLink{URI: "file:///a"},
+	}
+	var texts []string
+	for tc := range mcp.TextContents(contents) {
+		texts = append(texts, tc.Text)
+	}
+	if diff := cmp.Diff([]string{"one", "two"}, texts); diff != "" {
+		t.Errorf("TextContents mismatch (-want +got):\n%s", diff)
+	}
+	images := slices.Collect(mcp.ContentsOfType[*mcp.ImageContent](contents))
+	if len(images) != 1 || images[0] != image {
+		t.Errorf("ContentsOfType[*ImageContent] = %v, want [%v]", images, image)
+	}
+	if audio := slices.Collect(mcp.ContentsOfType[*mcp.AudioContent](contents)); len(audio) != 0 {
+		t.Errorf("ContentsOfType[*AudioContent] = %v, want none", audio)
+	}
+	// Stopping early is respected.
+	for range mcp.TextContents(contents) {
+		break
+	}
+}
@@ -6,11 +6,14 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 	"testing/synctest"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 )
@@ -266,3 +269,131 @@ func TestElicitationNoValidationWithoutAccept(t *testing.T) {
 		})
 	}
 }
+
+func TestElicitationInvalidAction(t *testing.T) {
+	ctx := context.Background()
+
+	for _, action := range []string{"", "maybe", "Accept"} {
+		t.Run(string(action), func(t *testing.T) {
+			ct, st := NewInMemoryTransports()
+			s := NewServer(testImpl, nil)
+			ss, err := s.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+
+			c := NewClient(testImpl, &ClientOptions{
+				ElicitationHandler: func(context.Context, *ElicitRequest) (*ElicitResult, error) {
+					return &ElicitResult{Action: action}, nil
+				},
+			})
+			cs, err := c.Connect(ctx, ct, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+
+			_, err = ss.Elicit(ctx, &ElicitParams{Message: "Continue?"})
+			if err == nil || !strings.Contains(err.Error(), "invalid elicitation action") {
+				t.Errorf("Elicit() error = %v, want invalid action error", err)
+			}
+		})
+	}
+
+	// A nil result is an error, not a panic.
+	t.Run("nil result", func(t *testing.T) {
+		ct, st := NewInMemoryTransports()
+		ss, err := NewServer(testImpl, nil).Connect(ctx, st, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ss.Close()
+		c := NewClient(testImpl, &ClientOptions{
+			ElicitationHandler: func(context.Context, *ElicitRequest) (*ElicitResult, error) {
+				return nil, nil
+			},
+		})
+		cs, err := c.Connect(ctx, ct, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+		if _, err := ss.Elicit(ctx, &ElicitParams{Message: "Continue?"}); err == nil || !strings.Contains(err.Error(), "missing elicitation result") {
+			t.Errorf("Elicit() error = %v, want missing result error", err)
+		}
+	})
+
+	for _, action := range []string{ElicitActionAccept, ElicitActionDecline, ElicitActionCancel} {
+		if err := checkElicitResult(&ElicitResult{Action: action}); err != nil {
+			t.Errorf("checkElicitResult(%q) = %v, want nil", action, err)
+		}
+	}
+	if err := checkElicitResult(nil); err == nil {
+		t.Error("checkElicitResult(nil) = nil, want error")
+	}
+	if !(&ElicitResult{Action: ElicitActionAccept}).Accepted() {
+		t.Error("Accepted() = false for accept action")
+	}
+	if (&ElicitResult{Action: ElicitActionDecline}).Accepted() {
+		t.Error("Accepted() = true for decline action")
+	}
+}
+
+func TestElicitationDefaultsOnDecline(t *testing.T) {
+	ctx := context.Background()
+
+	schema := &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"color": {Type: "string", Default: json.RawMessage(`"blue"`)},
+		},
+	}
+
+	testCases := []struct {
+		action      string
+		optIn       bool
+		wantContent map[string]any
+	}{
+		{ElicitActionAccept, false, map[string]any{"color": "blue"}},
+		{ElicitActionDecline, false, nil},
+		{ElicitActionCancel, false, nil},
+		{ElicitActionAccept, true, map[string]any{"color": "blue"}},
+		{ElicitActionDecline, true, map[string]any{"color": "blue"}},
+		{ElicitActionCancel, true, map[string]any{"color": "blue"}},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/optIn=%t", tc.action, tc.optIn), func(t *testing.T) {
+			ct, st := NewInMemoryTransports()
+			s := NewServer(testImpl, &ServerOptions{ElicitationDefaultsOnDecline: tc.optIn})
+			ss, err := s.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+
+			c := NewClient(testImpl, &ClientOptions{
+				ElicitationHandler: func(_ context.Context, req *ElicitRequest) (*ElicitResult, error) {
+					res := &ElicitResult{Action: tc.action}
+					if tc.action == ElicitActionAccept {
+						res.Content = map[string]any{}
+					}
+					return res, nil
+				},
+			})
+			cs, err := c.Connect(ctx, ct, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+
+			res, err := ss.Elicit(ctx, &ElicitParams{Message: "Pick a color", RequestedSchema: schema})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantContent, res.Content); diff != "" {
+				t.Errorf("Elicit() content mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -44,6 +44,15 @@ func (s *featureSet[T]) add(fs ...T) {
 	s.sortedKeys = nil
 }
 
+// clone returns a copy of the set. The features themselves are shared.
+func (s *featureSet[T]) clone() *featureSet[T] {
+	return &featureSet[T]{
+		uniqueID:   s.uniqueID,
+		features:   maps.Clone(s.features),
+		sortedKeys: s.sortedKeys, // never modified in place
+	}
+}
+
 // remove removes all features with the given uids from the set if present,
 // and returns whether any were removed.
 // It is not an error to remove a nonexistent feature.
@@ -29,15 +29,31 @@ const (
 	LevelEmergency = slog.LevelError + 12
 )
 
+// The logging levels of the MCP protocol, which are the syslog severities of
+// RFC 5424, in increasing order of severity.
+//
+// See also the corresponding [slog.Level] constants [LevelDebug] through
+// [LevelEmergency].
+const (
+	LoggingLevelDebug     LoggingLevel = "debug"
+	LoggingLevelInfo      LoggingLevel = "info"
+	LoggingLevelNotice    LoggingLevel = "notice"
+	LoggingLevelWarning   LoggingLevel = "warning"
+	LoggingLevelError     LoggingLevel = "error"
+	LoggingLevelCritical  LoggingLevel = "critical"
+	LoggingLevelAlert     LoggingLevel = "alert"
+	LoggingLevelEmergency LoggingLevel = "emergency"
+)
+
 var slogToMCP = map[slog.Level]LoggingLevel{
-	LevelDebug:     "debug",
-	LevelInfo:      "info",
-	LevelNotice:    "notice",
-	LevelWarning:   "warning",
-	LevelError:     "error",
-	LevelCritical:  "critical",
-	LevelAlert:     "alert",
-	LevelEmergency: "emergency",
+	LevelDebug:     LoggingLevelDebug,
+	LevelInfo:      LoggingLevelInfo,
+	LevelNotice:    LoggingLevelNotice,
+	LevelWarning:   LoggingLevelWarning,
+	LevelError:     LoggingLevelError,
+	LevelCritical:  LoggingLevelCritical,
+	LevelAlert:     LoggingLevelAlert,
+	LevelEmergency: LoggingLevelEmergency,
 }
 
 var mcpToSlog = make(map[LoggingLevel]slog.Level)
@@ -52,7 +68,7 @@ func slogLevelToMCP(sl slog.Level) LoggingLevel {
 	if ml, ok := slogToMCP[sl]; ok {
 		return ml
 	}
-	return "debug" // for lack of a better idea
+	return LoggingLevelDebug // for lack of a better idea
 }
 
 func mcpLevelToSlog(ll LoggingLevel) slog.Level {
@@ -63,9 +79,74 @@ func mcpLevelToSlog(ll LoggingLevel) slog.Level {
 	return LevelDebug
 }
 
-// compareLevels behaves like [cmp.Compare] for [LoggingLevel]s.
-func compareLevels(l1, l2 LoggingLevel) int {
-	return cmp.Compare(mcpLevelToSlog(l1), mcpLevelToSlog(l2))
+// Severity returns the numerical RFC 5424 severity of l, from 0 for
+// "emergency" to 7 for "debug". Note that lower values are more severe.
+//
+// Unknown levels are treated as "debug".
+func (l LoggingLevel) Severity() int {
+	switch l {
+	case LoggingLevelEmergency:
+		return 0
+	case LoggingLevelAlert:
+		return 1
+	case LoggingLevelCritical:
+		return 2
+	case LoggingLevelError:
+		return 3
+	case LoggingLevelWarning:
+		return 4
+	case LoggingLevelNotice:
+		return 5
+	case LoggingLevelInfo:
+		return 6
+	}
+	return 7
+}
+
+// Compare behaves like [cmp.Compare] for logging levels ordered from least to
+// most severe: it returns a negative number if l is less severe than other, a
+// positive number if it is more severe, and 0 if they are equally severe.
+//
+// For example, a message at level l meets the threshold set by
+// "logging/setLevel" if l.Compare(threshold) >= 0.
+//
+// Unknown levels are treated as "debug".
+func (l LoggingLevel) Compare(other LoggingLevel) int {
+	return cmp.Compare(other.Severity(), l.Severity())
+}
+
+// loggerKey is the _meta key for the logger name in a "logging/setLevel"
+// request.
+const loggerKey = MetaKeyPrefix + "logger"
+
+// SetLogger restricts the request to messages from the named logger (see
+// [LoggingMessageParams].Logger), so that a client can change the verbosity
+// of one subsystem without affecting others. The level for a named logger
+// overrides the session's level, which continues to apply to all other
+// loggers.
+//
+// Servers that do not support per-logger levels apply the level to all
+// loggers.
+func (x *SetLoggingLevelParams) SetLogger(name string) {
+	if x.Meta == nil {
+		x.Meta = Meta{}
+	}
+	x.Meta[loggerKey] = name
+}
+
+// GetLogger returns the logger name set by [SetLoggingLevelParams.SetLogger],
+// or "" if the request applies to all loggers.
+func (x *SetLoggingLevelParams) GetLogger() string {
+	name, _ := x.Meta[loggerKey].(string)
+	return name
+}
+
+// levelFor returns the logging level for messages from the named logger.
+func (s *ServerSessionState) levelFor(logger string) LoggingLevel {
+	if l, ok := s.LoggerLevels[logger]; ok {
+		return l
+	}
+	return s.LogLevel
 }
 
 // LoggingHandlerOptions are options for a LoggingHandler.
@@ -147,7 +228,7 @@ func (h *LoggingHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	// This is also checked in ServerSession.LoggingMessage, so checking it here
 	// is just an optimization that skips building the JSON.
 	h.ss.mu.Lock()
-	mcpLevel := h.ss.state.LogLevel
+	mcpLevel := h.ss.state.levelFor(h.opts.LoggerName)
 	h.ss.mu.Unlock()
 	return level >= mcpLevelToSlog(mcpLevel)
 }
@@ -822,6 +822,169 @@ R1 <roots/list
 	}
 }
 
+func TestChainMiddleware(t *testing.T) {
+	var calls []string
+	named := func(name string) Middleware {
+		return func(next MethodHandler) MethodHandler {
+			return func(ctx context.Context, method string, req Request) (Result, error) {
+				calls = append(calls, ">"+name)
+				defer func() { calls = append(calls, "<"+name) }()
+				return next(ctx, method, req)
+			}
+		}
+	}
+	base := func(context.Context, string, Request) (Result, error) {
+		calls = append(calls, "handler")
+		return nil, nil
+	}
+
+	for _, chain := range []func(...Middleware) Middleware{ChainReceiving, ChainSending} {
+		calls = nil
+		// Chaining (m1, m2) and then m3 is the same as (m1, m2, m3).
+		h := MethodHandler(base)
+		addMiddleware(&h, []Middleware{chain(named("m1"), named("m2")), named("m3")})
+		if _, err := h(context.Background(), "ping", nil); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{">m1", ">m2", ">m3", "handler", "<m3", "<m2", "<m1"}
+		if diff := cmp.Diff(want, calls); diff != "" {
+			t.Errorf("calls mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	// An empty chain is the identity.
+	calls = nil
+	if _, err := ChainReceiving()(base)(context.Background(), "ping", nil); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"handler"}, calls); diff != "" {
+		t.Errorf("empty chain mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(testImpl, nil)
+	var handled atomic.Int32
+	AddTool(s, &Tool{Name: "greet"}, sayHi)
+	s.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+		var (
+			mu     sync.Mutex
+			cached *ListToolsResult
+		)
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			if method != methodListTools {
+				return next(ctx, method, req)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if cached != nil {
+				return cached, nil // served without calling the server
+			}
+			res, err := next(ctx, method, req)
+			if err != nil {
+				return nil, err
+			}
+			handled.Add(1)
+			cached = res.(*ListToolsResult)
+			return cached, nil
+		}
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, s, nil)
+	defer cleanup()
+
+	for range 3 {
+		res, err := cs.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Tools) != 1 || res.Tools[0].Name != "greet" {
+			t.Fatalf("ListTools() = %v, want tool \"greet\"", res.Tools)
+		}
+	}
+	if got := handled.Load(); got != 1 {
+		t.Errorf("server handled tools/list %d times, want 1", got)
+	}
+}
+
+func TestMiddlewareCloneParams(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer(testImpl, nil)
+	var handlerMeta Meta
+	AddTool(s, &Tool{Name: "greet"}, func(_ context.Context, req *CallToolRequest, _ map[string]any) (*CallToolResult, any, error) {
+		handlerMeta = req.Params.Meta
+		return nil, nil, nil
+	})
+	// tagging returns middleware that sets a _meta key on a copy of the
+	// tools/call params.
+	tagging := func(key string) Middleware {
+		return func(next MethodHandler) MethodHandler {
+			return func(ctx context.Context, method string, req Request) (Result, error) {
+				call, ok := req.(*CallToolRequest)
+				if !ok {
+					return next(ctx, method, req)
+				}
+				params, err := CloneParams(call.Params)
+				if err != nil {
+					return nil, err
+				}
+				if params.Meta == nil {
+					params.Meta = Meta{}
+				}
+				params.Meta[key] = true
+				return next(ctx, method, &CallToolRequest{Session: call.Session, Params: params, Extra: call.Extra})
+			}
+		}
+	}
+	var outerMeta Meta
+	var outerResult, cloned *CallToolResult
+	s.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			res, err := next(ctx, method, req)
+			if call, ok := req.(*CallToolRequest); ok && err == nil {
+				outerMeta = call.Params.Meta
+				outerResult = res.(*CallToolResult)
+				cloned, err = CloneResult(outerResult)
+				if err != nil {
+					return nil, err
+				}
+				cloned.Content = append(cloned.Content, &TextContent{Text: "redacted"})
+				return cloned, nil
+			}
+			return res, err
+		}
+	}, tagging("first"), tagging("second"))
+	cs, _, cleanup := basicClientServerConnection(t, nil, s, nil)
+	defer cleanup()
+
+	res, err := cs.CallTool(ctx, &CallToolParams{Name: "greet", Arguments: map[string]any{}, Meta: Meta{"client": "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"first", "second"} {
+		if handlerMeta[key] != true {
+			t.Errorf("handler meta %q = %v, want true", key, handlerMeta[key])
+		}
+		if _, ok := outerMeta[key]; ok {
+			t.Errorf("original params were modified: meta has %q", key)
+		}
+	}
+	if handlerMeta["client"] != "x" || outerMeta["client"] != "x" {
+		t.Errorf("client meta lost: handler %v, original %v", handlerMeta, outerMeta)
+	}
+	if len(outerResult.Content) == len(cloned.Content) {
+		t.Errorf("modifying the cloned result changed the original")
+	}
+	if got := res.Content[len(res.Content)-1].(*TextContent).Text; got != "redacted" {
+		t.Errorf("client got last content %q, want %q", got, "redacted")
+	}
+
+	var nilParams *CallToolParams
+	if got, err := CloneParams(nilParams); err != nil || got != nil {
+		t.Errorf("CloneParams(nil) = %v, %v, want nil, nil", got, err)
+	}
+}
+
 type safeBuffer struct {
 	mu  sync.Mutex
 	buf bytes.Buffer
@@ -1275,6 +1438,29 @@ func TestElicitationSchemaValidation(t *testing.T) {
 			},
 			expectedError: "elicit schema must be of type 'object', got \"string\"",
 		},
+		{
+			name: "root $ref",
+			schema: &jsonschema.Schema{
+				Ref: "#/$defs/form",
+				Defs: map[string]*jsonschema.Schema{
+					"form": {Type: "object"},
+				},
+			},
+			expectedError: "elicit schema uses $ref \"#/$defs/form\", but elicitation schemas must be flat: inline the referenced schema",
+		},
+		{
+			name: "property $ref",
+			schema: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"name": {Ref: "#/$defs/name"},
+				},
+				Defs: map[string]*jsonschema.Schema{
+					"name": {Type: "string"},
+				},
+			},
+			expectedError: "elicit schema property \"name\" uses $ref \"#/$defs/name\", but elicitation schemas must be flat: inline the referenced schema",
+		},
 		{
 			name: "nested object property",
 			schema: &jsonschema.Schema{
@@ -1966,7 +2152,7 @@ func TestStartKeepalive_FailureThreshold(t *testing.T) {
 		sess := &scriptedKeepaliveSession{pingErrs: []error{errors.New("boom")}}
 		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 		var cancel context.CancelFunc
-		startKeepalive(sess, interval, 3, &cancel, logger)
+		startKeepalive(sess, realClock{}, interval, 3, &cancel, logger)
 		defer cancel()
 
 		// After two ticks → two failures, still below threshold 3: not closed.
@@ -1998,7 +2184,7 @@ func TestStartKeepalive_SuccessResetsFailures(t *testing.T) {
 		}}
 		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 		var cancel context.CancelFunc
-		startKeepalive(sess, interval, 3, &cancel, logger)
+		startKeepalive(sess, realClock{}, interval, 3, &cancel, logger)
 		defer cancel()
 
 		time.Sleep(6 * interval)
@@ -2273,7 +2459,9 @@ func TestComplete(t *testing.T) {
 		},
 	}
 	server := NewServer(testImpl, serverOpts)
-	cs, _, cleanup := basicClientServerConnection(t, nil, server, func(s *Server) {})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, func(s *Server) {
+		s.AddPrompt(&Prompt{Name: "code_review"}, nil)
+	})
 	defer cleanup()
 
 	result, err := cs.Complete(context.Background(), &CompleteParams{
@@ -2295,6 +2483,131 @@ func TestComplete(t *testing.T) {
 	}
 }
 
+func TestCompleteWithContext(t *testing.T) {
+	cities := map[string][]string{
+		"France":  {"Paris", "Lyon", "Lille"},
+		"Germany": {"Berlin", "Leipzig"},
+	}
+	server := NewServer(testImpl, &ServerOptions{
+		CompletionHandler: func(_ context.Context, req *CompleteRequest) (*CompleteResult, error) {
+			var values []string
+			switch req.Params.Argument.Name {
+			case "country":
+				for c := range cities {
+					values = append(values, c)
+				}
+			case "city":
+				// Complete cities of the previously chosen country, if any.
+				if ctx := req.Params.Context; ctx != nil {
+					for _, c := range cities[ctx.Arguments["country"]] {
+						if strings.HasPrefix(c, req.Params.Argument.Value) {
+							values = append(values, c)
+						}
+					}
+				}
+			}
+			slices.Sort(values)
+			return &CompleteResult{Completion: CompletionResultDetails{Values: values}}, nil
+		},
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, func(s *Server) {
+		s.AddPrompt(&Prompt{Name: "weather", Arguments: []*PromptArgument{{Name: "country"}, {Name: "city"}}}, nil)
+	})
+	defer cleanup()
+
+	for _, tt := range []struct {
+		country, prefix string
+		want            []string
+	}{
+		{"France", "L", []string{"Lille", "Lyon"}},
+		{"Germany", "L", []string{"Leipzig"}},
+		{"", "L", nil},
+	} {
+		params := &CompleteParams{
+			Ref:      &CompleteReference{Type: "ref/prompt", Name: "weather"},
+			Argument: CompleteParamsArgument{Name: "city", Value: tt.prefix},
+		}
+		if tt.country != "" {
+			params.Context = &CompleteContext{Arguments: map[string]string{"country": tt.country}}
+		}
+		res, err := cs.Complete(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(tt.want, res.Completion.Values); diff != "" {
+			t.Errorf("completing city in %q: mismatch (-want +got):\n%s", tt.country, diff)
+		}
+	}
+}
+
+func TestCompleteReferenceValidation(t *testing.T) {
+	for _, rejectUnknown := range []bool{false, true} {
+		t.Run(fmt.Sprintf("reject=%t", rejectUnknown), func(t *testing.T) {
+			var called int
+			server := NewServer(testImpl, &ServerOptions{
+				CompletionHandler: func(context.Context, *CompleteRequest) (*CompleteResult, error) {
+					called++
+					return &CompleteResult{}, nil
+				},
+				RejectUnknownCompletionReferences: rejectUnknown,
+				PromptFilter: func(_ context.Context, _ *ServerSession, p *Prompt) bool {
+					return p.Name != "secret"
+				},
+				ResourceTemplateFilter: func(_ context.Context, _ *ServerSession, rt *ResourceTemplate) bool {
+					return rt.Name != "secret"
+				},
+			})
+			cs, _, cleanup := basicClientServerConnection(t, nil, server, func(s *Server) {
+				s.AddPrompt(&Prompt{Name: "greet"}, nil)
+				s.AddPrompt(&Prompt{Name: "secret"}, nil)
+				s.AddResource(&Resource{URI: "file:///readme", Name: "readme"}, nil)
+				s.AddResourceTemplate(&ResourceTemplate{URITemplate: "file:///docs/{name}", Name: "docs"}, nil)
+				s.AddResourceTemplate(&ResourceTemplate{URITemplate: "file:///secret/{name}", Name: "secret"}, nil)
+			})
+			defer cleanup()
+
+			unknownErr := func(msg string) string {
+				if rejectUnknown {
+					return msg
+				}
+				return ""
+			}
+			for _, tt := range []struct {
+				ref     CompleteReference
+				wantErr string // if empty, the handler is called
+			}{
+				{CompleteReference{Type: "ref/prompt", Name: "greet"}, ""},
+				{CompleteReference{Type: "ref/resource", URI: "file:///readme"}, ""},
+				{CompleteReference{Type: "ref/resource", URI: "file:///docs/{name}"}, ""},
+				{CompleteReference{Type: "ref/resource", URI: "file:///docs/intro"}, ""},
+				{CompleteReference{Type: "ref/prompt", Name: "secret"}, `unknown prompt "secret"`},
+				{CompleteReference{Type: "ref/resource", URI: "file:///secret/key"}, `unknown resource "file:///secret/key"`},
+				{CompleteReference{Type: "ref/prompt", Name: "unknown"}, unknownErr(`unknown prompt "unknown"`)},
+				{CompleteReference{Type: "ref/resource", URI: "file:///other"}, unknownErr(`unknown resource "file:///other"`)},
+			} {
+				called = 0
+				_, err := cs.Complete(context.Background(), &CompleteParams{
+					Argument: CompleteParamsArgument{Name: "name", Value: "x"},
+					Ref:      &tt.ref,
+				})
+				if tt.wantErr == "" {
+					if err != nil || called != 1 {
+						t.Errorf("Complete(%+v): got err %v and %d handler calls, want success", tt.ref, err, called)
+					}
+					continue
+				}
+				var rpcErr *jsonrpc.Error
+				if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeInvalidParams || !strings.Contains(rpcErr.Message, tt.wantErr) {
+					t.Errorf("Complete(%+v): got %v, want invalid params error containing %q", tt.ref, err, tt.wantErr)
+				}
+				if called != 0 {
+					t.Errorf("Complete(%+v): handler called for invalid reference", tt.ref)
+				}
+			}
+		})
+	}
+}
+
 // TestEmbeddedStructResponse performs a tool call to verify that a struct with
 // an embedded pointer generates a correct, flattened JSON schema and that its
 // response is validated successfully.
@@ -3375,3 +3688,471 @@ func TestCallCustomMethodTypedNilParams(t *testing.T) {
 		t.Fatalf("CallCustomMethod with typed-nil params: %v", err)
 	}
 }
+
+func TestLoggingLevelCompare(t *testing.T) {
+	levels := []LoggingLevel{
+		LoggingLevelDebug,
+		LoggingLevelInfo,
+		LoggingLevelNotice,
+		LoggingLevelWarning,
+		LoggingLevelError,
+		LoggingLevelCritical,
+		LoggingLevelAlert,
+		LoggingLevelEmergency,
+	}
+	for i, l := range levels {
+		if got, want := l.Severity(), 7-i; got != want {
+			t.Errorf("%s.Severity() = %d, want %d", l, got, want)
+		}
+		if got := slogLevelToMCP(mcpLevelToSlog(l)); got != l {
+			t.Errorf("slog round trip of %s = %s", l, got)
+		}
+		for j, other := range levels {
+			if got, want := l.Compare(other), min(max(i-j, -1), 1); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", l, other, got, want)
+			}
+		}
+	}
+	if got := LoggingLevel("verbose").Compare(LoggingLevelDebug); got != 0 {
+		t.Errorf("unknown level compared to debug = %d, want 0", got)
+	}
+}
+
+func TestPerLoggerLevels(t *testing.T) {
+	ctx := context.Background()
+	messages := make(chan *LoggingMessageParams, 20)
+	client := NewClient(testImpl, &ClientOptions{
+		LoggingMessageHandler: func(_ context.Context, req *LoggingMessageRequest) {
+			messages <- req.Params
+		},
+	})
+	cs, ss, cleanup := basicClientServerConnection(t, client, nil, nil)
+	defer cleanup()
+
+	// received logs a message from each of the db and http loggers at each
+	// level, followed by a sentinel, and returns the messages the client
+	// received.
+	received := func() []string {
+		t.Helper()
+		for _, logger := range []string{"db", "http"} {
+			for _, level := range []LoggingLevel{LoggingLevelDebug, LoggingLevelInfo, LoggingLevelError} {
+				if err := ss.Log(ctx, &LoggingMessageParams{Logger: logger, Level: level, Data: "x"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		// The sentinel is sent only if a level is set for the "end" logger.
+		if err := ss.Log(ctx, &LoggingMessageParams{Logger: "end", Level: LoggingLevelEmergency, Data: "x"}); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for m := range messages {
+			if m.Logger == "end" {
+				return got
+			}
+			got = append(got, m.Logger+":"+string(m.Level))
+		}
+		return got
+	}
+	setLevel := func(logger string, level LoggingLevel) {
+		t.Helper()
+		params := &SetLoggingLevelParams{Level: level}
+		if logger != "" {
+			params.SetLogger(logger)
+		}
+		if err := cs.SetLoggingLevel(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A level for one logger doesn't enable the others.
+	setLevel("end", LoggingLevelEmergency)
+	setLevel("db", LoggingLevelDebug)
+	if diff := cmp.Diff([]string{"db:debug", "db:info", "db:error"}, received()); diff != "" {
+		t.Errorf("with db level only: mismatch (-want +got):\n%s", diff)
+	}
+
+	// The session level applies to loggers without their own level.
+	setLevel("", LoggingLevelError)
+	if diff := cmp.Diff([]string{"db:debug", "db:info", "db:error", "http:error"}, received()); diff != "" {
+		t.Errorf("with session level: mismatch (-want +got):\n%s", diff)
+	}
+
+	// Logger levels can be raised as well as lowered.
+	setLevel("db", LoggingLevelEmergency)
+	if diff := cmp.Diff([]string{"http:error"}, received()); diff != "" {
+		t.Errorf("with quiet db: mismatch (-want +got):\n%s", diff)
+	}
+
+	ss.mu.Lock()
+	got := ss.state.LoggerLevels
+	ss.mu.Unlock()
+	if got["db"] != LoggingLevelEmergency || got["end"] != LoggingLevelEmergency {
+		t.Errorf("session state logger levels = %v", got)
+	}
+}
+
+func TestLogDataChecks(t *testing.T) {
+	ctx := context.Background()
+	messages := make(chan *LoggingMessageParams, 10)
+	client := NewClient(testImpl, &ClientOptions{
+		LoggingMessageHandler: func(_ context.Context, req *LoggingMessageRequest) {
+			messages <- req.Params
+		},
+	})
+	var logBuf safeBuffer
+	server := NewServer(testImpl, &ServerOptions{
+		MaxLogDataSize: 20,
+		Logger:         slog.New(slog.NewTextHandler(&logBuf, nil)),
+	})
+	cs, ss, cleanup := basicClientServerConnection(t, client, server, nil)
+	defer cleanup()
+	if err := cs.SetLoggingLevel(ctx, &SetLoggingLevelParams{Level: LoggingLevelInfo}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range []any{
+		"small",
+		func() {}, // unmarshalable: dropped
+		strings.Repeat("x", 100),
+		"last",
+	} {
+		if err := ss.Log(ctx, &LoggingMessageParams{Level: LoggingLevelInfo, Data: data}); err != nil {
+			t.Fatalf("Log(%T): %v", data, err)
+		}
+	}
+	var got []any
+	for m := range messages {
+		got = append(got, m.Data)
+		if m.Data == "last" {
+			break
+		}
+	}
+	want := []any{"small", `"xxxxxxxxxxxxxxxxxxx...(truncated)`, "last"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("log data mismatch (-want +got):\n%s", diff)
+	}
+	serverLog := string(logBuf.Bytes())
+	for _, msg := range []string{"dropping log message", "truncating log message"} {
+		if !strings.Contains(serverLog, msg) {
+			t.Errorf("server log does not contain %q:\n%s", msg, serverLog)
+		}
+	}
+}
+
+func TestSessionCancelAll(t *testing.T) {
+	ctx := context.Background()
+
+	// blocker is a handler body that reports that it has started, then waits
+	// for its context to be cancelled.
+	type blocker struct {
+		started, cancelled chan struct{}
+	}
+	newBlocker := func() blocker { return blocker{make(chan struct{}), make(chan struct{})} }
+	block := func(ctx context.Context, b blocker) error {
+		close(b.started)
+		<-ctx.Done()
+		close(b.cancelled)
+		return ctx.Err()
+	}
+
+	toolBlock := newBlocker()
+	sampleBlock := newBlocker()
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "block", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, _ *CallToolRequest) (*CallToolResult, error) {
+		return nil, block(ctx, toolBlock)
+	})
+	client := NewClient(testImpl, &ClientOptions{
+		CreateMessageHandler: func(ctx context.Context, _ *CreateMessageRequest) (*CreateMessageResult, error) {
+			return nil, block(ctx, sampleBlock)
+		},
+	})
+	cs, ss, cleanup := basicClientServerConnection(t, client, server, nil)
+	defer cleanup()
+
+	toolErr := make(chan error, 1)
+	go func() {
+		_, err := cs.CallTool(ctx, &CallToolParams{Name: "block"})
+		toolErr <- err
+	}()
+	sampleErr := make(chan error, 1)
+	go func() {
+		_, err := ss.CreateMessage(ctx, &CreateMessageParams{})
+		sampleErr <- err
+	}()
+	<-toolBlock.started
+	<-sampleBlock.started
+
+	// Cancelling on the server cancels both the client's tool call, which the
+	// server is handling, and the server's sampling request, which the client
+	// is notified of.
+	if err := ss.CancelAll(); err != nil {
+		t.Fatal(err)
+	}
+	<-toolBlock.cancelled
+	<-sampleBlock.cancelled
+	if err := <-sampleErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateMessage returned %v, want context.Canceled", err)
+	}
+	if err := <-toolErr; err == nil {
+		t.Errorf("CallTool succeeded unexpectedly")
+	}
+
+	// The session remains usable.
+	if err := ss.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancelling on the client cancels its outgoing tool call.
+	toolBlock = newBlocker()
+	go func() {
+		_, err := cs.CallTool(ctx, &CallToolParams{Name: "block"})
+		toolErr <- err
+	}()
+	<-toolBlock.started
+	if err := cs.CancelAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-toolErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("CallTool returned %v, want context.Canceled", err)
+	}
+	<-toolBlock.cancelled
+	if err := cs.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionEstablishedHandler(t *testing.T) {
+	for _, version := range []string{protocolVersion20251125, protocolVersion20260728} {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+			serverInfos := make(chan *SessionInfo, 1)
+			server := NewServer(&Implementation{Name: "server", Version: "v1"}, &ServerOptions{
+				SessionEstablishedHandler: func(_ context.Context, info *SessionInfo) { serverInfos <- info },
+			})
+			AddTool(server, &Tool{Name: "greet"}, sayHi)
+			var clientInfo *SessionInfo
+			client := NewClient(&Implementation{Name: "client", Version: "v2"}, &ClientOptions{
+				SessionEstablishedHandler: func(_ context.Context, info *SessionInfo) { clientInfo = info },
+			})
+
+			ct, st := NewInMemoryTransports()
+			ss, err := server.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+			cs, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: version})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+
+			if clientInfo == nil {
+				t.Fatal("client SessionEstablishedHandler not called")
+			}
+			if clientInfo.Session != cs || clientInfo.ProtocolVersion != version ||
+				clientInfo.ClientInfo.Name != "client" || clientInfo.ServerInfo.Name != "server" ||
+				clientInfo.ServerCapabilities.Tools == nil || clientInfo.ClientCapabilities == nil {
+				t.Errorf("client session info = %+v", clientInfo)
+			}
+
+			// With the new protocol, there is no handshake: the server
+			// establishes the session when the client calls server/discover.
+			info := <-serverInfos
+			if info.Session != ss {
+				t.Errorf("server session info: Session = %v, want %v", info.Session, ss)
+			}
+			// Both sides agree on the session.
+			if diff := cmp.Diff(clientInfo, info, cmpopts.IgnoreFields(SessionInfo{}, "Session")); diff != "" {
+				t.Errorf("session info mismatch (-client +server):\n%s", diff)
+			}
+
+			var buf bytes.Buffer
+			slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})).Info("established", "session", info)
+			want := "level=INFO msg=established session.session_id=\"\" session.protocol_version=" + version +
+				" session.client.name=client session.client.version=v2 session.server.name=server session.server.version=v1\n"
+			if got := buf.String(); got != want {
+				t.Errorf("log output:\ngot  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestNoEmptyMetaOnWire(t *testing.T) {
+	ctx := context.Background()
+	var ct, st Transport = NewInMemoryTransports()
+	var logbuf safeBuffer
+	ct = &LoggingTransport{Transport: ct, Writer: &logbuf}
+
+	// Middleware on both sides leaves empty metadata on everything it sees.
+	clearMeta := func(h MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			if p := req.GetParams(); p != nil && !p.isNil() {
+				p.SetMeta(map[string]any{})
+			}
+			res, err := h(ctx, method, req)
+			if res != nil {
+				if _, ok := res.(*emptyResult); !ok {
+					res.SetMeta(nil)
+				}
+			}
+			return res, err
+		}
+	}
+	s := NewServer(testImpl, nil)
+	AddTool(s, &Tool{Name: "greet"}, sayHi)
+	s.AddSendingMiddleware(clearMeta)
+	s.AddReceivingMiddleware(clearMeta)
+	ss, err := s.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(testImpl, nil)
+	c.AddSendingMiddleware(clearMeta)
+	c.AddReceivingMiddleware(clearMeta)
+	cs, err := c.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.ListTools(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.CallTool(ctx, &CallToolParams{Name: "greet", Arguments: map[string]any{"Name": "user"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ss.ListRoots(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+	ss.Wait()
+
+	logs := logbuf.Bytes()
+	for _, bad := range []string{`"_meta":{}`, `"_meta":null`} {
+		if bytes.Contains(logs, []byte(bad)) {
+			t.Errorf("MCP logs contain %s:\n%s", bad, logs)
+		}
+	}
+}
+
+func TestCancellationReason(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	causes := make(chan error, 1)
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "block", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, _ *CallToolRequest) (*CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil, ctx.Err()
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	callCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		<-started
+		cancel(errors.New("user pressed stop"))
+	}()
+	if _, err := cs.CallTool(callCtx, &CallToolParams{Name: "block"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CallTool error = %v, want context.Canceled", err)
+	}
+
+	cause := <-causes
+	var rce *RequestCancelledError
+	if !errors.As(cause, &rce) || rce.Reason != "user pressed stop" {
+		t.Errorf("handler context cause = %v, want RequestCancelledError with reason %q", cause, "user pressed stop")
+	}
+	if !errors.Is(cause, context.Canceled) {
+		t.Errorf("handler context cause %v does not wrap context.Canceled", cause)
+	}
+}
+
+func TestWatchResource(t *testing.T) {
+	for _, version := range []string{protocolVersion20251125, protocolVersion20260728} {
+		t.Run(version, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			subCh := make(chan string, 8)
+			unsubCh := make(chan string, 8)
+			server := resourceSubServer(t, subCh, unsubCh)
+			var reads atomic.Int32
+			server.AddResource(&Resource{Name: "counter", URI: "file:///counter"}, func(_ context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+				n := reads.Add(1)
+				return &ReadResourceResult{Contents: []*ResourceContents{{URI: req.Params.URI, Text: fmt.Sprint(n)}}}, nil
+			})
+			ct, st := NewInMemoryTransports()
+			ss, err := server.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+			cs, err := NewClient(testImpl, nil).Connect(ctx, ct, &ClientSessionOptions{protocolVersion: version})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+
+			watchCtx, stopWatch := context.WithCancel(ctx)
+			updates, err := cs.WatchResource(watchCtx, "file:///counter")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := <-subCh; got != "file:///counter" {
+				t.Fatalf("subscribed to %q, want file:///counter", got)
+			}
+			for _, want := range []string{"1", "2"} {
+				if err := server.ResourceUpdated(ctx, &ResourceUpdatedNotificationParams{URI: "file:///counter"}); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case res := <-updates:
+					if got := res.Contents[0].Text; got != want {
+						t.Errorf("got contents %q, want %q", got, want)
+					}
+				case <-ctx.Done():
+					t.Fatal("timed out waiting for update")
+				}
+			}
+
+			stopWatch()
+			for range updates {
+			}
+			if version == protocolVersion20251125 {
+				if got := <-unsubCh; got != "file:///counter" {
+					t.Errorf("unsubscribed from %q, want file:///counter", got)
+				}
+			}
+		})
+	}
+}
+
+func TestWatchResourceSessionClose(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server := resourceSubServer(t, make(chan string, 8), make(chan string, 8))
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	updates, err := cs.WatchResource(ctx, "file:///r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.Close()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Error("got update after Close")
+		}
+	case <-ctx.Done():
+		t.Fatal("channel not closed after Close")
+	}
+}
@@ -6,6 +6,9 @@ package mcp
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 )
 
 // A PromptHandler handles a call to prompts/get.
@@ -15,3 +18,83 @@ type serverPrompt struct {
 	prompt  *Prompt
 	handler PromptHandler
 }
+
+// UserMessage returns prompt messages with role "user", one for each
+// content item.
+func UserMessage(content ...Content) []*PromptMessage {
+	return promptMessages(RoleUser, content)
+}
+
+// AssistantMessage returns prompt messages with role "assistant", one for
+// each content item.
+func AssistantMessage(content ...Content) []*PromptMessage {
+	return promptMessages(RoleAssistant, content)
+}
+
+func promptMessages(role Role, content []Content) []*PromptMessage {
+	msgs := make([]*PromptMessage, 0, len(content))
+	for _, c := range content {
+		msgs = append(msgs, &PromptMessage{Role: role, Content: c})
+	}
+	return msgs
+}
+
+// A GetPromptResultBuilder builds a [GetPromptResult].
+// Create one with [NewGetPromptResult], add messages, and finish with
+// [GetPromptResultBuilder.Result], whose return values match those of a
+// [PromptHandler]:
+//
+//	return mcp.NewGetPromptResult("Greeting").
+//		User(&mcp.TextContent{Text: "Say hi to " + name}).
+//		Result()
+//
+// Using a builder is optional; a GetPromptResult literal works just as well.
+type GetPromptResultBuilder struct {
+	res  GetPromptResult
+	errs []error
+}
+
+// NewGetPromptResult returns a builder for a [GetPromptResult] with the given
+// description, which may be empty.
+func NewGetPromptResult(description string) *GetPromptResultBuilder {
+	return &GetPromptResultBuilder{res: GetPromptResult{Description: description}}
+}
+
+// User appends a message with role "user" for each content item.
+func (b *GetPromptResultBuilder) User(content ...Content) *GetPromptResultBuilder {
+	return b.Message(RoleUser, content...)
+}
+
+// Assistant appends a message with role "assistant" for each content item.
+func (b *GetPromptResultBuilder) Assistant(content ...Content) *GetPromptResultBuilder {
+	return b.Message(RoleAssistant, content...)
+}
+
+// Message appends a message with the given role for each content item.
+// An unknown role or nil content is reported by [GetPromptResultBuilder.Result].
+func (b *GetPromptResultBuilder) Message(role Role, content ...Content) *GetPromptResultBuilder {
+	if role != RoleUser && role != RoleAssistant {
+		b.errs = append(b.errs, fmt.Errorf("invalid prompt message role %q: want %q or %q", role, RoleUser, RoleAssistant))
+	}
+	for i, c := range content {
+		if c == nil {
+			b.errs = append(b.errs, fmt.Errorf("%s message %d: nil content", role, len(b.res.Messages)+i))
+		}
+	}
+	b.res.Messages = append(b.res.Messages, promptMessages(role, content)...)
+	return b
+}
+
+// Result returns the built result, or an error if any message was invalid.
+// Each call returns a new result, so a builder may be used as a template.
+func (b *GetPromptResultBuilder) Result() (*GetPromptResult, error) {
+	if err := errors.Join(b.errs...); err != nil {
+		return nil, err
+	}
+	res := b.res
+	res.Messages = slices.Clone(b.res.Messages)
+	if res.Messages == nil {
+		res.Messages = []*PromptMessage{}
+	}
+	return &res, nil
+}
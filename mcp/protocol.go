@@ -6,6 +6,7 @@ package mcp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 
@@ -203,6 +204,10 @@ type CallToolParams struct {
 	Name string `json:"name"`
 	// Arguments holds the tool arguments. It can hold any value that can be
 	// marshaled to JSON.
+	//
+	// Arguments that are already serialized can be passed as a
+	// [json.RawMessage], which is sent as is, without being decoded and
+	// re-encoded. It must hold a JSON object; an empty RawMessage is sent as {}.
 	Arguments any `json:"arguments,omitempty"`
 
 	// InputResponses maps input request IDs to responses, provided when
@@ -252,7 +257,8 @@ type CallToolResult struct {
 	//
 	// When using a [ToolHandlerFor] with structured output, if Content is unset
 	// it will be populated with JSON text content corresponding to the
-	// structured output value.
+	// structured output value, unless the tool is marked with
+	// [Tool.SetStructuredContentOnly].
 	Content []Content `json:"content"`
 
 	// StructuredContent is an optional value that represents the structured
@@ -337,6 +343,21 @@ func (r *CallToolResult) GetError() error {
 	return r.err
 }
 
+// DisplayContent returns the content to show for the result. It is Content,
+// unless Content is empty and the result has structured content, as for tools
+// marked with [Tool.SetStructuredContentOnly]. In that case, DisplayContent
+// returns a single [TextContent] holding the JSON of the structured content.
+func (r *CallToolResult) DisplayContent() []Content {
+	if len(r.Content) > 0 || r.StructuredContent == nil {
+		return r.Content
+	}
+	data, err := json.Marshal(r.StructuredContent)
+	if err != nil {
+		return r.Content
+	}
+	return []Content{&TextContent{Text: string(data)}}
+}
+
 func (*CallToolResult) isResult() {}
 
 func (r *CallToolResult) setResultType(rt resultType) { r.resultType = rt }
@@ -405,6 +426,34 @@ func (x *CallToolParamsRaw) isNil() bool            { return x == nil }
 func (x *CallToolParamsRaw) GetProgressToken() any  { return getProgressToken(x) }
 func (x *CallToolParamsRaw) SetProgressToken(t any) { setProgressToken(x, t) }
 
+// GetIdempotencyKey returns the idempotency key of the call, or "" if none.
+// See [ServerOptions.IdempotencyKeyTTL].
+func (x *CallToolParams) GetIdempotencyKey() string { return getIdempotencyKey(x) }
+
+// SetIdempotencyKey sets the idempotency key of the call. Calls with the same
+// key are treated as retries of one another by servers that support
+// idempotency keys. See [ServerOptions.IdempotencyKeyTTL].
+func (x *CallToolParams) SetIdempotencyKey(key string) { setIdempotencyKey(x, key) }
+
+// GetIdempotencyKey returns the idempotency key of the call, or "" if none.
+// See [ServerOptions.IdempotencyKeyTTL].
+func (x *CallToolParamsRaw) GetIdempotencyKey() string { return getIdempotencyKey(x) }
+
+// GetDryRun reports whether the call requests a dry run. See
+// [CallToolParams.SetDryRun].
+func (x *CallToolParams) GetDryRun() bool { return getDryRun(x) }
+
+// SetDryRun sets whether the call requests a dry run: the tool validates its
+// arguments and reports what it would do, without side effects. Servers
+// reject dry runs of tools that do not support them; see
+// [Tool.SetSupportsDryRun].
+func (x *CallToolParams) SetDryRun(dryRun bool) { setDryRun(x, dryRun) }
+
+// GetDryRun reports whether the call requests a dry run. Tool handlers that
+// support dry runs must not perform side effects when it reports true. See
+// [Tool.SetSupportsDryRun].
+func (x *CallToolParamsRaw) GetDryRun() bool { return getDryRun(x) }
+
 type CancelledParams struct {
 	// This property is reserved by the protocol to allow clients and servers to
 	// attach additional metadata to their responses.
@@ -748,6 +797,49 @@ func (p *CreateMessageWithToolsParams) toBase() (*CreateMessageParams, error) {
 	}, nil
 }
 
+// ValidateToolResults reports an error if a [ToolResultContent] in the
+// messages does not refer to a [ToolUseContent] in an earlier message.
+// See also [ServerOptions.ValidateSamplingToolResults].
+func (p *CreateMessageWithToolsParams) ValidateToolResults() error {
+	var contents [][]Content
+	for _, m := range p.Messages {
+		contents = append(contents, m.Content)
+	}
+	return validateToolResults(contents)
+}
+
+// ValidateToolResults reports an error if a [ToolResultContent] in the
+// messages does not refer to a [ToolUseContent] in an earlier message.
+// See also [ServerOptions.ValidateSamplingToolResults].
+func (p *CreateMessageParams) ValidateToolResults() error {
+	var contents [][]Content
+	for _, m := range p.Messages {
+		contents = append(contents, []Content{m.Content})
+	}
+	return validateToolResults(contents)
+}
+
+// validateToolResults checks that every tool result in the messages, given as
+// their content, refers to a tool use in an earlier message.
+func validateToolResults(messages [][]Content) error {
+	toolUseIDs := map[string]bool{}
+	for i, content := range messages {
+		for _, c := range content {
+			if r, ok := c.(*ToolResultContent); ok && !toolUseIDs[r.ToolUseID] {
+				return fmt.Errorf("message %d: tool_result with toolUseId %q does not follow a tool_use with that ID", i, r.ToolUseID)
+			}
+		}
+		// Record tool uses after checking results, so that a result cannot
+		// refer to a use in the same message.
+		for _, c := range content {
+			if u, ok := c.(*ToolUseContent); ok {
+				toolUseIDs[u.ID] = true
+			}
+		}
+	}
+	return nil
+}
+
 // SamplingMessageV2 describes a message issued to or received from an
 // LLM API, supporting array content for parallel tool calls. The "V2" refers
 // to the 2025-11-25 spec, which changed content from a single block to
@@ -1492,6 +1584,10 @@ type Prompt struct {
 	Icons []Icon `json:"icons,omitempty"`
 }
 
+// DisplayName returns the name to show for the prompt in a user interface: its
+// Title if set, otherwise its Name.
+func (p *Prompt) DisplayName() string { return displayName(p.Title, p.Name) }
+
 // Describes an argument that a prompt can accept.
 type PromptArgument struct {
 	// Intended for programmatic or logical use, but used as a display name in past
@@ -1506,6 +1602,10 @@ type PromptArgument struct {
 	Required bool `json:"required,omitempty"`
 }
 
+// DisplayName returns the name to show for the argument in a user interface:
+// its Title if set, otherwise its Name.
+func (a *PromptArgument) DisplayName() string { return displayName(a.Title, a.Name) }
+
 type PromptListChangedParams struct {
 	// This property is reserved by the protocol to allow clients and servers to
 	// attach additional metadata to their responses.
@@ -1670,6 +1770,10 @@ type Resource struct {
 	Icons []Icon `json:"icons,omitempty"`
 }
 
+// DisplayName returns the name to show for the resource in a user interface:
+// its Title if set, otherwise its Name.
+func (r *Resource) DisplayName() string { return displayName(r.Title, r.Name) }
+
 type ResourceListChangedParams struct {
 	// This property is reserved by the protocol to allow clients and servers to
 	// attach additional metadata to their responses.
@@ -1713,9 +1817,19 @@ type ResourceTemplate struct {
 	Icons []Icon `json:"icons,omitempty"`
 }
 
+// DisplayName returns the name to show for the resource template in a user
+// interface: its Title if set, otherwise its Name.
+func (t *ResourceTemplate) DisplayName() string { return displayName(t.Title, t.Name) }
+
 // The sender or recipient of messages and data in a conversation.
 type Role string
 
+// The roles defined by the protocol.
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
 // Represents a root directory or file that the server can operate on.
 //
 // Deprecated: the roots feature is deprecated as of protocol version
@@ -1924,6 +2038,19 @@ type Tool struct {
 	Icons []Icon `json:"icons,omitempty"`
 }
 
+// DisplayName returns the name to show for the tool in a user interface: its
+// Title if set, otherwise the Title of its Annotations if set, otherwise its
+// Name.
+func (t *Tool) DisplayName() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	if t.Annotations != nil && t.Annotations.Title != "" {
+		return t.Annotations.Title
+	}
+	return t.Name
+}
+
 // hintomitempty is a compatibility parameter that restores the pre-1.7.0
 // behavior of [ToolAnnotations] JSON marshaling, where false-valued bare bool
 // fields (ReadOnlyHint, IdempotentHint) were omitted from the output.
@@ -2135,6 +2262,8 @@ type ElicitResult struct {
 	// - "accept": User submitted the form/confirmed the action
 	// - "decline": User explicitly declined the action
 	// - "cancel": User dismissed without making an explicit choice
+	//
+	// Use the ElicitAction constants rather than string literals.
 	Action string `json:"action"`
 	// The submitted form data, only present when action is "accept".
 	// Contains values matching the requested schema.
@@ -2144,6 +2273,37 @@ type ElicitResult struct {
 func (*ElicitResult) isResult()        {}
 func (*ElicitResult) isInputResponse() {}
 
+// The elicitation actions defined by the protocol, reported in
+// [ElicitResult.Action].
+const (
+	// ElicitActionAccept means the user submitted the form or confirmed the
+	// action.
+	ElicitActionAccept = "accept"
+	// ElicitActionDecline means the user explicitly declined the action.
+	ElicitActionDecline = "decline"
+	// ElicitActionCancel means the user dismissed the request without making
+	// an explicit choice.
+	ElicitActionCancel = "cancel"
+)
+
+// Accepted reports whether the user accepted the elicitation.
+func (r *ElicitResult) Accepted() bool {
+	return r != nil && r.Action == ElicitActionAccept
+}
+
+// checkElicitResult reports an error if res is nil or its action is not one
+// of the known elicitation actions.
+func checkElicitResult(res *ElicitResult) error {
+	if res == nil {
+		return errors.New("missing elicitation result")
+	}
+	switch res.Action {
+	case ElicitActionAccept, ElicitActionDecline, ElicitActionCancel:
+		return nil
+	}
+	return fmt.Errorf("invalid elicitation action %q (must be %q, %q or %q)", res.Action, ElicitActionAccept, ElicitActionDecline, ElicitActionCancel)
+}
+
 // ElicitationCompleteParams is sent from the server to the client, informing it that an out-of-band elicitation interaction has completed.
 type ElicitationCompleteParams struct {
 	// This property is reserved by the protocol to allow clients and servers to
@@ -2175,6 +2335,18 @@ type Implementation struct {
 	Icons []Icon `json:"icons,omitempty"`
 }
 
+// DisplayName returns the name to show for the implementation in a user
+// interface: its Title if set, otherwise its Name.
+func (i *Implementation) DisplayName() string { return displayName(i.Title, i.Name) }
+
+// displayName returns title if it is non-empty, otherwise name.
+func displayName(title, name string) string {
+	if title != "" {
+		return title
+	}
+	return name
+}
+
 // CompletionCapabilities describes the server's support for argument autocompletion.
 type CompletionCapabilities struct{}
 
@@ -2325,6 +2497,11 @@ const (
 	MetaKeySubscriptionID = "io.modelcontextprotocol/subscriptionId"
 )
 
+// MetaKeyPrefix prefixes the _meta keys of the SDK's own extensions to the
+// protocol, such as a [ByteRange] in a "resources/read" request, so that they
+// cannot clash with keys defined by the specification or by applications.
+const MetaKeyPrefix = "io.modelcontextprotocol.go-sdk/"
+
 // UnsupportedProtocolVersionData is the SEP-2575 payload carried in the
 // `data` field of a JSON-RPC error response with code
 // [CodeUnsupportedProtocolVersion]. The server uses it to advertise which
@@ -5,6 +5,7 @@
 package mcp
 
 import (
+	"bytes"
 	"encoding/json"
 	"maps"
 	"testing"
@@ -1353,3 +1354,81 @@ func TestToolAnnotations_MarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestDisplayName(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"tool title", (&Tool{Name: "n", Title: "t", Annotations: &ToolAnnotations{Title: "a"}}).DisplayName(), "t"},
+		{"tool annotations title", (&Tool{Name: "n", Annotations: &ToolAnnotations{Title: "a"}}).DisplayName(), "a"},
+		{"tool name", (&Tool{Name: "n", Annotations: &ToolAnnotations{}}).DisplayName(), "n"},
+		{"tool no annotations", (&Tool{Name: "n"}).DisplayName(), "n"},
+		{"prompt title", (&Prompt{Name: "n", Title: "t"}).DisplayName(), "t"},
+		{"prompt name", (&Prompt{Name: "n"}).DisplayName(), "n"},
+		{"prompt argument", (&PromptArgument{Name: "n", Title: "t"}).DisplayName(), "t"},
+		{"resource", (&Resource{Name: "n", Title: "t"}).DisplayName(), "t"},
+		{"resource template", (&ResourceTemplate{Name: "n"}).DisplayName(), "n"},
+		{"implementation", (&Implementation{Name: "n", Title: "t"}).DisplayName(), "t"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: DisplayName() = %q, want %q", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestNoEmptyMeta(t *testing.T) {
+	// Marshal every params and result type with empty and nil metadata, as
+	// middleware might leave them, and check that "_meta" is omitted.
+	check := func(name string, v any) {
+		t.Helper()
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Errorf("%s: marshaling %T: %v", name, v, err)
+			return
+		}
+		if bytes.Contains(data, []byte(`"_meta"`)) {
+			t.Errorf("%s: %T marshals with empty _meta: %s", name, v, data)
+		}
+	}
+	for _, infos := range []map[string]methodInfo{clientMethodInfos, serverMethodInfos} {
+		for method, info := range infos {
+			for _, meta := range []map[string]any{nil, {}} {
+				if p, err := info.unmarshalParams(json.RawMessage(`{}`)); err == nil && p != nil && !p.isNil() {
+					p.SetMeta(meta)
+					check(method+" params", p)
+				}
+				if info.flags&notification == 0 {
+					r := info.newResult()
+					if _, ok := r.(*emptyResult); ok {
+						continue // has no metadata
+					}
+					r.SetMeta(meta)
+					check(method+" result", r)
+				}
+			}
+		}
+	}
+
+	// Metadata also appears on values nested in params and results, some of
+	// which have custom marshaling.
+	empty := Meta{}
+	for _, v := range []any{
+		&Tool{Meta: empty, Name: "t"},
+		&Prompt{Meta: empty, Name: "p"},
+		&Resource{Meta: empty, URI: "file:///r"},
+		&ResourceTemplate{Meta: empty, URITemplate: "file:///{x}"},
+		&Root{Meta: empty, URI: "file:///"},
+		&TextContent{Meta: empty},
+		&ImageContent{Meta: empty, Data: []byte("x")},
+		&AudioContent{Meta: empty, Data: []byte("x")},
+		&ResourceLink{Meta: empty, URI: "file:///r"},
+		&EmbeddedResource{Meta: empty, Resource: &ResourceContents{Meta: empty, URI: "file:///r", Text: "x"}},
+		&ToolUseContent{Meta: empty, ID: "1", Name: "t", Input: map[string]any{}},
+		&ToolResultContent{Meta: empty, ToolUseID: "1"},
+	} {
+		check("nested", v)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// A ServerTool is a [Tool] bound to its handler, for use in a [Registry].
+//
+// The Tool and Handler fields have the same meaning as the arguments to
+// [Server.AddTool]. Use [NewServerTool] to build a ServerTool from a typed
+// handler, as with the top-level [AddTool] function.
+type ServerTool struct {
+	Tool    *Tool
+	Handler ToolHandler
+
+	// err records a failure to build the tool in NewServerTool, so that it can
+	// be reported along with the other registration errors.
+	err error
+}
+
+// NewServerTool returns a ServerTool for the given tool and typed handler,
+// with the same automatic behavior as the top-level [AddTool] function.
+//
+// Unlike AddTool, NewServerTool does not panic if the tool's schemas cannot be
+// inferred or are invalid: the error is instead reported by
+// [NewServerFromRegistry].
+func NewServerTool[In, Out any](t *Tool, h ToolHandlerFor[In, Out]) *ServerTool {
+	tt, hh, err := toolForErr(t, h, nil)
+	if err != nil {
+		return &ServerTool{Tool: t, err: err}
+	}
+	return &ServerTool{Tool: tt, Handler: hh}
+}
+
+// A ServerPrompt is a [Prompt] bound to its handler, for use in a [Registry].
+type ServerPrompt struct {
+	Prompt  *Prompt
+	Handler PromptHandler
+}
+
+// A ServerResource is a [Resource] bound to its handler, for use in a
+// [Registry].
+type ServerResource struct {
+	Resource *Resource
+	Handler  ResourceHandler
+}
+
+// A ServerResourceTemplate is a [ResourceTemplate] bound to its handler, for
+// use in a [Registry].
+type ServerResourceTemplate struct {
+	ResourceTemplate *ResourceTemplate
+	Handler          ResourceHandler
+}
+
+// A Registry describes the features of a server as data.
+// See [NewServerFromRegistry].
+type Registry struct {
+	Tools             []*ServerTool
+	Prompts           []*ServerPrompt
+	Resources         []*ServerResource
+	ResourceTemplates []*ServerResourceTemplate
+}
+
+// NewServerFromRegistry creates a new MCP server, as with [NewServer], and
+// adds the features described by reg.
+//
+// All features are validated before any are added. If any feature is invalid,
+// or two features share a name (or URI, for resources), NewServerFromRegistry
+// returns a nil Server and an error joining every problem found, rather than
+// panicking at the first one as the Server.AddXXX methods do.
+func NewServerFromRegistry(impl *Implementation, options *ServerOptions, reg Registry) (*Server, error) {
+	if err := reg.check(); err != nil {
+		return nil, err
+	}
+	s := NewServer(impl, options)
+	for _, t := range reg.Tools {
+		s.AddTool(t.Tool, t.Handler)
+	}
+	for _, p := range reg.Prompts {
+		s.AddPrompt(p.Prompt, p.Handler)
+	}
+	for _, r := range reg.Resources {
+		s.AddResource(r.Resource, r.Handler)
+	}
+	for _, t := range reg.ResourceTemplates {
+		s.AddResourceTemplate(t.ResourceTemplate, t.Handler)
+	}
+	return s, nil
+}
+
+// check reports all the problems with the features of reg.
+func (reg Registry) check() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	tools := make(map[string]bool)
+	for i, t := range reg.Tools {
+		switch {
+		case t == nil || t.Tool == nil:
+			fail("tool %d: missing tool", i)
+			continue
+		case t.err != nil:
+			fail("tool %q: %v", t.Tool.Name, t.err)
+		case t.Handler == nil:
+			fail("tool %q: missing handler", t.Tool.Name)
+		default:
+			if err := checkTool(t.Tool); err != nil {
+				fail("tool %q: %v", t.Tool.Name, err)
+			}
+		}
+		if tools[t.Tool.Name] {
+			fail("tool %q: duplicate name", t.Tool.Name)
+		}
+		tools[t.Tool.Name] = true
+	}
+
+	prompts := make(map[string]bool)
+	for i, p := range reg.Prompts {
+		if p == nil || p.Prompt == nil {
+			fail("prompt %d: missing prompt", i)
+			continue
+		}
+		if p.Handler == nil {
+			fail("prompt %q: missing handler", p.Prompt.Name)
+		}
+		if prompts[p.Prompt.Name] {
+			fail("prompt %q: duplicate name", p.Prompt.Name)
+		}
+		prompts[p.Prompt.Name] = true
+	}
+
+	resources := make(map[string]bool)
+	for i, r := range reg.Resources {
+		if r == nil || r.Resource == nil {
+			fail("resource %d: missing resource", i)
+			continue
+		}
+		if _, err := url.Parse(r.Resource.URI); err != nil {
+			fail("resource %q: %v", r.Resource.URI, err)
+		}
+		if r.Handler == nil {
+			fail("resource %q: missing handler", r.Resource.URI)
+		}
+		if resources[r.Resource.URI] {
+			fail("resource %q: duplicate URI", r.Resource.URI)
+		}
+		resources[r.Resource.URI] = true
+	}
+
+	templates := make(map[string]bool)
+	for i, t := range reg.ResourceTemplates {
+		if t == nil || t.ResourceTemplate == nil {
+			fail("resource template %d: missing resource template", i)
+			continue
+		}
+		if _, err := uritemplate.New(t.ResourceTemplate.URITemplate); err != nil {
+			fail("resource template %q: invalid URI template: %v", t.ResourceTemplate.URITemplate, err)
+		}
+		if t.Handler == nil {
+			fail("resource template %q: missing handler", t.ResourceTemplate.URITemplate)
+		}
+		if templates[t.ResourceTemplate.URITemplate] {
+			fail("resource template %q: duplicate URI template", t.ResourceTemplate.URITemplate)
+		}
+		templates[t.ResourceTemplate.URITemplate] = true
+	}
+
+	return errors.Join(errs...)
+}
@@ -5,11 +5,15 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -65,6 +69,32 @@ func ResourceNotFoundError(uri string) error {
 	}
 }
 
+// NormalizeURI returns a normalized form of uri, suitable for
+// [ServerOptions.NormalizeResourceURI]. It lowercases the scheme and host,
+// decodes percent-encoded characters in the path (other than "/"), and removes a trailing
+// slash from a non-root path. The case of the path is preserved.
+//
+// If uri cannot be parsed, NormalizeURI returns it unchanged.
+func NormalizeURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	// Path holds the decoded path; clearing RawPath makes String re-encode
+	// it canonically. An encoded slash must stay encoded, since decoding it
+	// would change the path's segments.
+	if !strings.Contains(strings.ToUpper(u.RawPath), "%2F") {
+		u.RawPath = ""
+	}
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+	return u.String()
+}
+
 // readFileResource reads from the filesystem at a URI relative to dirFilepath, respecting
 // the roots.
 // dirFilepath and rootFilepaths are absolute filesystem paths.
@@ -197,3 +227,181 @@ func (sr *serverResourceTemplate) Matches(uri string) bool {
 	}
 	return tmpl.Regexp().MatchString(uri)
 }
+
+// byteRangeKey is the _meta key for a [ByteRange] in a "resources/read"
+// request or result.
+const byteRangeKey = MetaKeyPrefix + "byteRange"
+
+// A ByteRange describes a contiguous range of bytes within a resource.
+//
+// A client requests part of a resource by calling
+// [ReadResourceParams.SetRange]. A server reports the range it returned with
+// [ReadResourceResult.SetRange].
+type ByteRange struct {
+	// Offset is the index of the first byte of the range.
+	Offset int64 `json:"offset"`
+	// Length is the number of bytes in the range. In a request, zero means the
+	// range extends to the end of the resource.
+	Length int64 `json:"length,omitempty"`
+	// Total is the size of the entire resource in bytes, or zero if unknown.
+	// It is meaningful only in results.
+	Total int64 `json:"total,omitempty"`
+}
+
+// SetRange requests that the server return only the bytes of the resource
+// in r. Servers that do not support ranges return the entire resource, so
+// callers should check [ReadResourceResult.GetRange].
+func (x *ReadResourceParams) SetRange(r ByteRange) { setByteRange(&x.Meta, r) }
+
+// GetRange returns the byte range requested by the client, if any.
+func (x *ReadResourceParams) GetRange() (ByteRange, bool) { return getByteRange(x.Meta) }
+
+// SetRange records that the result holds only the bytes of the resource in r.
+func (x *ReadResourceResult) SetRange(r ByteRange) { setByteRange(&x.Meta, r) }
+
+// GetRange returns the byte range held by the result. If it reports false,
+// the result holds the entire resource.
+func (x *ReadResourceResult) GetRange() (ByteRange, bool) { return getByteRange(x.Meta) }
+
+func setByteRange(m *Meta, r ByteRange) {
+	if *m == nil {
+		*m = Meta{}
+	}
+	(*m)[byteRangeKey] = r
+}
+
+func getByteRange(m Meta) (ByteRange, bool) {
+	switch v := m[byteRangeKey].(type) {
+	case nil:
+		return ByteRange{}, false
+	case ByteRange:
+		return v, true
+	default:
+		// After unmarshaling, the value is a map[string]any.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ByteRange{}, false
+		}
+		var r ByteRange
+		if err := json.Unmarshal(data, &r); err != nil {
+			return ByteRange{}, false
+		}
+		return r, true
+	}
+}
+
+// applyByteRange returns res restricted to the byte range r requested by the
+// client, for results that the resource handler did not already restrict.
+// It does not modify res or its contents, which the handler may reuse.
+//
+// Only results consisting of a single blob are restricted: slicing text could
+// split a UTF-8 sequence. Other results are returned whole, without a range.
+func applyByteRange(res *ReadResourceResult, r ByteRange) (*ReadResourceResult, error) {
+	if _, ok := res.GetRange(); ok {
+		return res, nil // the handler applied the range
+	}
+	if len(res.Contents) != 1 || res.Contents[0].Blob == nil {
+		return res, nil
+	}
+	c := *res.Contents[0]
+	total := int64(len(c.Blob))
+	if r.Offset < 0 || r.Length < 0 || r.Offset > total {
+		return nil, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: fmt.Sprintf("invalid byte range (offset %d, length %d) for resource of %d bytes", r.Offset, r.Length, total),
+		}
+	}
+	end := total
+	if r.Length > 0 {
+		end = min(r.Offset+r.Length, total)
+	}
+	c.Blob = c.Blob[r.Offset:end]
+	res2 := *res
+	res2.Contents = []*ResourceContents{&c}
+	res2.Meta = maps.Clone(res.Meta)
+	res2.SetRange(ByteRange{Offset: r.Offset, Length: end - r.Offset, Total: total})
+	return &res2, nil
+}
+
+// sniffMIMEType detects the MIME type of c from its contents, for
+// [ServerOptions.SniffResourceMIMETypes]. It returns "" if c is empty.
+//
+// Text contents always receive a text type: http.DetectContentType may
+// classify text containing control characters as binary.
+func sniffMIMEType(c *ResourceContents) string {
+	switch {
+	case c.Blob != nil:
+		return http.DetectContentType(c.Blob)
+	case c.Text != "":
+		if t := http.DetectContentType([]byte(c.Text)); strings.HasPrefix(t, "text/") {
+			return t
+		}
+		return "text/plain; charset=utf-8"
+	}
+	return ""
+}
+
+// NDJSONMIMEType is the MIME type of newline-delimited JSON (NDJSON), in which
+// each line holds a single JSON value.
+const NDJSONMIMEType = "application/x-ndjson"
+
+// NDJSONResourceHandler returns a [ResourceHandler] that serves a collection
+// of records as NDJSON text with MIME type [NDJSONMIMEType], one record per
+// line.
+//
+// The records function is called for each read, and its records are encoded
+// into a single text value that is sent to the client in one message: a
+// resource read has a single result, so the records are not streamed. If
+// records returns an error, the read fails with that error.
+//
+// Clients can decode the records with [NDJSONRecords].
+func NDJSONResourceHandler[T any](records func(context.Context, *ReadResourceRequest) ([]T, error)) ResourceHandler {
+	return func(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+		rs, err := records(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		for _, r := range rs {
+			if err := enc.Encode(r); err != nil {
+				return nil, fmt.Errorf("encoding record: %w", err)
+			}
+		}
+		return &ReadResourceResult{Contents: []*ResourceContents{{
+			URI:      req.Params.URI,
+			MIMEType: NDJSONMIMEType,
+			Text:     b.String(),
+		}}}, nil
+	}
+}
+
+// NDJSONRecords iterates over the records of NDJSON resource contents,
+// decoding each into a value of type T only as it is reached. The contents may be held in either
+// the Text or Blob field.
+//
+// Iteration stops after the first error, such as a malformed record.
+func NDJSONRecords[T any](c *ResourceContents) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var r io.Reader = strings.NewReader(c.Text)
+		if c.Blob != nil {
+			r = bytes.NewReader(c.Blob)
+		}
+		dec := json.NewDecoder(r)
+		for {
+			var v T
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, fmt.Errorf("decoding NDJSON record: %w", err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
@@ -5,10 +5,16 @@
 package mcp
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 )
 
 func TestFileRoot(t *testing.T) {
@@ -130,3 +136,262 @@ func TestTemplateMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestReadResourceRange(t *testing.T) {
+	data := []byte("0123456789")
+	shared := &ReadResourceResult{Contents: []*ResourceContents{{Blob: data}}}
+	cs, _, cleanup := basicConnection(t, func(s *Server) {
+		s.AddResource(&Resource{URI: "file:///blob", Name: "blob"}, func(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+			return &ReadResourceResult{Contents: []*ResourceContents{{Blob: data}}}, nil
+		})
+		s.AddResource(&Resource{URI: "file:///text", Name: "text"}, func(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+			return &ReadResourceResult{Contents: []*ResourceContents{{Text: string(data)}}}, nil
+		})
+		// A handler that returns the same result every time.
+		s.AddResource(&Resource{URI: "file:///shared", Name: "shared"}, func(ctx context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+			return shared, nil
+		})
+	})
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name      string
+		r         ByteRange
+		wantBlob  string
+		wantRange ByteRange
+	}{
+		{"middle", ByteRange{Offset: 2, Length: 3}, "234", ByteRange{Offset: 2, Length: 3, Total: 10}},
+		{"to end", ByteRange{Offset: 7}, "789", ByteRange{Offset: 7, Length: 3, Total: 10}},
+		{"past end", ByteRange{Offset: 8, Length: 5}, "89", ByteRange{Offset: 8, Length: 2, Total: 10}},
+		{"empty", ByteRange{Offset: 10}, "", ByteRange{Offset: 10, Total: 10}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &ReadResourceParams{URI: "file:///blob"}
+			params.SetRange(tt.r)
+			res, err := cs.ReadResource(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(res.Contents[0].Blob); got != tt.wantBlob {
+				t.Errorf("blob = %q, want %q", got, tt.wantBlob)
+			}
+			got, ok := res.GetRange()
+			if !ok {
+				t.Fatal("result has no range")
+			}
+			if diff := cmp.Diff(tt.wantRange, got); diff != "" {
+				t.Errorf("range mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Out of bounds ranges are rejected.
+	params := &ReadResourceParams{URI: "file:///blob"}
+	params.SetRange(ByteRange{Offset: 11})
+	_, err := cs.ReadResource(ctx, params)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeInvalidParams {
+		t.Errorf("ReadResource with offset past end: got %v, want invalid params error", err)
+	}
+
+	// Text is returned whole, without a range.
+	params = &ReadResourceParams{URI: "file:///text"}
+	params.SetRange(ByteRange{Offset: 2, Length: 3})
+	res, err := cs.ReadResource(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Contents[0].Text; got != string(data) {
+		t.Errorf("text = %q, want %q", got, data)
+	}
+	if r, ok := res.GetRange(); ok {
+		t.Errorf("text result has range %+v, want none", r)
+	}
+
+	// Applying a range does not modify the handler's result, so successive
+	// reads of a shared result see all of its contents.
+	for _, r := range []ByteRange{{Offset: 2, Length: 3}, {Offset: 5, Length: 2}} {
+		params := &ReadResourceParams{URI: "file:///shared"}
+		params.SetRange(r)
+		res, err := cs.ReadResource(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := string(data[r.Offset : r.Offset+r.Length])
+		if got := string(res.Contents[0].Blob); got != want {
+			t.Errorf("shared blob with range %+v = %q, want %q", r, got, want)
+		}
+	}
+	if got := string(shared.Contents[0].Blob); got != string(data) {
+		t.Errorf("shared result blob = %q after reads, want %q", got, data)
+	}
+	if r, ok := shared.GetRange(); ok {
+		t.Errorf("shared result has range %+v after reads, want none", r)
+	}
+}
+
+func TestSniffResourceMIMETypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	contents := map[string]*ResourceContents{
+		"file:///image":    {Blob: png},
+		"file:///binary":   {Blob: []byte{0, 1, 2, 3}},
+		"file:///html":     {Text: "<html><body>hi</body></html>"},
+		"file:///control":  {Text: "a\x00b"},
+		"file:///declared": {Text: "<html></html>", MIMEType: "text/x-custom"},
+		"file:///resource": {Text: "<html></html>"},
+	}
+	config := func(s *Server) {
+		for uri, c := range contents {
+			r := &Resource{URI: uri, Name: uri}
+			if uri == "file:///resource" {
+				r.MIMEType = "text/x-registered"
+			}
+			s.AddResource(r, func(context.Context, *ReadResourceRequest) (*ReadResourceResult, error) {
+				c := *c
+				return &ReadResourceResult{Contents: []*ResourceContents{&c}}, nil
+			})
+		}
+	}
+	want := map[string]string{
+		"file:///image":    "image/png",
+		"file:///binary":   "application/octet-stream",
+		"file:///html":     "text/html; charset=utf-8",
+		"file:///control":  "text/plain; charset=utf-8",
+		"file:///declared": "text/x-custom",
+		"file:///resource": "text/x-registered",
+	}
+	ctx := context.Background()
+	for _, sniff := range []bool{false, true} {
+		server := NewServer(testImpl, &ServerOptions{SniffResourceMIMETypes: sniff})
+		cs, _, cleanup := basicClientServerConnection(t, nil, server, config)
+		for uri, wantType := range want {
+			res, err := cs.ReadResource(ctx, &ReadResourceParams{URI: uri})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sniff && contents[uri].MIMEType == "" && uri != "file:///resource" {
+				wantType = ""
+			}
+			if got := res.Contents[0].MIMEType; got != wantType {
+				t.Errorf("sniff=%t: %s has MIME type %q, want %q", sniff, uri, got, wantType)
+			}
+		}
+		cleanup()
+	}
+}
+
+func TestNDJSONResource(t *testing.T) {
+	type record struct {
+		N int `json:"n"`
+	}
+	failAt := -1
+	cs, _, cleanup := basicConnection(t, func(s *Server) {
+		s.AddResource(&Resource{URI: "test://records", Name: "records"}, NDJSONResourceHandler(
+			func(context.Context, *ReadResourceRequest) ([]record, error) {
+				if failAt >= 0 {
+					return nil, errors.New("broken record")
+				}
+				return []record{{0}, {1}, {2}}, nil
+			}))
+	})
+	defer cleanup()
+	ctx := context.Background()
+
+	res, err := cs.ReadResource(ctx, &ReadResourceParams{URI: "test://records"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := res.Contents[0]
+	if c.MIMEType != NDJSONMIMEType {
+		t.Errorf("MIME type = %q, want %q", c.MIMEType, NDJSONMIMEType)
+	}
+	if want := "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n"; c.Text != want {
+		t.Errorf("text = %q, want %q", c.Text, want)
+	}
+	var got []int
+	for r, err := range NDJSONRecords[record](c) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r.N)
+	}
+	if want := []int{0, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+
+	// Blob contents are decoded too, and decoding stops at a malformed record.
+	var errs int
+	got = nil
+	for r, err := range NDJSONRecords[record](&ResourceContents{Blob: []byte("{\"n\":7}\nnot json\n{\"n\":8}\n")}) {
+		if err != nil {
+			errs++
+			continue
+		}
+		got = append(got, r.N)
+	}
+	if want := []int{7}; !slices.Equal(got, want) || errs != 1 {
+		t.Errorf("records = %v with %d errors, want %v with 1 error", got, errs, want)
+	}
+
+	// Errors from the records function fail the read.
+	failAt = 1
+	if _, err := cs.ReadResource(ctx, &ReadResourceParams{URI: "test://records"}); err == nil || !strings.Contains(err.Error(), "broken record") {
+		t.Errorf("ReadResource with failing records: got %v, want broken record error", err)
+	}
+}
+
+func TestNormalizeURI(t *testing.T) {
+	for _, tt := range []struct {
+		uri, want string
+	}{
+		{"file:///a.txt", "file:///a.txt"},
+		{"FILE:///A.txt", "file:///A.txt"},
+		{"https://Example.COM/docs/", "https://example.com/docs"},
+		{"file:///%41.txt", "file:///A.txt"},
+		{"file:///a%20b.txt", "file:///a%20b.txt"},
+		{"file:///a%2Fb/", "file:///a%2Fb"},
+		{"file:///", "file:///"},
+	} {
+		if got := NormalizeURI(tt.uri); got != tt.want {
+			t.Errorf("NormalizeURI(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeResourceURI(t *testing.T) {
+	handler := func(_ context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+		return &ReadResourceResult{Contents: []*ResourceContents{{Text: req.Params.URI}}}, nil
+	}
+	config := func(s *Server) {
+		s.AddResource(&Resource{URI: "file:///A.txt", Name: "a"}, handler)
+		s.AddResourceTemplate(&ResourceTemplate{URITemplate: "file:///dir/{name}", Name: "dir"}, handler)
+	}
+	ctx := context.Background()
+
+	// By default, URIs must match exactly.
+	cs, _, cleanup := basicConnection(t, config)
+	defer cleanup()
+	if _, err := cs.ReadResource(ctx, &ReadResourceParams{URI: "file:///a.txt"}); err == nil {
+		t.Error("ReadResource with different case succeeded without normalization")
+	}
+
+	server := NewServer(testImpl, &ServerOptions{
+		NormalizeResourceURI: func(uri string) string { return strings.ToLower(NormalizeURI(uri)) },
+	})
+	cs, _, cleanup = basicClientServerConnection(t, nil, server, config)
+	defer cleanup()
+	for _, uri := range []string{"file:///A.txt", "file:///a.txt", "FILE:///%61.TXT", "file:///DIR/x"} {
+		res, err := cs.ReadResource(ctx, &ReadResourceParams{URI: uri})
+		if err != nil {
+			t.Errorf("ReadResource(%q): %v", uri, err)
+			continue
+		}
+		if got := res.Contents[0].URI; got != uri {
+			t.Errorf("ReadResource(%q): content URI = %q, want the requested URI", uri, got)
+		}
+	}
+	if _, err := cs.ReadResource(ctx, &ReadResourceParams{URI: "file:///b.txt"}); err == nil {
+		t.Error("ReadResource of unregistered resource succeeded")
+	}
+}
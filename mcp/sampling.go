@@ -0,0 +1,75 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import "strings"
+
+// ModelInfo describes a model available to a client for sampling, for use
+// with [SelectModel].
+//
+// The scores rate the model relative to the others available to the client,
+// from 0 (worst) to 1 (best). CostScore is higher for cheaper models.
+//
+// Deprecated: the sampling feature is deprecated as of protocol version
+// 2026-07-28 (SEP-2577). It remains functional during the deprecation window
+// (at least twelve months). See
+// https://modelcontextprotocol.io/seps/2577-deprecate-roots-sampling-and-logging.
+type ModelInfo struct {
+	Name              string
+	CostScore         float64
+	SpeedScore        float64
+	IntelligenceScore float64
+}
+
+// SelectModel returns the name of the model in available that best matches
+// the server's preferences, or "" if available is empty.
+//
+// Following the guidance in [ModelPreferences], hints are evaluated in order,
+// and the first hint that is a case-insensitive substring of the name of some
+// available model restricts the choice to the models it matches. Among the
+// remaining models, SelectModel picks the one with the highest score, weighted
+// by the preferences' priorities. Ties are broken by order in available.
+//
+// Deprecated: the sampling feature is deprecated as of protocol version
+// 2026-07-28 (SEP-2577). It remains functional during the deprecation window
+// (at least twelve months). See
+// https://modelcontextprotocol.io/seps/2577-deprecate-roots-sampling-and-logging.
+func SelectModel(prefs *ModelPreferences, available []ModelInfo) string {
+	if len(available) == 0 {
+		return ""
+	}
+	if prefs == nil {
+		return available[0].Name
+	}
+	candidates := available
+	for _, h := range prefs.Hints {
+		if h == nil || h.Name == "" {
+			continue
+		}
+		hint := strings.ToLower(h.Name)
+		var matches []ModelInfo
+		for _, m := range available {
+			if strings.Contains(strings.ToLower(m.Name), hint) {
+				matches = append(matches, m)
+			}
+		}
+		if len(matches) > 0 {
+			candidates = matches
+			break
+		}
+	}
+	score := func(m ModelInfo) float64 {
+		return prefs.CostPriority*m.CostScore +
+			prefs.SpeedPriority*m.SpeedScore +
+			prefs.IntelligencePriority*m.IntelligenceScore
+	}
+	best := candidates[0]
+	for _, m := range candidates[1:] {
+		if score(m) > score(best) {
+			best = m
+		}
+	}
+	return best.Name
+}
@@ -10,7 +10,9 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -466,3 +468,225 @@ func TestClientCapabilities_CloneSampling(t *testing.T) {
 		t.Error("modifying cloned Sampling.Tools should not affect original")
 	}
 }
+
+func TestToolResultFromCallResult(t *testing.T) {
+	res := &CallToolResult{
+		Content:           []Content{&TextContent{Text: "division by zero"}},
+		StructuredContent: map[string]any{"error": "division by zero"},
+		IsError:           true,
+	}
+	got := ToolResultFromCallResult("tool_1", res)
+	want := &ToolResultContent{
+		ToolUseID:         "tool_1",
+		Content:           []Content{&TextContent{Text: "division by zero"}},
+		StructuredContent: map[string]any{"error": "division by zero"},
+		IsError:           true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ToolResultFromCallResult mismatch (-want +got):\n%s", diff)
+	}
+
+	// The result's content slice is not shared.
+	got.Content[0] = &TextContent{Text: "changed"}
+	if res.Content[0].(*TextContent).Text != "division by zero" {
+		t.Error("modifying the tool result content modified the call result")
+	}
+}
+
+func TestValidateToolResults(t *testing.T) {
+	use := func(id string) Content { return &ToolUseContent{ID: id, Name: "calc"} }
+	result := func(id string) Content { return &ToolResultContent{ToolUseID: id} }
+	text := &TextContent{Text: "hi"}
+	for _, tt := range []struct {
+		name     string
+		messages []*SamplingMessageV2
+		wantErr  string // substring; empty means success
+	}{
+		{"no tools", []*SamplingMessageV2{{Role: "user", Content: []Content{text}}}, ""},
+		{"paired", []*SamplingMessageV2{
+			{Role: "assistant", Content: []Content{use("a"), use("b")}},
+			{Role: "user", Content: []Content{result("b"), result("a")}},
+		}, ""},
+		{"orphan", []*SamplingMessageV2{
+			{Role: "assistant", Content: []Content{use("a")}},
+			{Role: "user", Content: []Content{result("a"), result("b")}},
+		}, `message 1: tool_result with toolUseId "b"`},
+		{"same message", []*SamplingMessageV2{
+			{Role: "user", Content: []Content{use("a"), result("a")}},
+		}, `message 0`},
+		{"result before use", []*SamplingMessageV2{
+			{Role: "user", Content: []Content{result("a")}},
+			{Role: "assistant", Content: []Content{use("a")}},
+		}, `message 0`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&CreateMessageWithToolsParams{Messages: tt.messages}).ValidateToolResults()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("got %v, want success", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSamplingToolResultsOption(t *testing.T) {
+	ctx := context.Background()
+	var called atomic.Bool
+	client := NewClient(testImpl, &ClientOptions{
+		CreateMessageWithToolsHandler: func(context.Context, *CreateMessageWithToolsRequest) (*CreateMessageWithToolsResult, error) {
+			called.Store(true)
+			return &CreateMessageWithToolsResult{Model: "m", Role: "assistant", Content: []Content{&TextContent{}}}, nil
+		},
+		Capabilities: &ClientCapabilities{
+			Sampling: &SamplingCapabilities{Tools: &SamplingToolsCapabilities{}},
+		},
+	})
+	server := NewServer(testImpl, &ServerOptions{ValidateSamplingToolResults: true})
+	_, ss, cleanup := basicClientServerConnection(t, client, server, nil)
+	defer cleanup()
+
+	_, err := ss.CreateMessage(ctx, &CreateMessageParams{
+		MaxTokens: 100,
+		Messages:  []*SamplingMessage{{Role: "user", Content: &ToolResultContent{ToolUseID: "missing"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("CreateMessage with orphaned tool result: got %v, want error naming it", err)
+	}
+	_, err = ss.CreateMessageWithTools(ctx, &CreateMessageWithToolsParams{
+		MaxTokens: 100,
+		Messages:  []*SamplingMessageV2{{Role: "user", Content: []Content{&ToolResultContent{ToolUseID: "missing"}}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("CreateMessageWithTools with orphaned tool result: got %v, want error naming it", err)
+	}
+	if called.Load() {
+		t.Error("client was asked to sample an invalid conversation")
+	}
+}
+
+func TestCreateMessageWithTools_BasicClient(t *testing.T) {
+	ctx := context.Background()
+	var gotParams *CreateMessageParams
+	client := NewClient(testImpl, &ClientOptions{
+		CreateMessageHandler: func(_ context.Context, req *CreateMessageRequest) (*CreateMessageResult, error) {
+			gotParams = req.Params
+			return &CreateMessageResult{Model: "m", Role: "assistant", Content: &TextContent{Text: "hi"}}, nil
+		},
+	})
+	_, ss, cleanup := basicClientServerConnection(t, client, nil, nil)
+	defer cleanup()
+
+	// The client has not declared the tools capability, so requests with
+	// and without tools are both sent as plain sampling requests.
+	for _, tools := range [][]*Tool{nil, {{Name: "calculator", InputSchema: map[string]any{"type": "object"}}}} {
+		gotParams = nil
+		res, err := ss.CreateMessageWithTools(ctx, &CreateMessageWithToolsParams{
+			MaxTokens:  100,
+			Messages:   []*SamplingMessageV2{{Role: "user", Content: []Content{&TextContent{Text: "hello"}}}},
+			Tools:      tools,
+			ToolChoice: &ToolChoice{Mode: "auto"},
+		})
+		if err != nil {
+			t.Fatalf("CreateMessageWithTools with %d tools: %v", len(tools), err)
+		}
+		want := &CreateMessageParams{
+			MaxTokens: 100,
+			Messages:  []*SamplingMessage{{Role: "user", Content: &TextContent{Text: "hello"}}},
+		}
+		if diff := cmp.Diff(want, gotParams); diff != "" {
+			t.Errorf("with %d tools: CreateMessageParams mismatch (-want +got):\n%s", len(tools), diff)
+		}
+		if diff := cmp.Diff([]Content{&TextContent{Text: "hi"}}, res.Content); diff != "" {
+			t.Errorf("with %d tools: result content mismatch (-want +got):\n%s", len(tools), diff)
+		}
+	}
+}
+
+func TestSelectModel(t *testing.T) {
+	models := []ModelInfo{
+		{Name: "claude-3-haiku-20240307", CostScore: 0.9, SpeedScore: 0.9, IntelligenceScore: 0.3},
+		{Name: "claude-3-5-sonnet-20241022", CostScore: 0.5, SpeedScore: 0.5, IntelligenceScore: 0.8},
+		{Name: "gpt-4o", CostScore: 0.4, SpeedScore: 0.6, IntelligenceScore: 0.8},
+	}
+	for _, tt := range []struct {
+		name  string
+		prefs *ModelPreferences
+		want  string
+	}{
+		{"nil preferences", nil, "claude-3-haiku-20240307"},
+		{"exact hint", &ModelPreferences{Hints: []*ModelHint{{Name: "gpt-4o"}}}, "gpt-4o"},
+		{"substring hint", &ModelPreferences{Hints: []*ModelHint{{Name: "Sonnet"}}}, "claude-3-5-sonnet-20241022"},
+		{"first matching hint", &ModelPreferences{Hints: []*ModelHint{{Name: "gemini"}, {Name: "gpt"}, {Name: "claude"}}}, "gpt-4o"},
+		{"ambiguous hint uses priorities", &ModelPreferences{
+			Hints:                []*ModelHint{{Name: "claude"}},
+			IntelligencePriority: 1,
+		}, "claude-3-5-sonnet-20241022"},
+		{"no matching hint", &ModelPreferences{Hints: []*ModelHint{{Name: "gemini"}}, SpeedPriority: 1}, "claude-3-haiku-20240307"},
+		{"weighted priorities", &ModelPreferences{IntelligencePriority: 0.8, SpeedPriority: 0.3}, "gpt-4o"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectModel(tt.prefs, models); got != tt.want {
+				t.Errorf("SelectModel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := SelectModel(&ModelPreferences{}, nil); got != "" {
+		t.Errorf("SelectModel with no models = %q, want empty", got)
+	}
+}
+
+func TestCreateMessageStream(t *testing.T) {
+	ctx := context.Background()
+	deltas := []string{"Hello", ", ", "world"}
+	client := NewClient(testImpl, &ClientOptions{
+		CreateMessageHandler: func(ctx context.Context, req *CreateMessageRequest) (*CreateMessageResult, error) {
+			token := req.Params.GetProgressToken()
+			if token == nil {
+				return nil, errors.New("missing progress token")
+			}
+			var text string
+			for i, d := range deltas {
+				text += d
+				if err := req.Session.NotifyProgress(ctx, &ProgressNotificationParams{
+					ProgressToken: token,
+					Message:       d,
+					Progress:      float64(i + 1),
+				}); err != nil {
+					return nil, err
+				}
+			}
+			return &CreateMessageResult{Model: "m", Role: "assistant", Content: &TextContent{Text: text}}, nil
+		},
+	})
+	var otherProgress atomic.Int32
+	server := NewServer(testImpl, &ServerOptions{
+		ProgressNotificationHandler: func(context.Context, *ProgressNotificationServerRequest) {
+			otherProgress.Add(1)
+		},
+	})
+	_, ss, cleanup := basicClientServerConnection(t, client, server, nil)
+	defer cleanup()
+
+	for range 20 {
+		var got []string
+		res, err := ss.CreateMessageStream(ctx, &CreateMessageParams{
+			MaxTokens: 100,
+			Messages:  []*SamplingMessage{{Role: "user", Content: &TextContent{Text: "hi"}}},
+		}, func(text string) { got = append(got, text) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(deltas, got); diff != "" {
+			t.Fatalf("partial text mismatch (-want +got):\n%s", diff)
+		}
+		if text := res.Content.(*TextContent).Text; text != "Hello, world" {
+			t.Errorf("result text = %q, want %q", text, "Hello, world")
+		}
+	}
+	if n := otherProgress.Load(); n != 0 {
+		t.Errorf("ProgressNotificationHandler called %d times for streaming progress", n)
+	}
+}
@@ -7,7 +7,6 @@ package mcp
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
 	"encoding/base64"
 	"encoding/gob"
 	"encoding/json"
@@ -20,11 +19,13 @@ import (
 	"path/filepath"
 	"reflect"
 	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/auth"
 	internaljson "github.com/modelcontextprotocol/go-sdk/internal/json"
 	"github.com/modelcontextprotocol/go-sdk/internal/jsonrpc2"
 	"github.com/modelcontextprotocol/go-sdk/internal/util"
@@ -50,18 +51,25 @@ type Server struct {
 	resources                   *featureSet[*serverResource]
 	resourceTemplates           *featureSet[*serverResourceTemplate]
 	sessions                    []*ServerSession
+	sessionIDs                  map[string]*ServerSession // non-empty session ID -> session using it, or nil until bound
 	sendingMethodHandler_       MethodHandler
 	receivingMethodHandler_     MethodHandler
 	toolChangeSubscriptions     map[*ServerSession]jsonrpc.ID            // session -> requestID for "tools/changed"
 	promptChangeSubscriptions   map[*ServerSession]jsonrpc.ID            // session -> requestID for "prompts/changed"
 	resourceChangeSubscriptions map[*ServerSession]jsonrpc.ID            // session -> requestID for "resources/changed"
 	resourceSubscriptions       map[string]map[*ServerSession]jsonrpc.ID // uri -> session -> requestID
-	pendingNotifications        map[string]*time.Timer                   // notification name -> timer for pending notification send
+	pendingNotifications        map[string]*pendingNotification          // notification name -> pending notification send
 	// receiveMethods is the merged map of methods this server may receive
 	// from a client: it always contains the standard server methods (from
 	// serverMethodInfos) plus any custom methods registered via
 	// [AddReceivingCustomMethod].
 	receiveMethods map[string]methodInfo
+	// requestSlots limits concurrent requests across sessions, if
+	// [ServerOptions.MaxConcurrentRequests] is set.
+	requestSlots chan struct{}
+	// clock is the source of time for keepalive and initialization timeouts,
+	// idempotency key expiry and list_changed debouncing.
+	clock clock
 }
 
 // ServerOptions is used to configure behavior of the server.
@@ -70,8 +78,51 @@ type ServerOptions struct {
 	Instructions string
 	// Logger may be set to a non-nil value to enable logging of server activity.
 	Logger *slog.Logger
+	// MaxLogDataSize, if positive, limits the size in bytes of the JSON
+	// encoding of the data of log messages sent to clients with
+	// [ServerSession.Log] or a [LoggingHandler]. The data of larger messages
+	// is replaced by a string holding a truncated prefix of its encoding.
+	//
+	// Regardless of MaxLogDataSize, log messages whose data cannot be
+	// marshaled to JSON are dropped. In either case, a warning is logged to
+	// Logger.
+	MaxLogDataSize int
+	// OnInitialize, if non-nil, is called with the client's initialization
+	// parameters before the server accepts a session's initialization. If it
+	// returns an error, initialization fails with that error. Use it to
+	// reject clients based on their name, version or capabilities.
+	//
+	// OnInitialize is a shorthand for an InitializeInterceptor that needs
+	// neither the connection metadata nor to report a token: it is called at
+	// the same points, immediately before InitializeInterceptor, which is not
+	// called if OnInitialize fails.
+	OnInitialize func(context.Context, *InitializeParams) error
+	// InitializeInterceptor, if non-nil, is called when a client initializes
+	// a session, before the server handles the request: for the "initialize"
+	// request, or, for clients using protocol version 2026-07-28 or later,
+	// the session's first request. It receives metadata about the connection
+	// and the client's initialization parameters.
+	//
+	// Use it to authenticate clients of transports without HTTP requests,
+	// such as stdio, where bearer tokens are unavailable: for example, check
+	// the peer credentials of a unix socket, or a token from the server's
+	// environment or the params' _meta. If the interceptor returns an error,
+	// the request fails with that error and the session remains
+	// uninitialized. Otherwise, a non-nil [auth.TokenInfo] it returns is
+	// reported in [RequestExtra.TokenInfo] for subsequent requests of the
+	// session, unless the transport provides its own.
+	InitializeInterceptor func(context.Context, *ConnectionInfo, *InitializeParams) (*auth.TokenInfo, error)
 	// If non-nil, called when "notifications/initialized" is received.
+	// The client is ready to handle requests at this point, so the handler
+	// may call client methods such as [ServerSession.ListRoots].
 	InitializedHandler func(context.Context, *InitializedRequest)
+	// If non-nil, called once the initialization handshake of a session is
+	// complete, after InitializedHandler, with a summary of the session.
+	// Clients using protocol version 2026-07-28 or later have no handshake;
+	// for them it is called when the client calls server/discover, as
+	// [Client.Connect] does, so that it is called once for each client
+	// connection either way, as is [ClientOptions.SessionEstablishedHandler].
+	SessionEstablishedHandler func(context.Context, *SessionInfo)
 	// PageSize is the maximum number of items to return in a single page for
 	// list methods (e.g. ListTools).
 	//
@@ -88,7 +139,25 @@ type ServerOptions struct {
 	// If non-nil, called when "notifications/progress" is received.
 	ProgressNotificationHandler func(context.Context, *ProgressNotificationServerRequest)
 	// If non-nil, called when "completion/complete" is received.
+	//
+	// The server rejects a request with an invalid params error, without
+	// calling CompletionHandler, if its reference is to a prompt, resource,
+	// or resource template hidden by [ServerOptions.PromptFilter] and
+	// similar filters. References to prompts and resources that are not
+	// registered reach CompletionHandler, so that it can serve dynamic
+	// references, unless RejectUnknownCompletionReferences is set.
+	//
+	// Arguments that the client has already resolved, such as a country
+	// chosen before completing a city, are passed in the request's
+	// Params.Context.Arguments, so that completions can depend on them.
+	// Params.Context is nil if the client sent no context.
 	CompletionHandler func(context.Context, *CompleteRequest) (*CompleteResult, error)
+	// RejectUnknownCompletionReferences makes the server reject, with an
+	// invalid params error, completion requests whose reference does not
+	// refer to a registered prompt, resource, or resource template. A
+	// "ref/resource" reference may refer to a resource template either by its
+	// URI template or by a URI that it matches.
+	RejectUnknownCompletionReferences bool
 	// If non-zero, defines an interval for regular "ping" requests.
 	// If the peer fails to respond to pings originating from the keepalive check,
 	// the session is automatically closed.
@@ -100,10 +169,198 @@ type ServerOptions struct {
 	// reset" guidance, letting a transient miss pass without tearing down an
 	// otherwise live session. Has no effect unless KeepAlive is non-zero.
 	KeepAliveFailureThreshold int
+	// InitializeTimeout, if non-zero, bounds the time a client has to
+	// complete the initialization handshake (by sending the
+	// "notifications/initialized" notification) after a session is
+	// connected. If the handshake does not complete in time, the session is
+	// closed.
+	//
+	// Clients using protocol version 2026-07-28 or later do not perform the
+	// handshake: their first request completes initialization.
+	InitializeTimeout time.Duration
+	// SamplingTimeout, if positive, bounds the time that
+	// [ServerSession.CreateMessage] and related methods wait for the client's
+	// response. ElicitationTimeout does the same for [ServerSession.Elicit].
+	//
+	// When a request times out, or its context is otherwise cancelled, the
+	// server sends a "notifications/cancelled" notification to the client and
+	// returns an error wrapping the context's error. Without a timeout, an
+	// unresponsive client blocks the caller until its context is done.
+	SamplingTimeout    time.Duration
+	ElicitationTimeout time.Duration
+	// MethodTimeouts bounds the time the server spends handling incoming
+	// requests for the given methods, such as "tools/call". When a request's
+	// timeout elapses, the context of its handler is cancelled, and the client
+	// receives an error with code [CodeRequestTimeout] even if the handler has
+	// not returned. This protects clients from runaway handlers; handlers
+	// should still honor their context, so that their work stops too. A
+	// handler that outlives its timeout continues to count against
+	// MaxConcurrentRequests and MaxConcurrentRequestsPerSession until it
+	// returns.
+	//
+	// DefaultMethodTimeout, if positive, applies to requests for methods
+	// without an entry in MethodTimeouts, other than "initialize" and the
+	// long-lived "subscriptions/listen". An entry of zero disables the
+	// timeout for its method.
+	MethodTimeouts       map[string]time.Duration
+	DefaultMethodTimeout time.Duration
+	// ElicitationDefaultsOnDecline, if true, makes [ServerSession.Elicit]
+	// fill in the requested schema's default values for "decline" and
+	// "cancel" results, not just for "accept" results. The content of such
+	// results is not validated against the schema.
+	ElicitationDefaultsOnDecline bool
+	// SafeMode, if true, permits calls only to tools annotated with
+	// [ToolAnnotations.ReadOnlyHint]. Calls to other tools fail with an error
+	// before their handler is invoked. This lets operators expose a read-only
+	// view of a server to untrusted clients.
+	//
+	// Since ReadOnlyHint is set by tool authors, SafeMode is only as reliable
+	// as the annotations of the server's tools.
+	SafeMode bool
+	// MaxToolArgumentBytes, if positive, limits the size of the raw JSON
+	// arguments of a tool call. Calls with larger arguments fail with an
+	// invalid params error before the arguments are unmarshaled or validated,
+	// and before the tool handler is invoked.
+	//
+	// This complements limits on the size of the request as a whole, such as
+	// those imposed by an HTTP server.
+	MaxToolArgumentBytes int
+	// ToolErrorMessage, if non-nil, computes the text sent to the client for a
+	// tool error. It is called for results whose error was recorded with
+	// [CallToolResult.SetError] and whose content is the default, that is, the
+	// text of the error. This includes errors returned by handlers added with
+	// [AddTool], and argument validation errors. Results with other content
+	// are sent unchanged.
+	//
+	// Use ToolErrorMessage to avoid leaking secrets, such as argument values
+	// that a handler interpolated into an error, to clients. Middleware and
+	// [CallToolResult.GetError] still see the original error. If nil, the
+	// error text is sent as is.
+	ToolErrorMessage func(context.Context, *CallToolRequest, error) string
+	// ToolFilter, if non-nil, reports whether a tool is visible to a session.
+	// Tools for which it returns false are omitted from the session's
+	// tools/list results, and calls to them fail as if they did not exist.
+	// For example, a filter can hide tools that the authenticated user (see
+	// [TokenInfo]) lacks the scopes to call.
+	//
+	// PromptFilter, ResourceFilter and ResourceTemplateFilter do the same for
+	// prompts, resources and resource templates. A hidden resource or template
+	// cannot be read or subscribed to. If ResourceFilter or
+	// ResourceTemplateFilter is set, subscribing to a URI that matches no
+	// resource or template also fails, so that hidden resources cannot be
+	// distinguished from missing ones.
+	//
+	// [ToolResultCache] also honors ToolFilter, never returning a cached
+	// result for a hidden tool.
+	//
+	// The context is that of the request. Filters may be called often, so
+	// they should be fast.
+	ToolFilter             func(context.Context, *ServerSession, *Tool) bool
+	PromptFilter           func(context.Context, *ServerSession, *Prompt) bool
+	ResourceFilter         func(context.Context, *ServerSession, *Resource) bool
+	ResourceTemplateFilter func(context.Context, *ServerSession, *ResourceTemplate) bool
+	// IdempotencyKeyTTL, if positive, enables idempotency keys for tools
+	// annotated with [ToolAnnotations.IdempotentHint].
+	//
+	// A client sets an idempotency key on a call with
+	// [CallToolParams.SetIdempotencyKey]. When a call to such a tool carries
+	// a key, its result is cached for IdempotencyKeyTTL, and later calls to the
+	// same tool with the same key in the same session return the cached result
+	// instead of invoking the tool again. This makes it safe to retry a call
+	// whose response was lost, for example to a network failure. A call that
+	// arrives while another with the same key is in progress waits for its
+	// result. Calls that fail with an error are not cached.
+	//
+	// Each session has its own cache, which is discarded when the session
+	// ends. In particular, keys have no effect for a stateless
+	// [StreamableHTTPHandler], which creates a new session for every request.
+	IdempotencyKeyTTL time.Duration
+	// ValidateSamplingToolResults, if true, causes [ServerSession.CreateMessage]
+	// and [ServerSession.CreateMessageWithTools] to fail without contacting the
+	// client if a tool result in the messages does not refer to an earlier
+	// tool use. See [CreateMessageWithToolsParams.ValidateToolResults].
+	ValidateSamplingToolResults bool
+	// NormalizeResourceURI, if non-nil, is used to match the URIs of
+	// "resources/read" requests against registered resources and resource
+	// templates when there is no exact match. A requested URI matches a
+	// resource if both normalize to the same string, and matches a resource
+	// template if its normalized form matches the template.
+	//
+	// [NormalizeURI] performs normalization that is appropriate for most
+	// URIs. Servers exposing case-insensitive filesystems may additionally
+	// fold the case of the path:
+	//
+	//	func(uri string) string { return strings.ToLower(mcp.NormalizeURI(uri)) }
+	//
+	// By default, URIs must match exactly.
+	NormalizeResourceURI func(uri string) string
+	// SniffResourceMIMETypes enables content sniffing of resource contents
+	// that have no MIME type, either from the read handler or from the
+	// registered [Resource] or [ResourceTemplate]. The type is detected with
+	// [http.DetectContentType].
+	//
+	// Text contents are always given a text type, falling back to
+	// "text/plain; charset=utf-8". Blob contents may be given any type,
+	// including "application/octet-stream" if nothing more specific is
+	// detected.
+	SniffResourceMIMETypes bool
+	// PreemptiveMethods lists methods that are handled as soon as they are
+	// read off the connection, rather than being queued behind requests and
+	// notifications that are still being handled.
+	//
+	// This is useful for methods such as "ping" and "notifications/cancelled",
+	// which should not be delayed by a slow handler: a queued ping can cause
+	// the peer's keepalive check to fail spuriously.
+	//
+	// Preemptive methods are handled synchronously with reading from the
+	// connection, so their handlers (including any receiving middleware)
+	// must not block.
+	PreemptiveMethods []string
+	// UnknownMethodHandler, if set, handles requests and notifications for
+	// methods that the server does not implement, such as experimental
+	// methods that are not registered with [AddReceivingCustomMethod]. Its
+	// result is sent as the response to a request, and ignored for
+	// notifications. To reject a method with a richer error, return a
+	// [*jsonrpc.Error], for example with code [jsonrpc.CodeMethodNotFound]
+	// and additional data.
+	//
+	// If UnknownMethodHandler is nil, requests for unknown methods fail with
+	// the standard "method not found" (-32601) error. This includes methods
+	// that are only valid in the other direction, such as
+	// "sampling/createMessage".
+	//
+	// The SSE transport rejects unknown methods before they reach the
+	// session, so UnknownMethodHandler is not called for SSE connections.
+	UnknownMethodHandler func(ctx context.Context, ss *ServerSession, req *jsonrpc.Request) (any, error)
+	// MaxConcurrentRequests, if positive, limits the number of requests that
+	// may be handled concurrently across all sessions of the server.
+	//
+	// MaxConcurrentRequestsPerSession, if positive, limits the number of
+	// requests that may be handled concurrently within a single session.
+	//
+	// The limits apply to requests that invoke user-provided handlers:
+	// "tools/call", "prompts/get", "resources/read", "completion/complete",
+	// and custom methods added with [AddReceivingCustomMethod]. Requests
+	// beyond either limit wait for a slot to become free, or fail with the
+	// request's context error if the request is cancelled while waiting.
+	MaxConcurrentRequests           int
+	MaxConcurrentRequestsPerSession int
 	// Function called when a client session subscribes to a resource.
 	SubscribeHandler func(context.Context, *SubscribeRequest) error
 	// Function called when a client session unsubscribes from a resource.
 	UnsubscribeHandler func(context.Context, *UnsubscribeRequest) error
+	// ListChangedDelay is how long the server waits after a tool, prompt or
+	// resource is added or removed before sending the corresponding
+	// list_changed notification. Each further change of the same kind within
+	// the delay restarts it, so a burst of changes, such as adding many tools
+	// in a loop, results in a single notification. However, changes do not
+	// postpone the notification by more than 100 times the delay after the
+	// first one (one second with the default delay), so that a steady stream
+	// of changes does not hold it back indefinitely.
+	//
+	// If zero, the delay is 10ms. If negative, notifications are sent without
+	// delay, though still asynchronously.
+	ListChangedDelay time.Duration
 
 	// Capabilities optionally configures the server's default capabilities,
 	// before any capabilities are inferred from other configuration or server
@@ -119,8 +376,8 @@ type ServerOptions struct {
 	// "tools", "prompts", and "resources" capabilities are automatically added when
 	// tools, prompts, or resources are added to the server (for example, via
 	// [Server.AddPrompt]), with default value `{"listChanged":true}`. Similarly,
-	// if the [ClientOptions.SubscribeHandler] or
-	// [ClientOptions.CompletionHandler] are set, the inferred capabilities are
+	// if the [ServerOptions.SubscribeHandler] or
+	// [ServerOptions.CompletionHandler] are set, the inferred capabilities are
 	// adjusted accordingly.
 	//
 	// Any non-nil field in Capabilities overrides the inferred value.
@@ -133,7 +390,23 @@ type ServerOptions struct {
 	//
 	// Conversely, if Capabilities does not set a field (for example, if the
 	// Prompts field is nil), the inferred capability will be used.
+	//
+	// Experimental and extension capabilities are never inferred: set the
+	// Experimental field, or use [ServerCapabilities.AddExtension], to
+	// advertise them.
+	//
+	// To advertise exactly the capabilities in Capabilities, including
+	// suppressing a capability the server would otherwise infer, set
+	// ExactCapabilities.
 	Capabilities *ServerCapabilities
+	// If true, the server advertises Capabilities (or the default described
+	// above, if Capabilities is nil) as is, without inferring any capabilities
+	// from its features or handlers.
+	//
+	// Advertising fewer capabilities does not disable the corresponding
+	// methods: for example, a server with tools still responds to tools/list
+	// even if it does not advertise the "tools" capability.
+	ExactCapabilities bool
 
 	// If true, advertises the prompts capability during initialization,
 	// even if no prompts have been registered.
@@ -157,7 +430,7 @@ type ServerOptions struct {
 	SchemaCache *SchemaCache
 
 	// GetSessionID provides the next session ID to use for an incoming request.
-	// If nil, a default randomly generated ID will be used.
+	// If nil, [NewSessionID] is used.
 	//
 	// Session IDs should be globally unique across the scope of the server,
 	// which may span multiple processes in the case of distributed servers.
@@ -193,6 +466,12 @@ func NewServer(impl *Implementation, options *ServerOptions) *Server {
 	if opts.PageSize == 0 {
 		opts.PageSize = DefaultPageSize
 	}
+	if opts.MaxConcurrentRequests < 0 {
+		panic(fmt.Errorf("invalid MaxConcurrentRequests %d", opts.MaxConcurrentRequests))
+	}
+	if opts.MaxConcurrentRequestsPerSession < 0 {
+		panic(fmt.Errorf("invalid MaxConcurrentRequestsPerSession %d", opts.MaxConcurrentRequestsPerSession))
+	}
 	if opts.SubscribeHandler != nil && opts.UnsubscribeHandler == nil {
 		panic("SubscribeHandler requires UnsubscribeHandler")
 	}
@@ -201,9 +480,24 @@ func NewServer(impl *Implementation, options *ServerOptions) *Server {
 	}
 
 	if opts.GetSessionID == nil {
-		opts.GetSessionID = rand.Text
+		opts.GetSessionID = NewSessionID
 	}
 
+	if f := opts.OnInitialize; f != nil {
+		// Fold OnInitialize into the interceptor, so that only one hook is
+		// called during initialization.
+		next := opts.InitializeInterceptor
+		opts.InitializeInterceptor = func(ctx context.Context, info *ConnectionInfo, params *InitializeParams) (*auth.TokenInfo, error) {
+			if err := f(ctx, params); err != nil {
+				return nil, err
+			}
+			if next == nil {
+				return nil, nil
+			}
+			return next(ctx, info, params)
+		}
+		opts.OnInitialize = nil
+	}
 	if opts.Logger == nil { // ensure we have a logger
 		opts.Logger = ensureLogger(nil)
 	}
@@ -224,13 +518,46 @@ func NewServer(impl *Implementation, options *ServerOptions) *Server {
 		promptChangeSubscriptions:   make(map[*ServerSession]jsonrpc.ID),
 		resourceChangeSubscriptions: make(map[*ServerSession]jsonrpc.ID),
 		resourceSubscriptions:       make(map[string]map[*ServerSession]jsonrpc.ID),
-		pendingNotifications:        make(map[string]*time.Timer),
+		pendingNotifications:        make(map[string]*pendingNotification),
+		sessionIDs:                  make(map[string]*ServerSession),
 		receiveMethods:              receiveMethods,
+		clock:                       realClock{},
+	}
+	if opts.MaxConcurrentRequests > 0 {
+		s.requestSlots = make(chan struct{}, opts.MaxConcurrentRequests)
 	}
 	s.AddReceivingMiddleware(serverMultiRoundTripMiddleware())
 	return s
 }
 
+// Clone returns a new server with the same implementation, options and
+// features as s. It is useful for customizing a preconfigured server for a
+// particular request or tenant, for example in the getServer function passed
+// to [NewStreamableHTTPHandler].
+//
+// The clone starts with the tools, prompts, resources, resource templates,
+// middleware and custom methods of s. Adding or removing features of the clone
+// does not affect s, and vice versa; the feature definitions and handlers
+// themselves are shared, so they must not be modified.
+//
+// The clone has its own per-instance state: it has no sessions or
+// subscriptions, and the limit of [ServerOptions.MaxConcurrentRequests]
+// applies to it separately.
+func (s *Server) Clone() *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s2 := NewServer(s.impl, &s.opts)
+	s2.prompts = s.prompts.clone()
+	s2.tools = s.tools.clone()
+	s2.resources = s.resources.clone()
+	s2.resourceTemplates = s.resourceTemplates.clone()
+	s2.sendingMethodHandler_ = s.sendingMethodHandler_
+	s2.receivingMethodHandler_ = s.receivingMethodHandler_
+	s2.receiveMethods = maps.Clone(s.receiveMethods)
+	s2.clock = s.clock
+	return s2
+}
+
 // AddPrompt adds a [Prompt] to the server, or replaces one with the same name.
 func (s *Server) AddPrompt(p *Prompt, h PromptHandler) {
 	// Assume there was a change, since add replaces existing items.
@@ -273,50 +600,59 @@ func (s *Server) AddTool(t *Tool, h ToolHandler) {
 	if err := validateToolName(t.Name); err != nil {
 		s.opts.Logger.Error(fmt.Sprintf("AddTool: invalid tool name %q: %v", t.Name, err))
 	}
+	if err := checkTool(t); err != nil {
+		panic(fmt.Errorf("AddTool %q: %w", t.Name, err))
+	}
+	st := &serverTool{tool: t, handler: h}
+	// Assume there was a change, since add replaces existing tools.
+	// (It's possible a tool was replaced with an identical one, but not worth checking.)
+	// TODO: Batch these changes by size and time? The typescript SDK doesn't.
+	// TODO: Surface notify error here? best not, in case we need to batch.
+	s.changeAndNotify(notificationToolListChanged, func() bool { s.tools.add(st); return true })
+}
+
+// checkTool reports whether t has the schemas and annotations required by
+// [Server.AddTool].
+func checkTool(t *Tool) error {
 	if t.InputSchema == nil {
 		// This prevents the tool author from forgetting to write a schema where
 		// one should be provided. If we papered over this by supplying the empty
 		// schema, then every input would be validated and the problem wouldn't be
 		// discovered until runtime, when the LLM sent bad data.
-		panic(fmt.Errorf("AddTool %q: missing input schema", t.Name))
+		return errors.New("missing input schema")
 	}
 	if s, ok := t.InputSchema.(*jsonschema.Schema); ok {
 		if s == nil {
-			panic(fmt.Errorf("AddTool %q: input schema is nil", t.Name))
+			return errors.New("input schema is nil")
 		}
 		if s.Type != "object" {
-			panic(fmt.Errorf(`AddTool %q: input schema must have type "object"`, t.Name))
+			return errors.New(`input schema must have type "object"`)
 		}
 	} else {
 		var m map[string]any
 		if err := remarshal(t.InputSchema, &m); err != nil {
-			panic(fmt.Errorf("AddTool %q: can't marshal input schema to a JSON object: %v", t.Name, err))
+			return fmt.Errorf("can't marshal input schema to a JSON object: %v", err)
 		}
 		if typ := m["type"]; typ != "object" {
-			panic(fmt.Errorf(`AddTool %q: input schema must have type "object" (got %v)`, t.Name, typ))
+			return fmt.Errorf(`input schema must have type "object" (got %v)`, typ)
 		}
 	}
 	if t.OutputSchema != nil {
 		if s, ok := t.OutputSchema.(*jsonschema.Schema); ok {
 			if s == nil {
-				panic(fmt.Errorf("AddTool %q: output schema is nil", t.Name))
+				return errors.New("output schema is nil")
 			}
 		} else {
 			var m any
 			if err := remarshal(t.OutputSchema, &m); err != nil {
-				panic(fmt.Errorf("AddTool %q: can't marshal output schema to JSON: %v", t.Name, err))
+				return fmt.Errorf("can't marshal output schema to JSON: %v", err)
 			}
 		}
 	}
 	if err := validateParamHeaderAnnotations(t); err != nil {
-		panic(fmt.Errorf("AddTool %q: invalid parameter header annotations: %v", t.Name, err))
+		return fmt.Errorf("invalid parameter header annotations: %v", err)
 	}
-	st := &serverTool{tool: t, handler: h}
-	// Assume there was a change, since add replaces existing tools.
-	// (It's possible a tool was replaced with an identical one, but not worth checking.)
-	// TODO: Batch these changes by size and time? The typescript SDK doesn't.
-	// TODO: Surface notify error here? best not, in case we need to batch.
-	s.changeAndNotify(notificationToolListChanged, func() bool { s.tools.add(st); return true })
+	return nil
 }
 
 func toolForErr[In, Out any](t *Tool, h ToolHandlerFor[In, Out], cache *SchemaCache) (*Tool, ToolHandler, error) {
@@ -349,6 +685,8 @@ func toolForErr[In, Out any](t *Tool, h ToolHandlerFor[In, Out], cache *SchemaCa
 		}
 	}
 
+	structuredOnly := tt.GetStructuredContentOnly()
+	contentMIMEType := tt.GetContentMIMEType()
 	th := func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
 		var input json.RawMessage
 		if req.Params.Arguments != nil {
@@ -393,6 +731,11 @@ func toolForErr[In, Out any](t *Tool, h ToolHandlerFor[In, Out], cache *SchemaCa
 		if res == nil {
 			res = &CallToolResult{}
 		}
+		if contentMIMEType != "" && !res.IsError {
+			// The text of an error result is an error message, not
+			// the tool's usual output.
+			res.Content = withContentMIMEType(res.Content, contentMIMEType)
+		}
 
 		// Marshal the output and put the RawMessage in the StructuredContent field.
 		// Skip when the handler returned input requests (multi round-trip): content and
@@ -420,7 +763,13 @@ func toolForErr[In, Out any](t *Tool, h ToolHandlerFor[In, Out], cache *SchemaCa
 			// some types may have custom JSON marshalling (issue #447).
 			outJSON, err = applySchema(outJSON, outputResolved, true)
 			if err != nil {
-				return nil, fmt.Errorf("validating tool output: %w", err)
+				// This is a bug in the tool, not the client's fault: log the
+				// details for the server developer.
+				outErr := &ToolOutputError{Tool: req.Params.Name, Err: err}
+				if req.Session != nil {
+					req.Session.server.opts.Logger.Error("tool output does not match its output schema", "tool", req.Params.Name, "error", err)
+				}
+				return nil, outErr
 			}
 			res.StructuredContent = outJSON // avoid a second marshal over the wire
 
@@ -431,14 +780,14 @@ func toolForErr[In, Out any](t *Tool, h ToolHandlerFor[In, Out], cache *SchemaCa
 			// Ensure a serialized-JSON TextContent fallback is present in case of servers using array
 			// or primitive structuredContent, so that pre-SEP-2106 clients can recover the structured
 			// payload from unstructured content.
-			if res.Content == nil {
-				res.Content = []Content{&TextContent{
-					Text: string(outJSON),
-				}}
-			} else if !isObjectJSON(outJSON) {
-				res.Content = append(res.Content, &TextContent{
-					Text: string(outJSON),
-				})
+			//
+			// Tools marked with SetStructuredContentOnly opt out of the copy.
+			switch {
+			case structuredOnly:
+			case res.Content == nil:
+				res.Content = []Content{fallbackTextContent(outJSON, contentMIMEType)}
+			case !isObjectJSON(outJSON):
+				res.Content = append(res.Content, fallbackTextContent(outJSON, contentMIMEType))
 			}
 		}
 		return res, nil
@@ -557,6 +906,13 @@ func setSchema[T any](sfield *any, rfield **jsonschema.Resolved, cache *SchemaCa
 // Unlike [Server.AddTool], AddTool does a lot automatically, and forces
 // tools to conform to the MCP spec. See [ToolHandlerFor] for a detailed
 // description of this automatic behavior.
+//
+// In particular, when AddTool fills in the text content of a result from the
+// output, that text is normally the output's JSON. But if the tool has a
+// non-JSON content MIME type (see [Tool.SetContentMIMEType]) and the output
+// is a string, the text is the string, unquoted: a tool that returns Markdown
+// as a string, with MIME type "text/markdown", produces Markdown text rather
+// than a JSON string literal.
 func AddTool[In, Out any](s *Server, t *Tool, h ToolHandlerFor[In, Out]) {
 	tt, hh, err := toolForErr(t, h, s.opts.SchemaCache)
 	if err != nil {
@@ -572,6 +928,9 @@ func (s *Server) RemoveTools(names ...string) {
 }
 
 // AddResource adds a [Resource] to the server, or replaces one with the same URI.
+// Since URIs identify resources, a server never holds two resources with the
+// same URI; replacements are logged so that accidental collisions can be
+// diagnosed.
 // AddResource panics if the resource URI is invalid or not absolute (has an empty scheme).
 func (s *Server) AddResource(r *Resource, h ResourceHandler) {
 	s.changeAndNotify(notificationResourceListChanged,
@@ -579,6 +938,10 @@ func (s *Server) AddResource(r *Resource, h ResourceHandler) {
 			if _, err := url.Parse(r.URI); err != nil {
 				panic(err) // url.Parse includes the URI in the error
 			}
+			if old, ok := s.resources.get(r.URI); ok {
+				s.opts.Logger.Info("AddResource: replacing resource with duplicate URI",
+					"uri", r.URI, "old_name", old.resource.Name, "new_name", r.Name)
+			}
 			s.resources.add(&serverResource{r, h})
 			return true
 		})
@@ -591,6 +954,7 @@ func (s *Server) RemoveResources(uris ...string) {
 }
 
 // AddResourceTemplate adds a [ResourceTemplate] to the server, or replaces one with the same URI.
+// As with [Server.AddResource], replacements are logged.
 // AddResourceTemplate panics if a URI template is invalid or not absolute (has an empty scheme).
 func (s *Server) AddResourceTemplate(t *ResourceTemplate, h ResourceHandler) {
 	s.changeAndNotify(notificationResourceListChanged,
@@ -600,6 +964,10 @@ func (s *Server) AddResourceTemplate(t *ResourceTemplate, h ResourceHandler) {
 			if err != nil {
 				panic(fmt.Errorf("URI template %q is invalid: %w", t.URITemplate, err))
 			}
+			if old, ok := s.resourceTemplates.get(t.URITemplate); ok {
+				s.opts.Logger.Info("AddResourceTemplate: replacing resource template with duplicate URI template",
+					"uri_template", t.URITemplate, "old_name", old.resourceTemplate.Name, "new_name", t.Name)
+			}
 			s.resourceTemplates.add(&serverResourceTemplate{t, h})
 			return true
 		})
@@ -626,6 +994,9 @@ func (s *Server) capabilities() *ServerCapabilities {
 			Logging: &LoggingCapabilities{},
 		}
 	}
+	if s.opts.ExactCapabilities {
+		return caps
+	}
 
 	// Augment with tools capability if tools exist or legacy HasTools is set.
 	if s.opts.HasTools || s.tools.len() > 0 {
@@ -665,9 +1036,72 @@ func (s *Server) complete(ctx context.Context, req *CompleteRequest) (*CompleteR
 	if s.opts.CompletionHandler == nil {
 		return nil, jsonrpc2.ErrMethodNotFound
 	}
+	if err := s.checkCompleteReference(ctx, req.Session, req.Params.Ref); err != nil {
+		return nil, err
+	}
 	return s.opts.CompletionHandler(ctx, req)
 }
 
+// checkCompleteReference reports an invalid params error if ref refers to a
+// prompt, resource, or resource template that is hidden from the session.
+// If [ServerOptions.RejectUnknownCompletionReferences] is set, it also
+// reports one if ref refers to none that is registered.
+func (s *Server) checkCompleteReference(ctx context.Context, ss *ServerSession, ref *CompleteReference) error {
+	invalid := func(format string, args ...any) error {
+		return &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+	}
+	if ref == nil {
+		return invalid("missing completion reference")
+	}
+	rejectUnknown := s.opts.RejectUnknownCompletionReferences
+	switch ref.Type {
+	case "ref/prompt":
+		s.mu.Lock()
+		p, ok := s.prompts.get(ref.Name)
+		s.mu.Unlock()
+		// Reject a hidden prompt even if unknown references are allowed, with
+		// the same error, so that it cannot be told apart from a missing one.
+		if ok && !featureVisible(ctx, ss, s.opts.PromptFilter, p.prompt) || !ok && rejectUnknown {
+			return invalid("completion reference to unknown prompt %q", ref.Name)
+		}
+	case "ref/resource":
+		r, rt := s.completionResource(ref.URI)
+		var visible bool
+		switch {
+		case r != nil:
+			visible = featureVisible(ctx, ss, s.opts.ResourceFilter, r.resource)
+		case rt != nil:
+			visible = featureVisible(ctx, ss, s.opts.ResourceTemplateFilter, rt.resourceTemplate)
+		default:
+			visible = !rejectUnknown
+		}
+		if !visible {
+			return invalid("completion reference to unknown resource %q", ref.URI)
+		}
+	default:
+		return invalid("unsupported completion reference type %q", ref.Type)
+	}
+	return nil
+}
+
+// completionResource returns the resource or resource template that a
+// "ref/resource" completion reference with the given URI refers to: either a
+// resource with that URI, or a resource template with that URI template or
+// one that matches the URI.
+func (s *Server) completionResource(uri string) (*serverResource, *serverResourceTemplate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.resources.get(uri); ok {
+		return r, nil
+	}
+	for rt := range s.resourceTemplates.all() {
+		if rt.resourceTemplate.URITemplate == uri || rt.Matches(uri) {
+			return nil, rt
+		}
+	}
+	return nil, nil
+}
+
 // Map from notification name to a function creating its corresponding Params.
 // We need to create a fresh one each time to add the jsonrpc ID. See
 // [injectMetaSubscriptionID].
@@ -677,9 +1111,33 @@ var changeNotificationParams = map[string]func() Params{
 	notificationResourceListChanged: func() Params { return &ResourceListChangedParams{} },
 }
 
-// How long to wait before sending a change notification.
+// How long to wait before sending a change notification, by default.
+// See [ServerOptions.ListChangedDelay].
 const notificationDelay = 10 * time.Millisecond
 
+// A burst of changes postpones its list_changed notification by at most this
+// many times the delay after the first change.
+// See [ServerOptions.ListChangedDelay].
+const maxListChangedDelayFactor = 100
+
+// A pendingNotification is a list_changed notification waiting to be sent.
+type pendingNotification struct {
+	timer    timer
+	deadline time.Time // changes do not postpone the notification past this
+}
+
+// listChangedDelay returns the debounce delay for list_changed notifications.
+func (s *Server) listChangedDelay() time.Duration {
+	switch d := s.opts.ListChangedDelay; {
+	case d == 0:
+		return notificationDelay
+	case d < 0:
+		return 0
+	default:
+		return d
+	}
+}
+
 // changeAndNotify is called when a feature is added or removed.
 // It calls change, which should do the work and report whether a change actually occurred.
 // If there was a change, it sets a timer to send a notification.
@@ -690,18 +1148,24 @@ func (s *Server) changeAndNotify(notification string, change func() bool) {
 	defer s.mu.Unlock()
 	if change() && s.shouldSendListChangedNotification(notification) {
 		if len(s.sessions) == 0 {
-			if t := s.pendingNotifications[notification]; t != nil {
-				t.Stop()
+			if p := s.pendingNotifications[notification]; p != nil {
+				p.timer.Stop()
 				s.pendingNotifications[notification] = nil
 			}
 			return
 		}
 
-		// Reset the outstanding delayed call, if any.
-		if t := s.pendingNotifications[notification]; t == nil {
-			s.pendingNotifications[notification] = time.AfterFunc(notificationDelay, func() { s.notifySessions(notification) })
+		// Reset the outstanding delayed call, if any, but not past its
+		// deadline, so that a steady stream of changes can't postpone the
+		// notification indefinitely.
+		delay := s.listChangedDelay()
+		if p := s.pendingNotifications[notification]; p == nil {
+			s.pendingNotifications[notification] = &pendingNotification{
+				timer:    s.clock.AfterFunc(delay, func() { s.notifySessions(notification) }),
+				deadline: s.clock.Now().Add(maxListChangedDelayFactor * delay),
+			}
 		} else {
-			t.Reset(notificationDelay)
+			p.timer.Reset(min(delay, p.deadline.Sub(s.clock.Now())))
 		}
 	}
 }
@@ -757,8 +1221,7 @@ func (s *Server) notifySubscribedSessions(subscribers map[*ServerSession]jsonrpc
 	for sess, reqID := range subscribers {
 		params := makeParams()
 		injectMetaSubscriptionID(params, reqID)
-		req := newRequest(sess, params)
-		if err := handleNotify(ctx, method, req); err != nil {
+		if err := sess.Notify(ctx, method, params); err != nil {
 			s.opts.Logger.Warn(fmt.Sprintf("calling %s: %v", method, err))
 		}
 	}
@@ -819,13 +1282,55 @@ func (s *Server) Sessions() iter.Seq[*ServerSession] {
 	return slices.Values(clients)
 }
 
-func (s *Server) listPrompts(_ context.Context, req *ListPromptsRequest) (*ListPromptsResult, error) {
+// ListToolDefinitions returns the tools currently added to the server, sorted
+// by name. It reports the server's own view, without the per-client filtering
+// applied to "tools/list", and is intended for tests and introspection.
+//
+// The result is a snapshot: later calls to [Server.AddTool] or
+// [Server.RemoveTools] do not affect it. The returned tools are shared with
+// the server and must not be modified.
+func (s *Server) ListToolDefinitions() []*Tool {
+	return definitions(s, s.tools, func(t *serverTool) *Tool { return t.tool })
+}
+
+// ListPromptDefinitions returns the prompts currently added to the server,
+// sorted by name. See [Server.ListToolDefinitions].
+func (s *Server) ListPromptDefinitions() []*Prompt {
+	return definitions(s, s.prompts, func(p *serverPrompt) *Prompt { return p.prompt })
+}
+
+// ListResourceDefinitions returns the resources currently added to the server,
+// sorted by URI. See [Server.ListToolDefinitions].
+func (s *Server) ListResourceDefinitions() []*Resource {
+	return definitions(s, s.resources, func(r *serverResource) *Resource { return r.resource })
+}
+
+// ListResourceTemplateDefinitions returns the resource templates currently
+// added to the server, sorted by URI template. See [Server.ListToolDefinitions].
+func (s *Server) ListResourceTemplateDefinitions() []*ResourceTemplate {
+	return definitions(s, s.resourceTemplates, func(t *serverResourceTemplate) *ResourceTemplate { return t.resourceTemplate })
+}
+
+// definitions returns the definitions of the features in fs, holding the
+// server lock.
+func definitions[T, D any](s *Server, fs *featureSet[T], def func(T) D) []D {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defs := make([]D, 0, fs.len())
+	for f := range fs.all() {
+		defs = append(defs, def(f))
+	}
+	return defs
+}
+
+func (s *Server) listPrompts(ctx context.Context, req *ListPromptsRequest) (*ListPromptsResult, error) {
 	if req.Params == nil {
 		req.Params = &ListPromptsParams{}
 	}
-	res, err := paginateList(s.prompts, s.opts.PageSize, req.Params, &ListPromptsResult{}, func(res *ListPromptsResult, prompts []*serverPrompt) {
+	visible := func(p *serverPrompt) bool {
+		return featureVisible(ctx, req.Session, s.opts.PromptFilter, p.prompt)
+	}
+	res, err := paginateList(snapshot(s, s.prompts), s.opts.PageSize, req.Params, &ListPromptsResult{}, visible, func(res *ListPromptsResult, prompts []*serverPrompt) {
 		res.Prompts = []*Prompt{} // avoid JSON null
 		for _, p := range prompts {
 			res.Prompts = append(res.Prompts, p.prompt)
@@ -842,7 +1347,7 @@ func (s *Server) getPrompt(ctx context.Context, req *GetPromptRequest) (*GetProm
 	s.mu.Lock()
 	prompt, ok := s.prompts.get(req.Params.Name)
 	s.mu.Unlock()
-	if !ok {
+	if !ok || !featureVisible(ctx, req.Session, s.opts.PromptFilter, prompt.prompt) {
 		// Return a proper JSON-RPC error with the correct error code
 		return nil, &jsonrpc.Error{
 			Code:    jsonrpc.CodeInvalidParams,
@@ -864,7 +1369,7 @@ func (s *Server) getPrompt(ctx context.Context, req *GetPromptRequest) (*GetProm
 // the server's capabilities, the server's identity, and the server's
 // instructions, allowing clients to negotiate without performing the legacy
 // initialize handshake.
-func (s *Server) discover(_ context.Context, req *ServerRequest[*DiscoverParams]) (*DiscoverResult, error) {
+func (s *Server) discover(ctx context.Context, req *ServerRequest[*DiscoverParams]) (*DiscoverResult, error) {
 	req.Session.mu.Lock()
 	versions := req.Session.supportedVersions
 	req.Session.mu.Unlock()
@@ -885,6 +1390,7 @@ func (s *Server) discover(_ context.Context, req *ServerRequest[*DiscoverParams]
 		Instructions:      s.opts.Instructions,
 	}
 	res.setDefaultCacheableValues()
+	req.Session.sessionEstablished(ctx)
 	return res, nil
 }
 
@@ -905,13 +1411,16 @@ func filterSupportedVersions(t Transport) []string {
 	return out
 }
 
-func (s *Server) listTools(_ context.Context, req *ListToolsRequest) (*ListToolsResult, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Server) listTools(ctx context.Context, req *ListToolsRequest) (*ListToolsResult, error) {
+	caps := req.ClientCapabilities()
 	if req.Params == nil {
 		req.Params = &ListToolsParams{}
 	}
-	res, err := paginateList(s.tools, s.opts.PageSize, req.Params, &ListToolsResult{}, func(res *ListToolsResult, tools []*serverTool) {
+	visible := func(t *serverTool) bool {
+		// Hide tools that would always fail for this client.
+		return clientSupportsTool(caps, t.tool) && featureVisible(ctx, req.Session, s.opts.ToolFilter, t.tool)
+	}
+	res, err := paginateList(snapshot(s, s.tools), s.opts.PageSize, req.Params, &ListToolsResult{}, visible, func(res *ListToolsResult, tools []*serverTool) {
 		res.Tools = []*Tool{} // avoid JSON null
 		for _, t := range tools {
 			res.Tools = append(res.Tools, t.tool)
@@ -932,13 +1441,47 @@ func (s *Server) getServerTool(name string) (*serverTool, bool) {
 }
 
 func (s *Server) callTool(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+	if limit := s.opts.MaxToolArgumentBytes; limit > 0 && len(req.Params.Arguments) > limit {
+		return nil, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: fmt.Sprintf("arguments of tool %q are too large (%d bytes, limit %d)", req.Params.Name, len(req.Params.Arguments), limit),
+		}
+	}
 	st, ok := s.getServerTool(req.Params.Name)
-	if !ok {
+	if !ok || !featureVisible(ctx, req.Session, s.opts.ToolFilter, st.tool) {
 		return nil, &jsonrpc.Error{
 			Code:    jsonrpc.CodeInvalidParams,
 			Message: fmt.Sprintf("unknown tool %q", req.Params.Name),
 		}
 	}
+	if s.opts.SafeMode && (st.tool.Annotations == nil || !st.tool.Annotations.ReadOnlyHint) {
+		return nil, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: fmt.Sprintf("tool %q is not available in read-only mode", req.Params.Name),
+		}
+	}
+	dryRun := req.Params.GetDryRun()
+	if dryRun && !st.tool.GetSupportsDryRun() {
+		return nil, &jsonrpc.Error{
+			Code:    jsonrpc.CodeInvalidParams,
+			Message: fmt.Sprintf("tool %q does not support dry runs", req.Params.Name),
+		}
+	}
+	// Dry runs are not cached, so that they never stand in for real calls.
+	if key := req.Params.GetIdempotencyKey(); key != "" && !dryRun && req.Session != nil && req.Session.idempotentResults != nil &&
+		st.tool.Annotations != nil && st.tool.Annotations.IdempotentHint {
+		// Each session has its own cache, so that clients cannot observe one
+		// another's results even if their sessions have no ID.
+		cacheKey := req.Params.Name + "\x00" + key
+		return req.Session.idempotentResults.do(ctx, s.clock, cacheKey, func() (*CallToolResult, error) {
+			return s.invokeTool(ctx, st, req)
+		})
+	}
+	return s.invokeTool(ctx, st, req)
+}
+
+// invokeTool calls the handler of st.
+func (s *Server) invokeTool(ctx context.Context, st *serverTool, req *CallToolRequest) (*CallToolResult, error) {
 	res, err := st.handler(ctx, req)
 	if err == nil && res != nil {
 		if err := handleMultiRoundTripResult(req.Session, s.opts.Logger, res); err != nil {
@@ -949,17 +1492,33 @@ func (s *Server) callTool(ctx context.Context, req *CallToolRequest) (*CallToolR
 			res2.Content = []Content{} // avoid "null"
 			res = &res2
 		}
+		if s.opts.ToolErrorMessage != nil && hasDefaultErrorContent(res) {
+			res2 := *res
+			res2.Content = []Content{&TextContent{Text: s.opts.ToolErrorMessage(ctx, req, res.err)}}
+			res = &res2
+		}
 	}
 	return res, err
 }
 
-func (s *Server) listResources(_ context.Context, req *ListResourcesRequest) (*ListResourcesResult, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// hasDefaultErrorContent reports whether the content of res is the text of
+// the error recorded with [CallToolResult.SetError].
+func hasDefaultErrorContent(res *CallToolResult) bool {
+	if !res.IsError || res.err == nil || len(res.Content) != 1 {
+		return false
+	}
+	text, ok := res.Content[0].(*TextContent)
+	return ok && text.Text == res.err.Error()
+}
+
+func (s *Server) listResources(ctx context.Context, req *ListResourcesRequest) (*ListResourcesResult, error) {
 	if req.Params == nil {
 		req.Params = &ListResourcesParams{}
 	}
-	res, err := paginateList(s.resources, s.opts.PageSize, req.Params, &ListResourcesResult{}, func(res *ListResourcesResult, resources []*serverResource) {
+	visible := func(r *serverResource) bool {
+		return featureVisible(ctx, req.Session, s.opts.ResourceFilter, r.resource)
+	}
+	res, err := paginateList(snapshot(s, s.resources), s.opts.PageSize, req.Params, &ListResourcesResult{}, visible, func(res *ListResourcesResult, resources []*serverResource) {
 		res.Resources = []*Resource{} // avoid JSON null
 		for _, r := range resources {
 			res.Resources = append(res.Resources, r.resource)
@@ -972,13 +1531,14 @@ func (s *Server) listResources(_ context.Context, req *ListResourcesRequest) (*L
 	return res, nil
 }
 
-func (s *Server) listResourceTemplates(_ context.Context, req *ListResourceTemplatesRequest) (*ListResourceTemplatesResult, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Server) listResourceTemplates(ctx context.Context, req *ListResourceTemplatesRequest) (*ListResourceTemplatesResult, error) {
 	if req.Params == nil {
 		req.Params = &ListResourceTemplatesParams{}
 	}
-	res, err := paginateList(s.resourceTemplates, s.opts.PageSize, req.Params, &ListResourceTemplatesResult{},
+	visible := func(rt *serverResourceTemplate) bool {
+		return featureVisible(ctx, req.Session, s.opts.ResourceTemplateFilter, rt.resourceTemplate)
+	}
+	res, err := paginateList(snapshot(s, s.resourceTemplates), s.opts.PageSize, req.Params, &ListResourceTemplatesResult{}, visible,
 		func(res *ListResourceTemplatesResult, rts []*serverResourceTemplate) {
 			res.ResourceTemplates = []*ResourceTemplate{} // avoid JSON null
 			for _, rt := range rts {
@@ -996,8 +1556,16 @@ func (s *Server) readResource(ctx context.Context, req *ReadResourceRequest) (*R
 	uri := req.Params.URI
 	// Look up the resource URI in the lists of resources and resource templates.
 	// This is a security check as well as an information lookup.
-	handler, mimeType, ok := s.lookupResourceHandler(uri)
-	if !ok {
+	var (
+		handler  ResourceHandler
+		mimeType string
+	)
+	switch r, rt := s.lookupResource(uri); {
+	case r != nil && featureVisible(ctx, req.Session, s.opts.ResourceFilter, r.resource):
+		handler, mimeType = r.handler, r.resource.MIMEType
+	case rt != nil && featureVisible(ctx, req.Session, s.opts.ResourceTemplateFilter, rt.resourceTemplate):
+		handler, mimeType = rt.handler, rt.resourceTemplate.MIMEType
+	default:
 		// Don't expose the server configuration to the client.
 		// Treat an unregistered resource the same as a registered one that couldn't be found.
 		return nil, ResourceNotFoundError(uri)
@@ -1024,26 +1592,79 @@ func (s *Server) readResource(ctx context.Context, req *ReadResourceRequest) (*R
 		if c.MIMEType == "" {
 			c.MIMEType = mimeType
 		}
+		if c.MIMEType == "" && s.opts.SniffResourceMIMETypes {
+			c.MIMEType = sniffMIMEType(c)
+		}
+	}
+	if r, ok := req.Params.GetRange(); ok {
+		res, err = applyByteRange(res, r)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return res, nil
 }
 
-// lookupResourceHandler returns the resource handler and MIME type for the resource or
-// resource template matching uri. If none, the last return value is false.
-func (s *Server) lookupResourceHandler(uri string) (ResourceHandler, string, bool) {
+// lookupResource returns the resource or resource template matching uri.
+// At most one of the results is non-nil; if none matches, both are nil.
+func (s *Server) lookupResource(uri string) (*serverResource, *serverResourceTemplate) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	// Try resources first.
 	if r, ok := s.resources.get(uri); ok {
-		return r.handler, r.resource.MIMEType, true
+		return r, nil
 	}
 	// Look for matching template.
 	for rt := range s.resourceTemplates.all() {
 		if rt.Matches(uri) {
-			return rt.handler, rt.resourceTemplate.MIMEType, true
+			return nil, rt
 		}
 	}
-	return nil, "", false
+	// Try again with normalized URIs.
+	if normalize := s.opts.NormalizeResourceURI; normalize != nil {
+		nuri := normalize(uri)
+		for r := range s.resources.all() {
+			if normalize(r.resource.URI) == nuri {
+				return r, nil
+			}
+		}
+		for rt := range s.resourceTemplates.all() {
+			if rt.Matches(nuri) {
+				return nil, rt
+			}
+		}
+	}
+	return nil, nil
+}
+
+// featureVisible reports whether the filter, one of the feature filters of
+// [ServerOptions], allows the session to see f. A nil filter allows
+// everything.
+func featureVisible[F any](ctx context.Context, ss *ServerSession, filter func(context.Context, *ServerSession, F) bool, f F) bool {
+	return filter == nil || filter(ctx, ss, f)
+}
+
+// resourceVisible reports whether the session may see the resource or
+// resource template matching uri, if any. If a resource or resource template
+// filter is set, a uri that matches neither is not visible.
+func (s *Server) resourceVisible(ctx context.Context, ss *ServerSession, uri string) bool {
+	switch r, rt := s.lookupResource(uri); {
+	case r != nil:
+		return featureVisible(ctx, ss, s.opts.ResourceFilter, r.resource)
+	case rt != nil:
+		return featureVisible(ctx, ss, s.opts.ResourceTemplateFilter, rt.resourceTemplate)
+	default:
+		return s.opts.ResourceFilter == nil && s.opts.ResourceTemplateFilter == nil
+	}
+}
+
+// snapshot returns a copy of fs that can be read without holding s.mu, so
+// that user-supplied filters are not called with the lock held.
+func snapshot[T any](s *Server, fs *featureSet[T]) *featureSet[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs.sortKeys() // so that the copy shares the sorted keys
+	return fs.clone()
 }
 
 // fileResourceHandler returns a ReadResourceHandler that reads paths using dir as
@@ -1126,6 +1747,9 @@ func (s *Server) subscribe(ctx context.Context, req *SubscribeRequest) (*emptyRe
 	if s.opts.SubscribeHandler == nil {
 		return nil, fmt.Errorf("%w: server does not support resource subscriptions", jsonrpc2.ErrMethodNotFound)
 	}
+	if !s.resourceVisible(ctx, req.Session, req.Params.URI) {
+		return nil, ResourceNotFoundError(req.Params.URI)
+	}
 	if err := s.opts.SubscribeHandler(ctx, req); err != nil {
 		return nil, err
 	}
@@ -1281,18 +1905,61 @@ func (s *Server) Run(ctx context.Context, t Transport) error {
 	}
 }
 
+// RunMultiple runs the server over each of the given transports
+// concurrently, as with [Server.Run], so that their sessions share the
+// server's tools, prompts and resources.
+//
+// Each session ends independently: a client disconnecting from one transport
+// does not end the sessions on the others. RunMultiple blocks until all the
+// sessions have ended, or the provided context is cancelled, in which case it
+// closes the sessions that remain. It returns the errors of all the sessions,
+// joined with [errors.Join]; if the context was cancelled, its error is
+// reported once.
+//
+// Transports served by an HTTP handler, such as [StreamableHTTPHandler], need
+// not be passed to RunMultiple: to serve a server over both stdio and HTTP,
+// return it from the handler's getServer function, and run the HTTP server
+// alongside RunMultiple or Run with a [StdioTransport].
+func (s *Server) RunMultiple(ctx context.Context, transports ...Transport) error {
+	errs := make([]error, len(transports))
+	var wg sync.WaitGroup
+	for i, t := range transports {
+		wg.Go(func() { errs[i] = s.Run(ctx, t) })
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = slices.DeleteFunc(errs, func(e error) bool { return e == nil || e == err })
+		if len(errs) == 0 {
+			return err
+		}
+		errs = append([]error{err}, errs...)
+	}
+	return errors.Join(errs...)
+}
+
 // bind implements the binder[*ServerSession] interface, so that Servers can
 // be connected using [connect].
 func (s *Server) bind(mcpConn Connection, conn *jsonrpc2.Connection, state *ServerSessionState, onClose func()) *ServerSession {
 	assert(mcpConn != nil && conn != nil, "nil connection")
 	ss := &ServerSession{conn: conn, mcpConn: mcpConn, server: s, onClose: onClose}
+	if n := s.opts.MaxConcurrentRequestsPerSession; n > 0 {
+		ss.requestSlots = make(chan struct{}, n)
+	}
+	if ttl := s.opts.IdempotencyKeyTTL; ttl > 0 {
+		ss.idempotentResults = &idempotencyCache{ttl: ttl}
+	}
 	if state != nil {
 		ss.state = *state
 	}
+	id := ss.ID()
 	s.mu.Lock()
 	s.sessions = append(s.sessions, ss)
+	if id != "" {
+		// The ID was reserved by [uniqueSessionTransport.Connect].
+		s.sessionIDs[id] = ss
+	}
 	s.mu.Unlock()
-	s.opts.Logger.Info("server session connected", "session_id", ss.ID())
+	s.opts.Logger.Info("server session connected", "session_id", id)
 	return ss
 }
 
@@ -1304,6 +1971,9 @@ func (s *Server) disconnect(cc *ServerSession) {
 	s.sessions = slices.DeleteFunc(s.sessions, func(cc2 *ServerSession) bool {
 		return cc2 == cc
 	})
+	if id := cc.ID(); id != "" && s.sessionIDs[id] == cc {
+		delete(s.sessionIDs, id)
+	}
 
 	for _, subscribedSessions := range s.resourceSubscriptions {
 		delete(subscribedSessions, cc)
@@ -1330,6 +2000,10 @@ type ServerSessionOptions struct {
 // [Connection.Wait]).
 //
 // If opts.State is non-nil, it is the initial state for the server.
+//
+// Connect fails if the transport's connection has a non-empty session ID (see
+// [Connection.SessionID]) that is already used by another session of the
+// server.
 func (s *Server) Connect(ctx context.Context, t Transport, opts *ServerSessionOptions) (*ServerSession, error) {
 	var state *ServerSessionState
 	var onClose func()
@@ -1339,7 +2013,13 @@ func (s *Server) Connect(ctx context.Context, t Transport, opts *ServerSessionOp
 	}
 
 	s.opts.Logger.Info("server connecting")
-	ss, err := connect(ctx, t, s, state, onClose, s.opts.Logger)
+	ct := t
+	if st, ok := t.(*StreamableServerTransport); !ok || !st.Stateless {
+		// Stateless transports are request-scoped, and legitimately share the
+		// session ID of the request that created them.
+		ct = uniqueSessionTransport{t, s}
+	}
+	ss, err := connect(ctx, ct, s, state, onClose, s.opts.Logger)
 	if err != nil {
 		s.opts.Logger.Error("server connect error", "error", err)
 		return nil, err
@@ -1358,6 +2038,7 @@ func (s *Server) Connect(ctx context.Context, t Transport, opts *ServerSessionOp
 	// guarantee of seeing this write, and -race flags it.
 	ss.mu.Lock()
 	ss.supportedVersions = filterSupportedVersions(t)
+	ss.transportKind = transportKind(t)
 	ss.mu.Unlock()
 
 	// Start keepalive before returning the session to avoid race conditions with Close.
@@ -1365,10 +2046,59 @@ func (s *Server) Connect(ctx context.Context, t Transport, opts *ServerSessionOp
 	if s.opts.KeepAlive > 0 {
 		ss.startKeepalive(ss.server.opts.KeepAlive)
 	}
+	if d := s.opts.InitializeTimeout; d > 0 {
+		ss.initializeTimer = s.clock.AfterFunc(d, ss.closeIfUninitialized)
+	}
 
 	return ss, nil
 }
 
+// uniqueSessionTransport wraps a Transport to reject connections whose
+// session ID is already in use by another session of the server.
+type uniqueSessionTransport struct {
+	Transport
+	server *Server
+}
+
+func (t uniqueSessionTransport) Connect(ctx context.Context) (Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := conn.SessionID()
+	if id == "" {
+		return conn, nil
+	}
+	s := t.server
+	s.mu.Lock()
+	_, dup := s.sessionIDs[id]
+	if !dup {
+		s.sessionIDs[id] = nil // reserved until the session is bound
+	}
+	s.mu.Unlock()
+	if dup {
+		conn.Close()
+		return nil, fmt.Errorf("session ID %q is already in use", id)
+	}
+	return conn, nil
+}
+
+// closeIfUninitialized closes the session if the client has not completed
+// initialization. See [ServerOptions.InitializeTimeout].
+func (ss *ServerSession) closeIfUninitialized() {
+	ss.mu.Lock()
+	params := ss.state.InitializeParams
+	initialized := ss.state.InitializedParams != nil ||
+		(params != nil && params.ProtocolVersion >= protocolVersion20260728)
+	ss.mu.Unlock()
+	if !initialized {
+		ss.server.opts.Logger.Error("client did not complete initialization; closing session",
+			"session_id", ss.ID(),
+			"timeout", ss.server.opts.InitializeTimeout)
+		_ = ss.Close()
+	}
+}
+
 // TODO: (nit) move all ServerSession methods below the ServerSession declaration.
 func (ss *ServerSession) initialized(ctx context.Context, params *InitializedParams) (Result, error) {
 	if params == nil {
@@ -1396,10 +2126,29 @@ func (ss *ServerSession) initialized(ctx context.Context, params *InitializedPar
 	if h := ss.server.opts.InitializedHandler; h != nil {
 		h(ctx, serverRequestFor(ss, params))
 	}
+	ss.sessionEstablished(ctx)
 	ss.server.opts.Logger.Info("session initialized")
 	return nil, nil
 }
 
+// sessionEstablished calls the SessionEstablishedHandler, if any, for ss,
+// whose InitializeParams must be set.
+func (ss *ServerSession) sessionEstablished(ctx context.Context) {
+	h := ss.server.opts.SessionEstablishedHandler
+	if h == nil {
+		return
+	}
+	initParams := ss.InitializeParams()
+	h(ctx, &SessionInfo{
+		Session:            ss,
+		ProtocolVersion:    negotiatedVersion(initParams.ProtocolVersion),
+		ClientInfo:         initParams.ClientInfo,
+		ClientCapabilities: initParams.Capabilities,
+		ServerInfo:         ss.server.impl,
+		ServerCapabilities: ss.server.capabilities(),
+	})
+}
+
 func (s *Server) callRootsListChangedHandler(ctx context.Context, req *RootsListChangedRequest) (Result, error) {
 	if h := s.opts.RootsListChangedHandler; h != nil {
 		h(ctx, req)
@@ -1408,6 +2157,15 @@ func (s *Server) callRootsListChangedHandler(ctx context.Context, req *RootsList
 }
 
 func (ss *ServerSession) callProgressNotificationHandler(ctx context.Context, p *ProgressNotificationParams) (Result, error) {
+	if token, ok := p.ProgressToken.(string); ok {
+		ss.mu.Lock()
+		partial := ss.samplingStreams[token]
+		ss.mu.Unlock()
+		if partial != nil {
+			partial(p.Message)
+			return nil, nil
+		}
+	}
 	if h := ss.server.opts.ProgressNotificationHandler; h != nil {
 		h(ctx, serverRequestFor(ss, p))
 	}
@@ -1419,7 +2177,24 @@ func (ss *ServerSession) callProgressNotificationHandler(ctx context.Context, p
 // This is typically used to report on the status of a long-running request
 // that was initiated by the client.
 func (ss *ServerSession) NotifyProgress(ctx context.Context, params *ProgressNotificationParams) error {
-	return handleNotify(ctx, notificationProgress, newServerRequest(ss, orZero[Params](params)))
+	return ss.Notify(ctx, notificationProgress, orZero[Params](params))
+}
+
+// Notify sends a notification with the given method and params to the client,
+// through the server's sending middleware. It is a low-level escape hatch for
+// custom or extension notifications: prefer typed methods such as
+// [ServerSession.NotifyProgress] and [ServerSession.Log] for standard ones,
+// as they perform additional checks.
+//
+// Params may be nil, or any [Params] value; custom params types can embed
+// [ParamsBase]. Notify does not check that the client understands the method.
+func (ss *ServerSession) Notify(ctx context.Context, method string, params Params) error {
+	if info, ok := ss.sendingMethodInfos()[method]; !ok {
+		ctx = context.WithValue(ctx, customNotificationContextKey{}, true)
+	} else if info.flags&notification == 0 {
+		return fmt.Errorf("%q is a request, not a notification", method)
+	}
+	return handleNotify(ctx, method, newServerRequest(ss, params))
 }
 
 // notifySubscriptionAcked sends a "notifications/subscriptions/acknowledged"
@@ -1450,15 +2225,56 @@ type ServerSession struct {
 	conn            *jsonrpc2.Connection
 	mcpConn         Connection
 	keepaliveCancel context.CancelFunc
+	initializeTimer timer
 
 	// supportedVersions is the subset of [supportedProtocolVersions] that the
 	// transport can actually serve, computed once at connection time from
 	// [ProtocolVersionSupporter] (if implemented by the transport) and used by
 	// the SEP-2575 server/discover handler.
 	supportedVersions []string
+	// transportKind is the kind of the session's transport, reported to
+	// handlers by [TransportKind].
+	transportKind string
+
+	// requestSlots limits concurrent requests in this session, if
+	// [ServerOptions.MaxConcurrentRequestsPerSession] is set.
+	requestSlots chan struct{}
+	// idempotentResults caches the session's tool call results by
+	// idempotency key, if [ServerOptions.IdempotencyKeyTTL] is set.
+	idempotentResults *idempotencyCache
 
 	mu    sync.Mutex
 	state ServerSessionState
+	// tokenInfo is the identity established by
+	// [ServerOptions.InitializeInterceptor], if any.
+	tokenInfo *auth.TokenInfo
+	// samplingStreams maps the progress tokens of sampling requests made with
+	// CreateMessageStream to their partial text callbacks.
+	samplingStreams    map[string]func(string)
+	nextSamplingStream int64
+	// outstanding holds the client requests currently being handled.
+	outstanding map[jsonrpc.ID]RequestInfo
+}
+
+// RequestInfo describes a client request that is being handled by a
+// [ServerSession].
+type RequestInfo struct {
+	ID     jsonrpc.ID
+	Method string
+	Start  time.Time // when the server began handling the request
+}
+
+// OutstandingRequests returns the client requests that the session is
+// currently handling, ordered by start time.
+//
+// It is intended for diagnostics, such as finding stuck requests or waiting
+// for in-flight work during a graceful shutdown.
+func (ss *ServerSession) OutstandingRequests() []RequestInfo {
+	ss.mu.Lock()
+	infos := slices.Collect(maps.Values(ss.outstanding))
+	ss.mu.Unlock()
+	slices.SortFunc(infos, func(a, b RequestInfo) int { return a.Start.Compare(b.Start) })
+	return infos
 }
 
 func (ss *ServerSession) updateState(mut func(*ServerSessionState)) {
@@ -1548,6 +2364,13 @@ func (ss *ServerSession) CreateMessage(ctx context.Context, params *CreateMessag
 		p2.Messages = []*SamplingMessage{} // avoid JSON "null"
 		params = &p2
 	}
+	if ss.server.opts.ValidateSamplingToolResults {
+		if err := params.ValidateToolResults(); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := withOptionalTimeout(ctx, ss.server.opts.SamplingTimeout)
+	defer cancel()
 	res, err := handleSend[*CreateMessageWithToolsResult](ctx, methodCreateMessage, newServerRequest(ss, orZero[Params](params)))
 	if err != nil {
 		return nil, err
@@ -1569,11 +2392,71 @@ func (ss *ServerSession) CreateMessage(ctx context.Context, params *CreateMessag
 	}, nil
 }
 
+// CreateMessageStream is like [ServerSession.CreateMessage], but also calls
+// partial with pieces of the response text as the client reports them,
+// before the final result is available.
+//
+// A client streams its response by sending progress notifications that carry
+// the progress token of the sampling request, with each new piece of text in
+// [ProgressNotificationParams.Message]:
+//
+//	req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+//		ProgressToken: req.Params.GetProgressToken(),
+//		Message:       delta,
+//		Progress:      float64(tokensSoFar),
+//	})
+//
+// CreateMessageStream replaces any progress token in params with one of its
+// own, and progress notifications bearing that token are not passed to
+// [ServerOptions.ProgressNotificationHandler]. The result always holds the
+// complete response; clients that do not stream simply return it.
+//
+// To preserve their order relative to the result, progress notifications are
+// handled as soon as they are read while a streaming request is in flight
+// (see [ServerOptions.PreemptiveMethods]). Consequently partial must not block,
+// and neither must ProgressNotificationHandler during that time.
+//
+// Deprecated: the sampling feature is deprecated as of protocol version
+// 2026-07-28 (SEP-2577). It remains functional during the deprecation window
+// (at least twelve months). Migrate to calling LLM provider APIs directly
+// from your server. See
+// https://modelcontextprotocol.io/seps/2577-deprecate-roots-sampling-and-logging.
+func (ss *ServerSession) CreateMessageStream(ctx context.Context, params *CreateMessageParams, partial func(text string)) (*CreateMessageResult, error) {
+	var p2 CreateMessageParams
+	if params != nil {
+		p2 = *params
+	}
+	p2.Meta = maps.Clone(p2.Meta)
+
+	ss.mu.Lock()
+	ss.nextSamplingStream++
+	token := fmt.Sprintf("sampling-stream-%d", ss.nextSamplingStream)
+	if ss.samplingStreams == nil {
+		ss.samplingStreams = make(map[string]func(string))
+	}
+	ss.samplingStreams[token] = partial
+	ss.mu.Unlock()
+	defer func() {
+		ss.mu.Lock()
+		delete(ss.samplingStreams, token)
+		ss.mu.Unlock()
+	}()
+
+	p2.SetProgressToken(token)
+	return ss.CreateMessage(ctx, &p2)
+}
+
 // CreateMessageWithTools sends a sampling request with tools to the client,
 // returning a [CreateMessageWithToolsResult] that supports array content
 // (for parallel tool calls). Use this instead of [ServerSession.CreateMessage]
 // when the request includes tools.
 //
+// If the client has not declared the sampling tools capability, the tools
+// and tool choice of the request are dropped, a warning is logged, and the
+// request continues as plain sampling: a client that only handles basic
+// sampling receives it as an ordinary "sampling/createMessage" request. Check
+// [ServerSession.ClientCapabilities] beforehand to detect this case.
+//
 // Deprecated: the sampling feature is deprecated as of protocol version
 // 2026-07-28 (SEP-2577). It remains functional during the deprecation window
 // (at least twelve months). Migrate to calling LLM provider APIs directly
@@ -1591,10 +2474,38 @@ func (ss *ServerSession) CreateMessageWithTools(ctx context.Context, params *Cre
 		p2.Messages = []*SamplingMessageV2{} // avoid JSON "null"
 		params = &p2
 	}
+	if len(params.Tools) > 0 || params.ToolChoice != nil {
+		// Per the spec, servers must not send tool-enabled sampling requests
+		// to clients that have not declared support for them. Fall back to
+		// plain sampling.
+		if iparams := ss.InitializeParams(); iparams == nil || iparams.Capabilities == nil ||
+			iparams.Capabilities.Sampling == nil || iparams.Capabilities.Sampling.Tools == nil {
+			ss.server.opts.Logger.Warn("client does not support tools in sampling; sending the request without tools",
+				"tools", len(params.Tools))
+			p2 := *params
+			p2.Tools = nil
+			p2.ToolChoice = nil
+			params = &p2
+		}
+	}
+	if ss.server.opts.ValidateSamplingToolResults {
+		if err := params.ValidateToolResults(); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := withOptionalTimeout(ctx, ss.server.opts.SamplingTimeout)
+	defer cancel()
 	return handleSend[*CreateMessageWithToolsResult](ctx, methodCreateMessage, newServerRequest(ss, orZero[Params](params)))
 }
 
 // Elicit sends an elicitation request to the client asking for user input.
+//
+// If the user accepts a form elicitation, the result content is validated
+// against the requested schema and the schema's defaults are filled in. By
+// default, declined and cancelled results are returned as sent by the client,
+// without validation or defaults; set
+// [ServerOptions.ElicitationDefaultsOnDecline] to fill in defaults for them
+// as well.
 func (ss *ServerSession) Elicit(ctx context.Context, params *ElicitParams) (*ElicitResult, error) {
 	if err := ss.checkInitialized(methodElicit); err != nil {
 		return nil, err
@@ -1630,12 +2541,18 @@ func (ss *ServerSession) Elicit(ctx context.Context, params *ElicitParams) (*Eli
 		}
 	}
 
-	res, err := handleSend[*ElicitResult](ctx, methodElicit, newServerRequest(ss, orZero[Params](params)))
+	sendCtx, cancel := withOptionalTimeout(ctx, ss.server.opts.ElicitationTimeout)
+	defer cancel()
+	res, err := handleSend[*ElicitResult](sendCtx, methodElicit, newServerRequest(ss, orZero[Params](params)))
 	if err != nil {
 		return nil, err
 	}
 
-	if res.Action != "accept" {
+	if err := checkElicitResult(res); err != nil {
+		return nil, err
+	}
+	accepted := res.Accepted()
+	if !accepted && !ss.server.opts.ElicitationDefaultsOnDecline {
 		return res, nil
 	}
 
@@ -1654,8 +2571,13 @@ func (ss *ServerSession) Elicit(ctx context.Context, params *ElicitParams) (*Eli
 	if err != nil {
 		return nil, err
 	}
-	if err := resolved.Validate(res.Content); err != nil {
-		return nil, fmt.Errorf("elicitation result content does not match requested schema: %v", err)
+	if accepted {
+		if err := resolved.Validate(res.Content); err != nil {
+			return nil, fmt.Errorf("elicitation result content does not match requested schema: %v", err)
+		}
+	} else if res.Content == nil {
+		// Content is typically absent for declined or cancelled elicitations.
+		res.Content = map[string]any{}
 	}
 	err = resolved.ApplyDefaults(&res.Content)
 	if err != nil {
@@ -1678,7 +2600,7 @@ func (ss *ServerSession) Elicit(ctx context.Context, params *ElicitParams) (*Eli
 // https://modelcontextprotocol.io/seps/2577-deprecate-roots-sampling-and-logging.
 func (ss *ServerSession) Log(ctx context.Context, params *LoggingMessageParams) error {
 	ss.mu.Lock()
-	logLevel := ss.state.LogLevel
+	logLevel := ss.state.levelFor(params.Logger)
 	ss.mu.Unlock()
 	if logLevel == "" {
 		// The spec is unclear, but seems to imply that no log messages are sent until the client
@@ -1686,10 +2608,35 @@ func (ss *ServerSession) Log(ctx context.Context, params *LoggingMessageParams)
 		// TODO(jba): read other SDKs, possibly file an issue.
 		return nil
 	}
-	if compareLevels(params.Level, logLevel) < 0 {
+	if params.Level.Compare(logLevel) < 0 {
 		return nil
 	}
-	return handleNotify(ctx, notificationLoggingMessage, newServerRequest(ss, orZero[Params](params)))
+	params, ok := ss.checkLogData(params)
+	if !ok {
+		return nil
+	}
+	return ss.Notify(ctx, notificationLoggingMessage, orZero[Params](params))
+}
+
+// checkLogData returns params with its data marshaled to JSON, and truncated
+// if it exceeds [ServerOptions.MaxLogDataSize]. It reports false if the data
+// cannot be marshaled.
+//
+// The caller's params are not modified.
+func (ss *ServerSession) checkLogData(params *LoggingMessageParams) (*LoggingMessageParams, bool) {
+	logger := ss.server.opts.Logger
+	data, err := json.Marshal(params.Data)
+	if err != nil {
+		logger.Warn("dropping log message with unmarshalable data", "logger", params.Logger, "error", err)
+		return nil, false
+	}
+	p2 := *params
+	p2.Data = json.RawMessage(data)
+	if limit := ss.server.opts.MaxLogDataSize; limit > 0 && len(data) > limit {
+		logger.Warn("truncating log message data", "logger", params.Logger, "size", len(data), "max", limit)
+		p2.Data = strings.ToValidUTF8(string(data[:limit]), "") + "...(truncated)"
+	}
+	return &p2, true
 }
 
 // AddSendingMiddleware wraps the current sending method handler using the provided
@@ -1797,10 +2744,26 @@ func (ss *ServerSession) receivingMethodHandler() MethodHandler {
 // getConn implements [session.getConn].
 func (ss *ServerSession) getConn() *jsonrpc2.Connection { return ss.conn }
 
+// preemptive implements [preemptiveHandler].
+func (ss *ServerSession) preemptive(method string) bool {
+	if method == notificationProgress {
+		// Progress for a streaming sampling request must be handled in order
+		// with the request's response, so that no partial text is lost.
+		ss.mu.Lock()
+		streaming := len(ss.samplingStreams) > 0
+		ss.mu.Unlock()
+		if streaming {
+			return true
+		}
+	}
+	return slices.Contains(ss.server.opts.PreemptiveMethods, method)
+}
+
 // handle invokes the method described by the given JSON RPC request.
 func (ss *ServerSession) handle(ctx context.Context, req *jsonrpc.Request) (any, error) {
 	ss.mu.Lock()
 	initialized := ss.state.InitializeParams != nil
+	kind := ss.transportKind
 	ss.mu.Unlock()
 
 	// Per-request protocol detection (SEP-2575): if the request carries
@@ -1825,6 +2788,12 @@ func (ss *ServerSession) handle(ctx context.Context, req *jsonrpc.Request) (any,
 		}
 	}
 
+	if !initialized && ss.server.opts.InitializeInterceptor != nil {
+		if err := ss.checkInitialize(ctx, req, validatedMeta); err != nil {
+			return nil, err
+		}
+	}
+
 	switch req.Method {
 	case methodInitialize, methodPing, notificationInitialized, notificationRootsListChanged, methodSetLevel, methodSubscribe, methodUnsubscribe:
 		if validatedMeta.usesNewProtocol {
@@ -1850,6 +2819,21 @@ func (ss *ServerSession) handle(ctx context.Context, req *jsonrpc.Request) (any,
 		}
 	}
 
+	if req.IsCall() {
+		info := RequestInfo{ID: req.ID, Method: req.Method, Start: ss.server.clock.Now()}
+		ss.mu.Lock()
+		if ss.outstanding == nil {
+			ss.outstanding = make(map[jsonrpc.ID]RequestInfo)
+		}
+		ss.outstanding[req.ID] = info
+		ss.mu.Unlock()
+		defer func() {
+			ss.mu.Lock()
+			delete(ss.outstanding, req.ID)
+			ss.mu.Unlock()
+		}()
+	}
+
 	// modelcontextprotocol/go-sdk#26: handle calls asynchronously, and
 	// notifications synchronously, except for 'initialize' which shouldn't be
 	// asynchronous to other
@@ -1861,13 +2845,189 @@ func (ss *ServerSession) handle(ctx context.Context, req *jsonrpc.Request) (any,
 	// server->client calls and notifications to the incoming request from which
 	// they originated. See [idContextKey] for details.
 	ctx = context.WithValue(ctx, idContextKey{}, req.ID)
+	if kind != "" {
+		ctx = context.WithValue(ctx, transportKindContextKey{}, kind)
+	}
+	if len(req.Params) > 0 {
+		ctx = context.WithValue(ctx, rawParamsContextKey{}, req.Params)
+	}
 	// For new-protocol requests, propagate the per-request log level.
 	if validatedMeta.usesNewProtocol {
 		ss.setLevel(ctx, &SetLoggingLevelParams{Level: validatedMeta.logLevel})
 	}
+	release := func() {}
+	if req.IsCall() && isLimitedMethod(req.Method) {
+		// Acquire the session slot first, so that a session waiting on its own
+		// limit does not hold a server-wide slot.
+		var err error
+		release, err = acquireSlots(ctx, ss.requestSlots, ss.server.requestSlots)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ss.mu.Lock()
+	tokenInfo := ss.tokenInfo
+	ss.mu.Unlock()
+	if tokenInfo != nil {
+		// Report the identity established by InitializeInterceptor, unless
+		// the transport provides one.
+		extra, _ := req.Extra.(*RequestExtra)
+		if extra == nil || extra.TokenInfo == nil {
+			var e RequestExtra
+			if extra != nil {
+				e = *extra
+			}
+			e.TokenInfo = tokenInfo
+			req.Extra = &e
+		}
+	}
+	if d := ss.server.methodTimeout(req.Method); d > 0 && req.IsCall() {
+		return handleWithTimeout(ctx, d, req.Method, func(ctx context.Context) (any, error) {
+			// Hold the request slots until the handler returns, even if the
+			// client has already been sent a timeout error: the handler's work
+			// still counts against the limits.
+			defer release()
+			return ss.handleMethod(ctx, req)
+		})
+	}
+	defer release()
+	return ss.handleMethod(ctx, req)
+}
+
+// checkInitialize calls [ServerOptions.InitializeInterceptor] if req
+// initializes the session.
+func (ss *ServerSession) checkInitialize(ctx context.Context, req *jsonrpc.Request, meta *validatedMeta) error {
+	var params *InitializeParams
+	switch {
+	case meta.usesNewProtocol:
+		if meta.initializeParams == nil {
+			return nil // a notification
+		}
+		params = meta.initializeParams
+	case req.Method == methodInitialize:
+		// Unmarshal as the initialize method does, to work around #607.
+		var v2 initializeParamsV2
+		if err := internaljson.Unmarshal(req.Params, &v2); err != nil {
+			return fmt.Errorf("%w: %v", jsonrpc2.ErrInvalidParams, err)
+		}
+		params = v2.toV1()
+	default:
+		return nil
+	}
+	info := &ConnectionInfo{TransportKind: ss.transportKind}
+	if c, ok := ss.mcpConn.(netConner); ok {
+		info.Conn = c.netConn()
+	}
+	if extra, ok := req.Extra.(*RequestExtra); ok && extra != nil {
+		info.Header = extra.Header
+	}
+	tokenInfo, err := ss.server.opts.InitializeInterceptor(ctx, info, params)
+	if err != nil {
+		return err
+	}
+	if tokenInfo != nil {
+		ss.mu.Lock()
+		ss.tokenInfo = tokenInfo
+		ss.mu.Unlock()
+	}
+	return nil
+}
+
+// handleMethod dispatches req to the handler for its method.
+func (ss *ServerSession) handleMethod(ctx context.Context, req *jsonrpc.Request) (any, error) {
+	if h := ss.server.opts.UnknownMethodHandler; h != nil {
+		if _, ok := ss.receivingMethodInfos()[req.Method]; !ok {
+			return h(ctx, ss, req)
+		}
+	}
 	return handleReceive(ctx, ss, req)
 }
 
+// methodTimeout returns the timeout for requests for method, or 0 if there is
+// none. See [ServerOptions.MethodTimeouts].
+func (s *Server) methodTimeout(method string) time.Duration {
+	if d, ok := s.opts.MethodTimeouts[method]; ok {
+		return d
+	}
+	if method == methodInitialize || method == methodSubscriptionsListen {
+		return 0
+	}
+	return s.opts.DefaultMethodTimeout
+}
+
+// handleWithTimeout calls handle with a context that is cancelled after d.
+// If handle has not returned by then, handleWithTimeout returns a
+// [CodeRequestTimeout] error without waiting for it.
+//
+// Unlike other timeouts, this one uses the wall clock rather than the
+// server's clock: the handler's context must carry a real deadline, so that
+// its Deadline method reports it and its Err method returns
+// [context.DeadlineExceeded], as handlers and the APIs they call expect. A
+// context cancelled by a clock timer could do neither. Tests control this
+// timeout with testing/synctest instead.
+func handleWithTimeout(ctx context.Context, d time.Duration, method string, handle func(context.Context) (any, error)) (any, error) {
+	timeoutErr := fmt.Errorf("%s timed out after %v", method, d)
+	ctx, cancel := context.WithTimeoutCause(ctx, d, timeoutErr)
+	defer cancel()
+	type result struct {
+		res any
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := handle(ctx)
+		done <- result{res, err}
+	}()
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-ctx.Done():
+		if context.Cause(ctx) != timeoutErr {
+			// The request was cancelled for another reason, such as by the
+			// client: let the handler respond, as without a timeout.
+			r := <-done
+			return r.res, r.err
+		}
+		return nil, &jsonrpc.Error{Code: CodeRequestTimeout, Message: timeoutErr.Error()}
+	}
+}
+
+// isLimitedMethod reports whether requests for method are subject to
+// [ServerOptions.MaxConcurrentRequests].
+func isLimitedMethod(method string) bool {
+	switch method {
+	case methodCallTool, methodGetPrompt, methodReadResource, methodComplete:
+		return true
+	}
+	_, standard := serverMethodInfos[method]
+	return !standard
+}
+
+// acquireSlots acquires a slot in each non-nil semaphore, in order, waiting
+// until one is available or ctx is done. On success, the returned func
+// releases the acquired slots.
+func acquireSlots(ctx context.Context, sems ...chan struct{}) (release func(), err error) {
+	var acquired []chan struct{}
+	release = func() {
+		for _, sem := range acquired {
+			<-sem
+		}
+	}
+	for _, sem := range sems {
+		if sem == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+			acquired = append(acquired, sem)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
 // InitializeParams returns the InitializeParams provided during the client's
 // initial connection.
 func (ss *ServerSession) InitializeParams() *InitializeParams {
@@ -1876,6 +3036,36 @@ func (ss *ServerSession) InitializeParams() *InitializeParams {
 	return ss.state.InitializeParams
 }
 
+// ClientCapabilities returns a normalized copy of the capabilities the client
+// declared when it initialized the session, or empty capabilities if it has
+// not yet done so. The result is never nil.
+//
+// Unlike the Capabilities of [ServerSession.InitializeParams], the result
+// has RootsV2 and the deprecated Roots field in agreement (see #607), and
+// non-nil Experimental and Extensions maps. Modifying it does not affect the
+// session.
+func (ss *ServerSession) ClientCapabilities() *ClientCapabilities {
+	var caps *ClientCapabilities
+	if params := ss.InitializeParams(); params != nil && params.Capabilities != nil {
+		caps = params.Capabilities.clone()
+	} else {
+		caps = &ClientCapabilities{}
+	}
+	if caps.RootsV2 == nil && caps.Roots.ListChanged {
+		caps.RootsV2 = &RootCapabilities{ListChanged: true}
+	}
+	if caps.RootsV2 != nil {
+		caps.Roots = *caps.RootsV2
+	}
+	if caps.Experimental == nil {
+		caps.Experimental = map[string]any{}
+	}
+	if caps.Extensions == nil {
+		caps.Extensions = map[string]any{}
+	}
+	return caps
+}
+
 func (ss *ServerSession) initialize(ctx context.Context, params *InitializeParams) (*InitializeResult, error) {
 	if params == nil {
 		return nil, fmt.Errorf("%w: \"params\" must be be provided", jsonrpc2.ErrInvalidParams)
@@ -1917,13 +3107,41 @@ func (ss *ServerSession) cancel(context.Context, *CancelledParams) (Result, erro
 }
 
 func (ss *ServerSession) setLevel(_ context.Context, params *SetLoggingLevelParams) (*emptyResult, error) {
+	logger := params.GetLogger()
 	ss.updateState(func(state *ServerSessionState) {
-		state.LogLevel = params.Level
+		if logger == "" {
+			state.LogLevel = params.Level
+			return
+		}
+		// Copy the map, since updateState shares the state with the
+		// transport.
+		levels := maps.Clone(state.LoggerLevels)
+		if levels == nil {
+			levels = make(map[string]LoggingLevel)
+		}
+		levels[logger] = params.Level
+		state.LoggerLevels = levels
 	})
-	ss.server.opts.Logger.Info("client log level set", "level", params.Level)
+	ss.server.opts.Logger.Info("client log level set", "level", params.Level, "logger", logger)
 	return &emptyResult{}, nil
 }
 
+// CancelAll cancels all of the session's in-flight requests, without closing
+// the session.
+//
+// Requests the session sent to the client fail with an error wrapping
+// [context.Canceled], and the client is notified of their cancellation.
+// Requests from the client that are being handled have their contexts
+// cancelled; handlers that respect cancellation return promptly. New requests
+// are unaffected.
+//
+// CancelAll lets a session drain its work before [ServerSession.Close], which
+// otherwise waits for ongoing requests to return. The error, if any, reports
+// cancellation notifications that could not be sent.
+func (ss *ServerSession) CancelAll() error {
+	return cancelAll(ss.conn)
+}
+
 // Close performs a graceful shutdown of the connection, preventing new
 // requests from being handled, and waiting for ongoing requests to return.
 // Close then terminates the connection.
@@ -1939,6 +3157,9 @@ func (ss *ServerSession) Close() error {
 		//    Close is idempotent and conn.Close() handles concurrent calls correctly
 		ss.keepaliveCancel()
 	}
+	if ss.initializeTimer != nil {
+		ss.initializeTimer.Stop()
+	}
 	err := ss.conn.Close()
 
 	if ss.onClose != nil && ss.calledOnClose.CompareAndSwap(false, true) {
@@ -1955,7 +3176,7 @@ func (ss *ServerSession) Wait() error {
 
 // startKeepalive starts the keepalive mechanism for this server session.
 func (ss *ServerSession) startKeepalive(interval time.Duration) {
-	startKeepalive(ss, interval, ss.server.opts.KeepAliveFailureThreshold, &ss.keepaliveCancel, ss.server.opts.Logger)
+	startKeepalive(ss, ss.server.clock, interval, ss.server.opts.KeepAliveFailureThreshold, &ss.keepaliveCancel, ss.server.opts.Logger)
 }
 
 // pageToken is the internal structure for the opaque pagination cursor.
@@ -1996,7 +3217,9 @@ func decodeCursor(cursor string) (*pageToken, error) {
 // from a featureSet. It populates the provided result res with the items
 // and sets its next cursor for subsequent pages.
 // If there are no more pages, the next cursor within the result will be an empty string.
-func paginateList[P listParams, R listResult[T], T any](fs *featureSet[T], pageSize int, params P, res R, setFunc func(R, []T)) (R, error) {
+// If keep is non-nil, only the items for which it returns true are listed,
+// so that every page except the last is full.
+func paginateList[P listParams, R listResult[T], T any](fs *featureSet[T], pageSize int, params P, res R, keep func(T) bool, setFunc func(R, []T)) (R, error) {
 	var seq iter.Seq[T]
 	if params.cursorPtr() == nil || *params.cursorPtr() == "" {
 		seq = fs.all()
@@ -2012,6 +3235,9 @@ func paginateList[P listParams, R listResult[T], T any](fs *featureSet[T], pageS
 	var count int
 	var features []T
 	for f := range seq {
+		if keep != nil && !keep(f) {
+			continue
+		}
 		count++
 		// If we've seen pageSize + 1 elements, we've gathered enough info to determine
 		// if there's a next page. Stop processing the sequence.
@@ -12,13 +12,20 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/auth"
 	"github.com/modelcontextprotocol/go-sdk/internal/jsonrpc2"
 	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 )
@@ -185,7 +192,7 @@ func TestServerPaginateBasic(t *testing.T) {
 			fs := newFeatureSet(func(t *testItem) string { return t.Name })
 			fs.add(tc.initialItems...)
 			params := &testListParams{Cursor: tc.inputCursor}
-			gotResult, err := paginateList(fs, tc.inputPageSize, params, &testListResult{}, func(res *testListResult, items []*testItem) {
+			gotResult, err := paginateList(fs, tc.inputPageSize, params, &testListResult{}, nil, func(res *testListResult, items []*testItem) {
 				res.Items = items
 			})
 			if (err != nil) != tc.wantErr {
@@ -216,7 +223,7 @@ func TestServerPaginateVariousPageSizes(t *testing.T) {
 		// Iterate through all pages, comparing sub-slices to the paginated list.
 		for {
 			params := &testListParams{Cursor: nextCursor}
-			gotResult, err := paginateList(fs, pageSize, params, &testListResult{}, func(res *testListResult, items []*testItem) {
+			gotResult, err := paginateList(fs, pageSize, params, &testListResult{}, nil, func(res *testListResult, items []*testItem) {
 				res.Items = items
 			})
 			if err != nil {
@@ -1451,3 +1458,1604 @@ func TestServerSessionHandle_RejectsRemovedMethodsOnNewProtocol(t *testing.T) {
 		})
 	}
 }
+
+func TestServerPreemptiveMethods(t *testing.T) {
+	for _, preempt := range []bool{false, true} {
+		t.Run(fmt.Sprintf("preempt=%t", preempt), func(t *testing.T) {
+			ctx := context.Background()
+			release := make(chan struct{})
+			opts := &ServerOptions{
+				// Notifications are handled synchronously, so a slow notification
+				// handler blocks the handler queue.
+				ProgressNotificationHandler: func(context.Context, *ProgressNotificationServerRequest) {
+					<-release
+				},
+			}
+			if preempt {
+				opts.PreemptiveMethods = []string{methodPing}
+			}
+			server := NewServer(testImpl, opts)
+			ct, st := NewInMemoryTransports()
+			ss, err := server.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+			cs, err := NewClient(testImpl, nil).Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+			defer close(release) // before closing the sessions, which await handlers
+
+			if err := cs.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: "t", Progress: 1}); err != nil {
+				t.Fatal(err)
+			}
+			pingCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			err = cs.Ping(pingCtx, nil)
+			if preempt && err != nil {
+				t.Errorf("Ping failed despite preemption: %v", err)
+			}
+			if !preempt && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Ping returned %v, want %v", err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestServerMaxConcurrentRequests(t *testing.T) {
+	for _, opts := range []*ServerOptions{
+		{MaxConcurrentRequests: 2},
+		{MaxConcurrentRequestsPerSession: 2},
+	} {
+		var (
+			mu              sync.Mutex
+			running, maxRun int
+			release         = make(chan struct{})
+		)
+		server := NewServer(testImpl, opts)
+		AddTool(server, &Tool{Name: "slow"}, func(ctx context.Context, req *CallToolRequest, args any) (*CallToolResult, any, error) {
+			mu.Lock()
+			running++
+			maxRun = max(maxRun, running)
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return &CallToolResult{}, nil, nil
+		})
+		cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Go(func() {
+				if _, err := cs.CallTool(context.Background(), &CallToolParams{Name: "slow"}); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+		time.Sleep(50 * time.Millisecond) // let requests pile up
+		// Other requests are not subject to the limit.
+		if _, err := cs.ListTools(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+		close(release)
+		wg.Wait()
+		cleanup()
+		if maxRun != 2 {
+			t.Errorf("%+v: max concurrent tool calls = %d, want 2", *opts, maxRun)
+		}
+	}
+}
+
+func TestServerInitializeTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		server := NewServer(testImpl, &ServerOptions{InitializeTimeout: 100 * time.Millisecond})
+
+		// A client that never initializes is disconnected.
+		ct, st := NewInMemoryTransports()
+		ss, err := server.Connect(ctx, st, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := ct.Connect(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		done := make(chan struct{})
+		go func() {
+			ss.Wait()
+			close(done)
+		}()
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+		select {
+		case <-done:
+		default:
+			t.Fatal("uninitialized session was not closed")
+		}
+
+		// A client that initializes in time is unaffected.
+		ct, st = NewInMemoryTransports()
+		ss, err = server.Connect(ctx, st, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ss.Close()
+		cs, err := NewClient(testImpl, nil).Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+		time.Sleep(200 * time.Millisecond)
+		if err := cs.Ping(ctx, nil); err != nil {
+			t.Errorf("Ping() after timeout: %v", err)
+		}
+	})
+}
+
+func TestServerAddResourceDuplicateURI(t *testing.T) {
+	var logbuf bytes.Buffer
+	server := NewServer(testImpl, &ServerOptions{Logger: slog.New(slog.NewTextHandler(&logbuf, nil))})
+	reader := func(text string) ResourceHandler {
+		return func(context.Context, *ReadResourceRequest) (*ReadResourceResult, error) {
+			return &ReadResourceResult{Contents: []*ResourceContents{{Text: text}}}, nil
+		}
+	}
+	server.AddResource(&Resource{URI: "file:///dup", Name: "first"}, reader("first"))
+	server.AddResource(&Resource{URI: "file:///dup", Name: "second"}, reader("second"))
+
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+	ctx := context.Background()
+
+	res, err := cs.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Resources) != 1 || res.Resources[0].Name != "second" {
+		t.Errorf("ListResources: got %v, want only the second resource", res.Resources)
+	}
+	read, err := cs.ReadResource(ctx, &ReadResourceParams{URI: "file:///dup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := read.Contents[0].Text; got != "second" {
+		t.Errorf("ReadResource: got %q, want %q", got, "second")
+	}
+	if !strings.Contains(logbuf.String(), "duplicate URI") {
+		t.Errorf("replacement was not logged; log:\n%s", logbuf.String())
+	}
+}
+
+func TestServerIdempotencyKeys(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		server := NewServer(testImpl, &ServerOptions{IdempotencyKeyTTL: time.Minute})
+		var calls atomic.Int32
+		handler := func(context.Context, *CallToolRequest) (*CallToolResult, error) {
+			n := calls.Add(1)
+			return &CallToolResult{Content: []Content{&TextContent{Text: fmt.Sprint(n)}}}, nil
+		}
+		schema := &jsonschema.Schema{Type: "object"}
+		server.AddTool(&Tool{Name: "idempotent", InputSchema: schema, Annotations: &ToolAnnotations{IdempotentHint: true}}, handler)
+		server.AddTool(&Tool{Name: "other", InputSchema: schema}, handler)
+		cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+		defer cleanup()
+
+		call := func(tool, key string) string {
+			t.Helper()
+			params := &CallToolParams{Name: tool}
+			if key != "" {
+				params.SetIdempotencyKey(key)
+			}
+			res, err := cs.CallTool(ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return res.Content[0].(*TextContent).Text
+		}
+
+		first := call("idempotent", "k1")
+		if got := call("idempotent", "k1"); got != first {
+			t.Errorf("retry with same key: got result %s, want cached result %s", got, first)
+		}
+		if got := call("idempotent", "k2"); got == first {
+			t.Error("call with different key returned cached result")
+		}
+		if got := call("idempotent", ""); got == first {
+			t.Error("call without key returned cached result")
+		}
+		other := call("other", "k3")
+		if got := call("other", "k3"); got == other {
+			t.Error("tool without IdempotentHint returned cached result")
+		}
+
+		time.Sleep(2 * time.Minute)
+		if got := call("idempotent", "k1"); got == first {
+			t.Error("retry after TTL returned expired result")
+		}
+	})
+}
+
+// TestServerIdempotencyKeysStateless checks that clients of a stateless
+// server, whose sessions have no ID, do not share cached results.
+func TestServerIdempotencyKeysStateless(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, &ServerOptions{IdempotencyKeyTTL: time.Minute})
+	server.AddTool(&Tool{
+		Name:        "whoami",
+		InputSchema: &jsonschema.Schema{Type: "object"},
+		Annotations: &ToolAnnotations{IdempotentHint: true},
+	}, func(_ context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		return &CallToolResult{Content: []Content{&TextContent{Text: req.Extra.Header.Get("X-User")}}}, nil
+	})
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{Stateless: true})
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	for _, user := range []string{"alice", "bob"} {
+		transport := &StreamableClientTransport{
+			Endpoint: httpServer.URL,
+			HTTPClient: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-User", user)
+				return http.DefaultTransport.RoundTrip(req)
+			})},
+		}
+		cs, err := NewClient(testImpl, nil).Connect(ctx, transport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params := &CallToolParams{Name: "whoami"}
+		params.SetIdempotencyKey("k")
+		res, err := cs.CallTool(ctx, params)
+		cs.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Content[0].(*TextContent).Text; got != user {
+			t.Errorf("call by %s returned %q", user, got)
+		}
+	}
+}
+
+func TestServerDryRun(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, &ServerOptions{IdempotencyKeyTTL: time.Minute})
+	var deleted atomic.Int32
+	handler := func(_ context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		if req.Params.GetDryRun() {
+			return &CallToolResult{Content: []Content{&TextContent{Text: "would delete 1 file"}}}, nil
+		}
+		deleted.Add(1)
+		return &CallToolResult{Content: []Content{&TextContent{Text: "deleted 1 file"}}}, nil
+	}
+	schema := &jsonschema.Schema{Type: "object"}
+	annotations := &ToolAnnotations{DestructiveHint: jsonschema.Ptr(true), IdempotentHint: true}
+	supported := &Tool{Name: "delete", InputSchema: schema, Annotations: annotations}
+	supported.SetSupportsDryRun(true)
+	server.AddTool(supported, handler)
+	server.AddTool(&Tool{Name: "unsupported", InputSchema: schema, Annotations: annotations}, handler)
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	res, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range res.Tools {
+		if got, want := tool.GetSupportsDryRun(), tool.Name == "delete"; got != want {
+			t.Errorf("tool %q: GetSupportsDryRun() = %t, want %t", tool.Name, got, want)
+		}
+	}
+
+	call := func(name string, dryRun bool) (string, error) {
+		params := &CallToolParams{Name: name}
+		params.SetIdempotencyKey("k")
+		params.SetDryRun(dryRun)
+		res, err := cs.CallTool(ctx, params)
+		if err != nil {
+			return "", err
+		}
+		return res.Content[0].(*TextContent).Text, nil
+	}
+
+	if got, err := call("delete", true); err != nil || got != "would delete 1 file" {
+		t.Errorf("dry run = %q, %v, want preview", got, err)
+	}
+	if deleted.Load() != 0 {
+		t.Error("dry run performed the deletion")
+	}
+	// The dry run must not be returned as the cached result of the real call.
+	if got, err := call("delete", false); err != nil || got != "deleted 1 file" {
+		t.Errorf("real call = %q, %v, want deletion", got, err)
+	}
+	if _, err := call("unsupported", true); err == nil || !strings.Contains(err.Error(), "does not support dry runs") {
+		t.Errorf("dry run of unsupported tool: got error %v, want rejection", err)
+	}
+	if got := deleted.Load(); got != 1 {
+		t.Errorf("deleted %d times, want 1", got)
+	}
+}
+
+func TestServerSafeMode(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, &ServerOptions{SafeMode: true})
+	var called atomic.Bool
+	handler := func(context.Context, *CallToolRequest) (*CallToolResult, error) {
+		called.Store(true)
+		return &CallToolResult{}, nil
+	}
+	schema := &jsonschema.Schema{Type: "object"}
+	server.AddTool(&Tool{Name: "read", InputSchema: schema, Annotations: &ToolAnnotations{ReadOnlyHint: true}}, handler)
+	server.AddTool(&Tool{Name: "write", InputSchema: schema, Annotations: &ToolAnnotations{ReadOnlyHint: false}}, handler)
+	server.AddTool(&Tool{Name: "unannotated", InputSchema: schema}, handler)
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	if _, err := cs.CallTool(ctx, &CallToolParams{Name: "read"}); err != nil {
+		t.Errorf("calling read-only tool: %v", err)
+	}
+	if !called.Load() {
+		t.Error("read-only tool was not called")
+	}
+	for _, name := range []string{"write", "unannotated"} {
+		called.Store(false)
+		_, err := cs.CallTool(ctx, &CallToolParams{Name: name})
+		if err == nil || !strings.Contains(err.Error(), "not available in read-only mode") {
+			t.Errorf("calling %q: got %v, want read-only mode error", name, err)
+		}
+		if called.Load() {
+			t.Errorf("handler of %q was called in safe mode", name)
+		}
+	}
+}
+
+func TestServerMaxToolArgumentBytes(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, &ServerOptions{MaxToolArgumentBytes: 30})
+	var called atomic.Int32
+	AddTool(server, &Tool{Name: "echo"}, func(_ context.Context, _ *CallToolRequest, args map[string]any) (*CallToolResult, any, error) {
+		called.Add(1)
+		return &CallToolResult{}, nil, nil
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	if _, err := cs.CallTool(ctx, &CallToolParams{Name: "echo", Arguments: map[string]any{"s": "short"}}); err != nil {
+		t.Fatalf("small arguments: %v", err)
+	}
+	_, err := cs.CallTool(ctx, &CallToolParams{Name: "echo", Arguments: map[string]any{"s": strings.Repeat("x", 100)}})
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeInvalidParams || !strings.Contains(rpcErr.Message, "too large") {
+		t.Errorf("large arguments: got %v, want invalid params error", err)
+	}
+	if got := called.Load(); got != 1 {
+		t.Errorf("handler called %d times, want 1", got)
+	}
+}
+
+func TestServerClientRequestTimeouts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		var cancelled atomic.Int32
+		wait := func(ctx context.Context) {
+			<-ctx.Done()
+			cancelled.Add(1)
+		}
+		client := NewClient(testImpl, &ClientOptions{
+			CreateMessageHandler: func(ctx context.Context, _ *CreateMessageRequest) (*CreateMessageResult, error) {
+				wait(ctx)
+				return nil, ctx.Err()
+			},
+			ElicitationHandler: func(ctx context.Context, _ *ElicitRequest) (*ElicitResult, error) {
+				wait(ctx)
+				return nil, ctx.Err()
+			},
+		})
+		server := NewServer(testImpl, &ServerOptions{
+			SamplingTimeout:    time.Second,
+			ElicitationTimeout: time.Second,
+		})
+		ct, st := NewInMemoryTransports()
+		ss, err := server.Connect(ctx, st, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ss.Close()
+		// The client is slow to read, and never responds.
+		cs, err := client.Connect(ctx, &latencyTransport{ct, 100 * time.Millisecond}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+
+		start := time.Now()
+		_, err = ss.CreateMessage(ctx, &CreateMessageParams{
+			MaxTokens: 10,
+			Messages:  []*SamplingMessage{{Role: "user", Content: &TextContent{Text: "hi"}}},
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("CreateMessage: got %v, want %v", err, context.DeadlineExceeded)
+		}
+		_, err = ss.Elicit(ctx, &ElicitParams{Message: "name?", RequestedSchema: &jsonschema.Schema{Type: "object"}})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Elicit: got %v, want %v", err, context.DeadlineExceeded)
+		}
+		if got, want := time.Since(start), 2*time.Second; got != want {
+			t.Errorf("requests took %v, want %v", got, want)
+		}
+
+		// The client learns that the requests were cancelled.
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if got := cancelled.Load(); got != 2 {
+			t.Errorf("client handlers cancelled: got %d, want 2", got)
+		}
+	})
+}
+
+func TestServerClone(t *testing.T) {
+	ctx := context.Background()
+	base := NewServer(testImpl, &ServerOptions{Instructions: "base"})
+	schema := &jsonschema.Schema{Type: "object"}
+	handler := func(context.Context, *CallToolRequest) (*CallToolResult, error) { return &CallToolResult{}, nil }
+	base.AddTool(&Tool{Name: "shared", InputSchema: schema}, handler)
+	var middlewareCalls atomic.Int32
+	base.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			middlewareCalls.Add(1)
+			return next(ctx, method, req)
+		}
+	})
+
+	tenant := base.Clone()
+	tenant.AddTool(&Tool{Name: "tenant", InputSchema: schema}, handler)
+	base.AddTool(&Tool{Name: "later", InputSchema: schema}, handler)
+
+	toolNames := func(s *Server) []string {
+		t.Helper()
+		cs, _, cleanup := basicClientServerConnection(t, nil, s, nil)
+		defer cleanup()
+		if got := cs.Instructions(); got != "base" {
+			t.Errorf("Instructions() = %q, want %q", got, "base")
+		}
+		res, err := cs.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	if diff := cmp.Diff([]string{"later", "shared"}, toolNames(base)); diff != "" {
+		t.Errorf("base tools mismatch (-want +got):\n%s", diff)
+	}
+	middlewareCalls.Store(0)
+	if diff := cmp.Diff([]string{"shared", "tenant"}, toolNames(tenant)); diff != "" {
+		t.Errorf("clone tools mismatch (-want +got):\n%s", diff)
+	}
+	if middlewareCalls.Load() == 0 {
+		t.Error("clone did not inherit receiving middleware")
+	}
+}
+
+func TestServerSessionOutstandingRequests(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "block", InputSchema: &jsonschema.Schema{Type: "object"}}, func(context.Context, *CallToolRequest) (*CallToolResult, error) {
+		close(started)
+		<-release
+		return &CallToolResult{}, nil
+	})
+	cs, ss, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	if got := ss.OutstandingRequests(); len(got) != 0 {
+		t.Fatalf("OutstandingRequests() before any call = %v, want none", got)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := cs.CallTool(ctx, &CallToolParams{Name: "block"})
+		errc <- err
+	}()
+	<-started
+	got := ss.OutstandingRequests()
+	if len(got) != 1 || got[0].Method != methodCallTool || got[0].Start.IsZero() || !got[0].ID.IsValid() {
+		t.Errorf("OutstandingRequests() during call = %+v, want one valid %s request", got, methodCallTool)
+	}
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if got := ss.OutstandingRequests(); len(got) != 0 {
+		t.Errorf("OutstandingRequests() after call = %v, want none", got)
+	}
+}
+
+func TestServerUnknownMethodHandler(t *testing.T) {
+	ctx := context.Background()
+	call := func(cs *ClientSession, method string) (map[string]any, error) {
+		var res map[string]any
+		err := cs.getConn().Call(ctx, method, map[string]any{}).Await(ctx, &res)
+		return res, err
+	}
+	wantCode := func(t *testing.T, err error, code int64) {
+		t.Helper()
+		var werr *jsonrpc.Error
+		if !errors.As(err, &werr) || werr.Code != code {
+			t.Errorf("got error %v, want code %d", err, code)
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		cs, _, cleanup := basicConnection(t, nil)
+		defer cleanup()
+		for _, method := range []string{"acme/experimental", methodCreateMessage} {
+			_, err := call(cs, method)
+			wantCode(t, err, jsonrpc.CodeMethodNotFound)
+		}
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		var methods []string
+		server := NewServer(testImpl, &ServerOptions{
+			UnknownMethodHandler: func(_ context.Context, _ *ServerSession, req *jsonrpc.Request) (any, error) {
+				methods = append(methods, req.Method)
+				if req.Method == "acme/experimental" {
+					return map[string]any{"ok": true}, nil
+				}
+				return nil, &jsonrpc.Error{Code: -32000, Message: "not yet"}
+			},
+		})
+		cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+		defer cleanup()
+
+		res, err := call(cs, "acme/experimental")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res["ok"] != true {
+			t.Errorf("got result %v, want ok", res)
+		}
+		_, err = call(cs, "acme/future")
+		wantCode(t, err, -32000)
+		// Known methods don't reach the handler.
+		if _, err := cs.ListTools(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"acme/experimental", "acme/future"}; !slices.Equal(methods, want) {
+			t.Errorf("handler called for %v, want %v", methods, want)
+		}
+	})
+}
+
+func TestTransportKind(t *testing.T) {
+	ctx := context.Background()
+	newServer := func(kinds chan<- string) *Server {
+		server := NewServer(testImpl, nil)
+		server.AddTool(&Tool{Name: "kind", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, _ *CallToolRequest) (*CallToolResult, error) {
+			kinds <- TransportKind(ctx)
+			return &CallToolResult{}, nil
+		})
+		return server
+	}
+
+	kinds := make(chan string, 1)
+	cs, _, cleanup := basicClientServerConnection(t, nil, newServer(kinds), nil)
+	defer cleanup()
+	if _, err := cs.CallTool(ctx, &CallToolParams{Name: "kind"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-kinds; got != TransportKindInMemory {
+		t.Errorf("TransportKind = %q, want %q", got, TransportKindInMemory)
+	}
+
+	for _, tt := range []struct {
+		t    Transport
+		want string
+	}{
+		{&StdioTransport{}, TransportKindStdio},
+		{&StreamableServerTransport{}, TransportKindStreamableHTTP},
+		{&SSEServerTransport{}, TransportKindSSE},
+		{&IOTransport{}, TransportKindIO},
+		{&LoggingTransport{Transport: &StdioTransport{}}, TransportKindStdio},
+		{&latencyTransport{}, ""},
+	} {
+		if got := transportKind(tt.t); got != tt.want {
+			t.Errorf("transportKind(%T) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+	if got := TransportKind(ctx); got != "" {
+		t.Errorf("TransportKind(context.Background()) = %q, want \"\"", got)
+	}
+}
+
+func TestServerSessionNotify(t *testing.T) {
+	ctx := context.Background()
+	var ct, st Transport = NewInMemoryTransports()
+	var logbuf safeBuffer
+	ct = &LoggingTransport{Transport: ct, Writer: &logbuf}
+
+	server := NewServer(testImpl, nil)
+	var sent []string
+	server.AddSendingMiddleware(func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			sent = append(sent, method)
+			return next(ctx, method, req)
+		}
+	})
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+	progress := make(chan float64, 1)
+	client := NewClient(testImpl, &ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *ProgressNotificationClientRequest) {
+			progress <- req.Params.Progress
+		},
+	})
+	cs, err := client.Connect(ctx, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// Standard notifications are delivered to the typed handlers.
+	if err := ss.Notify(ctx, notificationProgress, &ProgressNotificationParams{ProgressToken: "t", Progress: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-progress; got != 0.5 {
+		t.Errorf("progress = %v, want 0.5", got)
+	}
+
+	// Custom notifications are sent as is.
+	type eventParams struct {
+		ParamsBase
+		Kind string `json:"kind"`
+	}
+	if err := ss.Notify(ctx, "acme/event", &eventParams{Kind: "deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	// The in-memory transport delivers messages in order, so a ping response
+	// means the notification was received.
+	if err := cs.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if log := string(logbuf.Bytes()); !strings.Contains(log, `"method":"acme/event","params":{"kind":"deploy"}`) {
+		t.Errorf("client did not receive the custom notification; log:\n%s", log)
+	}
+
+	if err := ss.Notify(ctx, methodListRoots, nil); err == nil {
+		t.Error("Notify with a request method succeeded unexpectedly")
+	}
+	if want := []string{notificationProgress, "acme/event"}; !slices.Equal(sent, want) {
+		t.Errorf("sending middleware saw %v, want %v", sent, want)
+	}
+}
+
+func TestNewServerFromRegistry(t *testing.T) {
+	ctx := context.Background()
+	resourceHandler := func(context.Context, *ReadResourceRequest) (*ReadResourceResult, error) {
+		return &ReadResourceResult{}, nil
+	}
+	promptHandler := func(context.Context, *GetPromptRequest) (*GetPromptResult, error) {
+		return &GetPromptResult{}, nil
+	}
+
+	server, err := NewServerFromRegistry(testImpl, nil, Registry{
+		Tools:             []*ServerTool{NewServerTool(&Tool{Name: "greet"}, sayHi)},
+		Prompts:           []*ServerPrompt{{&Prompt{Name: "code_review"}, promptHandler}},
+		Resources:         []*ServerResource{{&Resource{Name: "info", URI: "file:///info.txt"}, resourceHandler}},
+		ResourceTemplates: []*ServerResourceTemplate{{&ResourceTemplate{Name: "files", URITemplate: "file:///{name}"}, resourceHandler}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+	res, err := cs.CallTool(ctx, &CallToolParams{Name: "greet", Arguments: map[string]any{"Name": "user"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Content[0].(*TextContent).Text, "hi user"; got != want {
+		t.Errorf("greet = %q, want %q", got, want)
+	}
+	prompts, err := cs.ListPrompts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, err := cs.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := cs.ListResourceTemplates(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts.Prompts) != 1 || len(resources.Resources) != 1 || len(templates.ResourceTemplates) != 1 {
+		t.Errorf("got %d prompts, %d resources, %d resource templates; want 1 of each",
+			len(prompts.Prompts), len(resources.Resources), len(templates.ResourceTemplates))
+	}
+
+	// All registration errors are reported together.
+	_, err = NewServerFromRegistry(testImpl, nil, Registry{
+		Tools: []*ServerTool{
+			NewServerTool(&Tool{Name: "greet"}, sayHi),
+			NewServerTool(&Tool{Name: "greet"}, sayHi),
+			{Tool: &Tool{Name: "noschema"}, Handler: func(context.Context, *CallToolRequest) (*CallToolResult, error) { return nil, nil }},
+			NewServerTool(&Tool{Name: "badschema", InputSchema: &jsonschema.Schema{Type: "string"}}, sayHi),
+		},
+		Prompts:           []*ServerPrompt{{&Prompt{Name: "nohandler"}, nil}},
+		Resources:         []*ServerResource{{&Resource{URI: "file:///a"}, resourceHandler}, {&Resource{URI: "file:///a"}, resourceHandler}},
+		ResourceTemplates: []*ServerResourceTemplate{{&ResourceTemplate{URITemplate: "file:///{a"}, resourceHandler}},
+	})
+	if err == nil {
+		t.Fatal("NewServerFromRegistry succeeded unexpectedly")
+	}
+	for _, want := range []string{
+		`tool "greet": duplicate name`,
+		`tool "noschema": missing input schema`,
+		`tool "badschema": input schema`,
+		`prompt "nohandler": missing handler`,
+		`resource "file:///a": duplicate URI`,
+		`resource template "file:///{a": invalid URI template`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestToolErrorMessage(t *testing.T) {
+	ctx := context.Background()
+	type args struct {
+		Token string `json:"token"`
+	}
+	server := NewServer(testImpl, &ServerOptions{
+		ToolErrorMessage: func(_ context.Context, req *CallToolRequest, err error) string {
+			return fmt.Sprintf("tool %q failed", req.Params.Name)
+		},
+	})
+	AddTool(server, &Tool{Name: "fail"}, func(_ context.Context, _ *CallToolRequest, a args) (*CallToolResult, any, error) {
+		return nil, nil, fmt.Errorf("invalid token %q", a.Token)
+	})
+	server.AddTool(&Tool{Name: "custom", InputSchema: &jsonschema.Schema{Type: "object"}}, func(context.Context, *CallToolRequest) (*CallToolResult, error) {
+		res := &CallToolResult{Content: []Content{&TextContent{Text: "try again later"}}}
+		res.SetError(errors.New("internal detail"))
+		return res, nil
+	})
+	var gotErrs []string
+	server.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			res, err := next(ctx, method, req)
+			if res, ok := res.(*CallToolResult); ok && res.GetError() != nil {
+				gotErrs = append(gotErrs, res.GetError().Error())
+			}
+			return res, err
+		}
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	for _, test := range []struct {
+		name string
+		args any
+		want string
+	}{
+		{"fail", map[string]any{"token": "s3cret"}, `tool "fail" failed`},
+		{"fail", map[string]any{"token": 42}, `tool "fail" failed`}, // validation error
+		{"custom", nil, "try again later"},
+	} {
+		res, err := cs.CallTool(ctx, &CallToolParams{Name: test.name, Arguments: test.args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Content[0].(*TextContent).Text; !res.IsError || got != test.want {
+			t.Errorf("%s(%v) = %q (IsError %t), want error %q", test.name, test.args, got, res.IsError, test.want)
+		}
+	}
+	if len(gotErrs) != 3 || !strings.Contains(gotErrs[0], "s3cret") {
+		t.Errorf("middleware saw errors %q, want the original errors", gotErrs)
+	}
+}
+
+func TestServerFeatureFilters(t *testing.T) {
+	ctx := context.Background()
+	// Only the "admin" client sees features whose names start with "admin".
+	allowed := func(ss *ServerSession, name string) bool {
+		return !strings.HasPrefix(name, "admin") || ss.InitializeParams().ClientInfo.Name == "admin"
+	}
+	server := NewServer(testImpl, &ServerOptions{
+		// With one item per page, filtering must not leave pages empty.
+		PageSize:           1,
+		SubscribeHandler:   func(context.Context, *SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *UnsubscribeRequest) error { return nil },
+		ToolFilter:         func(_ context.Context, ss *ServerSession, t *Tool) bool { return allowed(ss, t.Name) },
+		PromptFilter: func(_ context.Context, ss *ServerSession, p *Prompt) bool {
+			return allowed(ss, p.Name)
+		},
+		ResourceFilter: func(_ context.Context, ss *ServerSession, r *Resource) bool {
+			return allowed(ss, r.Name)
+		},
+		ResourceTemplateFilter: func(_ context.Context, ss *ServerSession, rt *ResourceTemplate) bool {
+			return allowed(ss, rt.Name)
+		},
+	})
+	toolHandler := func(context.Context, *CallToolRequest) (*CallToolResult, error) { return &CallToolResult{}, nil }
+	promptHandler := func(context.Context, *GetPromptRequest) (*GetPromptResult, error) { return &GetPromptResult{}, nil }
+	resourceHandler := func(_ context.Context, req *ReadResourceRequest) (*ReadResourceResult, error) {
+		return &ReadResourceResult{Contents: []*ResourceContents{{URI: req.Params.URI, Text: "x"}}}, nil
+	}
+	// Results of the admin tool cached for the admin client must not be
+	// returned to others.
+	server.AddReceivingMiddleware(ToolResultCache(time.Hour, 0))
+	cacheable := &ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true}
+	for _, name := range []string{"public", "admin"} {
+		server.AddTool(&Tool{Name: name, InputSchema: &jsonschema.Schema{Type: "object"}, Annotations: cacheable}, toolHandler)
+		server.AddPrompt(&Prompt{Name: name}, promptHandler)
+		server.AddResource(&Resource{Name: name, URI: "file:///" + name}, resourceHandler)
+		server.AddResourceTemplate(&ResourceTemplate{Name: name, URITemplate: "file:///" + name + "/{x}"}, resourceHandler)
+	}
+
+	for _, test := range []struct {
+		client string
+		want   []string
+	}{
+		{"admin", []string{"admin", "public"}},
+		{"guest", []string{"public"}},
+	} {
+		t.Run(test.client, func(t *testing.T) {
+			client := NewClient(&Implementation{Name: test.client, Version: "v1"}, nil)
+			cs, _, cleanup := basicClientServerConnection(t, client, server, nil)
+			defer cleanup()
+
+			var tools, prompts, resources, templates []string
+			for tool, err := range cs.Tools(ctx, nil) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				tools = append(tools, tool.Name)
+			}
+			for prompt, err := range cs.Prompts(ctx, nil) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				prompts = append(prompts, prompt.Name)
+			}
+			for resource, err := range cs.Resources(ctx, nil) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				resources = append(resources, resource.Name)
+			}
+			for template, err := range cs.ResourceTemplates(ctx, nil) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				templates = append(templates, template.Name)
+			}
+			for kind, got := range map[string][]string{"tools": tools, "prompts": prompts, "resources": resources, "templates": templates} {
+				slices.Sort(got)
+				if diff := cmp.Diff(test.want, got); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", kind, diff)
+				}
+			}
+
+			// Every page is full.
+			firstTools, err := cs.ListTools(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			firstPrompts, err := cs.ListPrompts(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			firstResources, err := cs.ListResources(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			firstTemplates, err := cs.ListResourceTemplates(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for kind, n := range map[string]int{
+				"tools":     len(firstTools.Tools),
+				"prompts":   len(firstPrompts.Prompts),
+				"resources": len(firstResources.Resources),
+				"templates": len(firstTemplates.ResourceTemplates),
+			} {
+				if n != 1 {
+					t.Errorf("first page of %s has %d items, want 1", kind, n)
+				}
+			}
+			if firstTools.NextCursor != "" && len(test.want) == 1 {
+				t.Errorf("tools/list returned next cursor %q after the last visible tool", firstTools.NextCursor)
+			}
+
+			// Hidden features can't be used either.
+			wantOK := test.client == "admin"
+			_, err = cs.CallTool(ctx, &CallToolParams{Name: "admin"})
+			if gotOK := err == nil; gotOK != wantOK {
+				t.Errorf("calling admin tool: got error %v, want success %t", err, wantOK)
+			}
+			_, err = cs.GetPrompt(ctx, &GetPromptParams{Name: "admin"})
+			if gotOK := err == nil; gotOK != wantOK {
+				t.Errorf("getting admin prompt: got error %v, want success %t", err, wantOK)
+			}
+			for _, uri := range []string{"file:///admin", "file:///admin/1"} {
+				_, err = cs.ReadResource(ctx, &ReadResourceParams{URI: uri})
+				if gotOK := err == nil; gotOK != wantOK {
+					t.Errorf("reading %s: got error %v, want success %t", uri, err, wantOK)
+				}
+			}
+
+			// Subscriptions are checked too. Use the legacy protocol, which
+			// reports subscription errors to the client.
+			ct, st := NewInMemoryTransports()
+			ss, err := server.Connect(ctx, st, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ss.Close()
+			legacy, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer legacy.Close()
+			for uri, want := range map[string]bool{
+				"file:///public":  true,
+				"file:///admin":   wantOK,
+				"file:///admin/1": wantOK,
+				"file:///missing": false,
+			} {
+				err := legacy.Subscribe(ctx, &SubscribeParams{URI: uri})
+				if got := err == nil; got != want {
+					t.Errorf("subscribing to %s: got error %v, want success %t", uri, err, want)
+				}
+			}
+		})
+	}
+}
+
+// sessionIDTransport is a Transport whose connections report a fixed session
+// ID.
+type sessionIDTransport struct {
+	Transport
+	id string
+}
+
+func (t sessionIDTransport) Connect(ctx context.Context) (Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sessionIDConn{Connection: conn, id: t.id}, nil
+}
+
+// sessionIDConn is not comparable, like some real connections, so that
+// comparing it with == panics.
+type sessionIDConn struct {
+	Connection
+	id string
+	_  []byte
+}
+
+func (c sessionIDConn) SessionID() string { return c.id }
+
+func TestServerConnectDuplicateSessionID(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	connect := func(id string) (*ServerSession, error) {
+		_, st := NewInMemoryTransports()
+		return server.Connect(ctx, sessionIDTransport{st, id}, nil)
+	}
+
+	ss1, err := connect("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connect("abc"); err == nil {
+		t.Error("Connect with duplicate session ID succeeded unexpectedly")
+	}
+	// Empty session IDs are not unique.
+	for range 2 {
+		ss, err := connect("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ss.Close()
+	}
+
+	// Once the session ends, its ID may be reused.
+	ss1.Close()
+	ss1.Wait()
+	ss2, err := connect("abc")
+	if err != nil {
+		t.Fatalf("Connect after session ended: %v", err)
+	}
+	ss2.Close()
+
+	if id := NewSessionID(); len(id) < 26 || id == NewSessionID() {
+		t.Errorf("NewSessionID() = %q, want a long random ID", id)
+	}
+}
+
+func TestServerRunMultiple(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "greet"}, sayHi)
+
+	var clientTransports, serverTransports []Transport
+	for range 2 {
+		ct, st := NewInMemoryTransports()
+		clientTransports = append(clientTransports, ct)
+		serverTransports = append(serverTransports, st)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.RunMultiple(ctx, serverTransports...) }()
+
+	client := NewClient(testImpl, nil)
+	var sessions []*ClientSession
+	for _, ct := range clientTransports {
+		cs, err := client.Connect(ctx, ct, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+		sessions = append(sessions, cs)
+	}
+
+	// Both sessions share the server's tools.
+	for i, cs := range sessions {
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "greet", Arguments: map[string]any{"Name": "user"}}); err != nil {
+			t.Errorf("session %d: CallTool: %v", i, err)
+		}
+	}
+
+	// Ending one session leaves the other running.
+	sessions[0].Close()
+	if err := sessions[1].Ping(ctx, nil); err != nil {
+		t.Errorf("Ping after other session closed: %v", err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("RunMultiple returned early: %v", err)
+	default:
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("RunMultiple after cancel = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestProgressTokenContext(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "work"}, func(ctx context.Context, req *CallToolRequest, _ any) (*CallToolResult, any, error) {
+		token, ok := ProgressToken(ctx)
+		if !ok {
+			return &CallToolResult{Content: []Content{&TextContent{Text: "no token"}}}, nil, nil
+		}
+		if err := req.Session.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: token, Progress: 1}); err != nil {
+			return nil, nil, err
+		}
+		return &CallToolResult{Content: []Content{&TextContent{Text: fmt.Sprint(token)}}}, nil, nil
+	})
+	progress := make(chan any, 10)
+	client := NewClient(testImpl, &ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *ProgressNotificationClientRequest) {
+			progress <- req.Params.ProgressToken
+		},
+	})
+	cs, _, cleanup := basicClientServerConnection(t, client, server, nil)
+	defer cleanup()
+
+	for _, test := range []struct {
+		token any
+		want  string
+	}{
+		{nil, "no token"},
+		{"abc", "abc"},
+		{42, "42"},
+	} {
+		params := &CallToolParams{Name: "work", Arguments: map[string]any{}}
+		if test.token != nil {
+			params.SetProgressToken(test.token)
+		}
+		res, err := cs.CallTool(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Content[0].(*TextContent).Text; got != test.want {
+			t.Errorf("token %v: tool saw %q, want %q", test.token, got, test.want)
+		}
+		if test.token != nil {
+			if got := <-progress; fmt.Sprint(got) != test.want {
+				t.Errorf("token %v: progress notification token = %v, want %v", test.token, got, test.want)
+			}
+		}
+	}
+}
+
+func TestGetPromptResultBuilder(t *testing.T) {
+	hi := &TextContent{Text: "hi"}
+	img := &ImageContent{Data: []byte("img"), MIMEType: "image/png"}
+	got, err := NewGetPromptResult("greeting").User(hi, img).Assistant(hi).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &GetPromptResult{
+		Description: "greeting",
+		Messages: []*PromptMessage{
+			{Role: RoleUser, Content: hi},
+			{Role: RoleUser, Content: img},
+			{Role: RoleAssistant, Content: hi},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(GetPromptResult{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(UserMessage(hi, img), want.Messages[:2]); diff != "" {
+		t.Errorf("UserMessage mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(AssistantMessage(hi), want.Messages[2:]); diff != "" {
+		t.Errorf("AssistantMessage mismatch (-want +got):\n%s", diff)
+	}
+
+	// An empty result still has a non-nil Messages slice.
+	if res, err := NewGetPromptResult("").Result(); err != nil || res.Messages == nil {
+		t.Errorf("empty result = %+v, %v; want non-nil messages", res, err)
+	}
+
+	for _, b := range []*GetPromptResultBuilder{
+		NewGetPromptResult("").Message("system", hi),
+		NewGetPromptResult("").User(hi, nil),
+	} {
+		if res, err := b.Result(); err == nil {
+			t.Errorf("Result() = %+v, want error", res)
+		}
+	}
+}
+
+func TestListChangedCoalescing(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		var tools, prompts, resources atomic.Int32
+		client := NewClient(testImpl, &ClientOptions{
+			ToolListChangedHandler:     func(context.Context, *ToolListChangedRequest) { tools.Add(1) },
+			PromptListChangedHandler:   func(context.Context, *PromptListChangedRequest) { prompts.Add(1) },
+			ResourceListChangedHandler: func(context.Context, *ResourceListChangedRequest) { resources.Add(1) },
+		})
+		const delay = 50 * time.Millisecond
+		server := NewServer(testImpl, &ServerOptions{ListChangedDelay: delay})
+		ct, st := NewInMemoryTransports()
+		ss, err := server.Connect(ctx, st, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ss.Close()
+		cs, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+
+		burst := func() {
+			for i := range 100 {
+				name := fmt.Sprintf("f%d", i)
+				AddTool(server, &Tool{Name: name}, sayHi)
+				server.AddPrompt(&Prompt{Name: name}, nil)
+				server.AddResource(&Resource{Name: name, URI: "file:///" + name}, nil)
+				// Longer than the default delay, but shorter than the
+				// configured one.
+				time.Sleep(2 * notificationDelay)
+			}
+			server.RemoveTools("f0")
+			server.RemovePrompts("f0")
+			server.RemoveResources("file:///f0")
+			time.Sleep(2 * delay)
+			synctest.Wait()
+		}
+		check := func(want int32) {
+			t.Helper()
+			for name, n := range map[string]*atomic.Int32{"tools": &tools, "prompts": &prompts, "resources": &resources} {
+				if got := n.Load(); got != want {
+					t.Errorf("got %d %s/list_changed notifications, want %d", got, name, want)
+				}
+			}
+		}
+		burst()
+		check(1)
+		burst()
+		check(2)
+	})
+}
+
+func TestListChangedMaxDelay(t *testing.T) {
+	// A steady stream of changes does not postpone the list_changed
+	// notification indefinitely.
+	ctx := context.Background()
+	notified := make(chan struct{}, 10)
+	client := NewClient(testImpl, &ClientOptions{
+		ToolListChangedHandler: func(context.Context, *ToolListChangedRequest) { notified <- struct{}{} },
+	})
+	const delay = 10 * time.Millisecond
+	server := NewServer(testImpl, &ServerOptions{ListChangedDelay: delay})
+	clock := newFakeClock()
+	server.clock = clock
+	ct, st := NewInMemoryTransports()
+	ss, err := server.Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+	cs, err := client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// Each change comes within the delay of the previous one, so only the
+	// deadline lets the notification through.
+	for i := range 2 * maxListChangedDelayFactor {
+		AddTool(server, &Tool{Name: fmt.Sprintf("t%d", i)}, sayHi)
+		clock.Advance(delay / 2)
+	}
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no tools/list_changed notification after the maximum delay")
+	}
+}
+
+func TestServerListDefinitions(t *testing.T) {
+	s := NewServer(testImpl, nil)
+	if got := s.ListToolDefinitions(); got == nil || len(got) != 0 {
+		t.Errorf("ListToolDefinitions() = %v, want empty non-nil", got)
+	}
+	AddTool(s, &Tool{Name: "b"}, sayHi)
+	AddTool(s, &Tool{Name: "a"}, sayHi)
+	s.AddPrompt(&Prompt{Name: "p"}, nil)
+	s.AddResource(&Resource{Name: "r", URI: "file:///r"}, nil)
+	s.AddResourceTemplate(&ResourceTemplate{Name: "rt", URITemplate: "file:///{x}"}, nil)
+
+	tools := s.ListToolDefinitions()
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(names, want) {
+		t.Errorf("tool names = %v, want %v", names, want)
+	}
+	if got := s.ListPromptDefinitions(); len(got) != 1 || got[0].Name != "p" {
+		t.Errorf("ListPromptDefinitions() = %v", got)
+	}
+	if got := s.ListResourceDefinitions(); len(got) != 1 || got[0].URI != "file:///r" {
+		t.Errorf("ListResourceDefinitions() = %v", got)
+	}
+	if got := s.ListResourceTemplateDefinitions(); len(got) != 1 || got[0].URITemplate != "file:///{x}" {
+		t.Errorf("ListResourceTemplateDefinitions() = %v", got)
+	}
+
+	// The snapshot is unaffected by later changes.
+	s.RemoveTools("a")
+	if len(tools) != 2 {
+		t.Errorf("snapshot changed after RemoveTools: %v", tools)
+	}
+	if got := s.ListToolDefinitions(); len(got) != 1 || got[0].Name != "b" {
+		t.Errorf("after RemoveTools, ListToolDefinitions() = %v", got)
+	}
+
+	// Listing is safe concurrently with changes.
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			AddTool(s, &Tool{Name: fmt.Sprint("t", i)}, sayHi)
+			s.ListToolDefinitions()
+		})
+	}
+	wg.Wait()
+	if got := len(s.ListToolDefinitions()); got != 11 {
+		t.Errorf("got %d tools, want 11", got)
+	}
+}
+
+func TestMethodTimeouts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		server := NewServer(testImpl, &ServerOptions{
+			MethodTimeouts:       map[string]time.Duration{"tools/call": time.Second, "prompts/get": 0},
+			DefaultMethodTimeout: 2 * time.Second,
+		})
+		handlerErr := make(chan error, 1)
+		AddTool(server, &Tool{Name: "stuck"}, func(ctx context.Context, _ *CallToolRequest, _ any) (*CallToolResult, any, error) {
+			// Ignore ctx for a while, as a runaway handler would.
+			time.Sleep(time.Hour)
+			handlerErr <- ctx.Err()
+			return &CallToolResult{}, nil, nil
+		})
+		AddTool(server, &Tool{Name: "fast"}, func(context.Context, *CallToolRequest, any) (*CallToolResult, any, error) {
+			return &CallToolResult{}, nil, nil
+		})
+		server.AddPrompt(&Prompt{Name: "slow"}, func(context.Context, *GetPromptRequest) (*GetPromptResult, error) {
+			time.Sleep(time.Minute)
+			return &GetPromptResult{}, nil
+		})
+		server.AddResource(&Resource{Name: "r", URI: "file:///r"}, func(ctx context.Context, _ *ReadResourceRequest) (*ReadResourceResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+		defer cleanup()
+
+		checkTimeout := func(name string, err error, want time.Duration, start time.Time) {
+			t.Helper()
+			var jerr *jsonrpc.Error
+			if !errors.As(err, &jerr) || jerr.Code != CodeRequestTimeout {
+				t.Errorf("%s: got error %v, want code %d", name, err, CodeRequestTimeout)
+			}
+			if got := time.Since(start); got != want {
+				t.Errorf("%s: timed out after %v, want %v", name, got, want)
+			}
+		}
+
+		start := time.Now()
+		_, err := cs.CallTool(ctx, &CallToolParams{Name: "stuck"})
+		checkTimeout("stuck tool", err, time.Second, start)
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "fast"}); err != nil {
+			t.Errorf("fast tool: %v", err)
+		}
+
+		// The default applies to methods without an entry.
+		start = time.Now()
+		_, err = cs.ReadResource(ctx, &ReadResourceParams{URI: "file:///r"})
+		checkTimeout("resource", err, 2*time.Second, start)
+
+		// A zero entry disables the timeout.
+		if _, err := cs.GetPrompt(ctx, &GetPromptParams{Name: "slow"}); err != nil {
+			t.Errorf("slow prompt: %v", err)
+		}
+
+		// The handler's context was cancelled at the deadline.
+		if err := <-handlerErr; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("stuck handler context error = %v, want deadline exceeded", err)
+		}
+	})
+}
+
+func TestMethodTimeoutsHoldSlots(t *testing.T) {
+	// A handler that outlives its timeout keeps its request slot until it
+	// returns.
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		server := NewServer(testImpl, &ServerOptions{
+			MethodTimeouts:        map[string]time.Duration{"tools/call": time.Second},
+			MaxConcurrentRequests: 1,
+		})
+		AddTool(server, &Tool{Name: "stuck"}, func(context.Context, *CallToolRequest, any) (*CallToolResult, any, error) {
+			time.Sleep(time.Minute)
+			return &CallToolResult{}, nil, nil
+		})
+		AddTool(server, &Tool{Name: "fast"}, func(context.Context, *CallToolRequest, any) (*CallToolResult, any, error) {
+			return &CallToolResult{}, nil, nil
+		})
+		cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+		defer cleanup()
+
+		start := time.Now()
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "stuck"}); err == nil {
+			t.Fatal("stuck tool succeeded, want timeout")
+		}
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "fast"}); err != nil {
+			t.Fatalf("fast tool: %v", err)
+		}
+		if got := time.Since(start); got != time.Minute {
+			t.Errorf("fast tool returned after %v, want %v", got, time.Minute)
+		}
+	})
+}
+
+func TestInitializeInterceptor(t *testing.T) {
+	for _, version := range []string{protocolVersion20251125, latestProtocolVersion} {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+			var gotInfo *ConnectionInfo
+			server := NewServer(testImpl, &ServerOptions{
+				InitializeInterceptor: func(_ context.Context, info *ConnectionInfo, params *InitializeParams) (*auth.TokenInfo, error) {
+					gotInfo = info
+					if params.ClientInfo.Name != "trusted" {
+						return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidRequest, Message: "untrusted client"}
+					}
+					return &auth.TokenInfo{UserID: "alice"}, nil
+				},
+			})
+			AddTool(server, &Tool{Name: "whoami"}, func(_ context.Context, req *CallToolRequest, _ any) (*CallToolResult, any, error) {
+				var user string
+				if req.Extra != nil && req.Extra.TokenInfo != nil {
+					user = req.Extra.TokenInfo.UserID
+				}
+				return NewCallToolResult().Text(user).Result(), nil, nil
+			})
+
+			connect := func(name string) (*ClientSession, error) {
+				ct, st := NewInMemoryTransports()
+				if _, err := server.Connect(ctx, st, nil); err != nil {
+					t.Fatal(err)
+				}
+				client := NewClient(&Implementation{Name: name, Version: "v1.0.0"}, nil)
+				return client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: version})
+			}
+
+			if cs, err := connect("untrusted"); err == nil {
+				// With the new protocol, initialization happens on the first
+				// request.
+				_, err = cs.CallTool(ctx, &CallToolParams{Name: "whoami"})
+				cs.Close()
+				if err == nil || !strings.Contains(err.Error(), "untrusted client") {
+					t.Errorf("untrusted client: got error %v, want rejection", err)
+				}
+			} else if !strings.Contains(err.Error(), "untrusted client") {
+				t.Errorf("untrusted client: got error %v, want rejection", err)
+			}
+
+			cs, err := connect("trusted")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+			res, err := cs.CallTool(ctx, &CallToolParams{Name: "whoami"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Content[0].(*TextContent).Text; got != "alice" {
+				t.Errorf("tool saw user %q, want %q", got, "alice")
+			}
+			if gotInfo.TransportKind != TransportKindInMemory || gotInfo.Conn == nil {
+				t.Errorf("got ConnectionInfo %+v, want in-memory kind and non-nil Conn", gotInfo)
+			}
+		})
+	}
+}
+
+func TestOnInitialize(t *testing.T) {
+	ctx := context.Background()
+	allowed := []string{"good-client"}
+	var intercepted []string // clients seen by InitializeInterceptor
+	server := NewServer(testImpl, &ServerOptions{
+		OnInitialize: func(_ context.Context, params *InitializeParams) error {
+			if !slices.Contains(allowed, params.ClientInfo.Name) {
+				return fmt.Errorf("client %q is not allowed", params.ClientInfo.Name)
+			}
+			return nil
+		},
+		InitializeInterceptor: func(_ context.Context, _ *ConnectionInfo, params *InitializeParams) (*auth.TokenInfo, error) {
+			intercepted = append(intercepted, params.ClientInfo.Name)
+			return nil, nil
+		},
+	})
+	connect := func(name string) (*ClientSession, error) {
+		ct, st := NewInMemoryTransports()
+		if _, err := server.Connect(ctx, st, nil); err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(&Implementation{Name: name, Version: "v1.0.0"}, nil)
+		return client.Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	}
+
+	if cs, err := connect("bad-client"); err == nil {
+		cs.Close()
+		t.Error("bad-client: connection succeeded, want initialize failure")
+	} else if !strings.Contains(err.Error(), `client "bad-client" is not allowed`) {
+		t.Errorf("bad-client: got error %v, want rejection", err)
+	}
+
+	cs, err := connect("good-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if err := cs.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The interceptor only sees clients accepted by OnInitialize.
+	if want := []string{"good-client"}; !slices.Equal(intercepted, want) {
+		t.Errorf("InitializeInterceptor saw %v, want %v", intercepted, want)
+	}
+}
+
+func TestServerSessionClientCapabilities(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *InitializeParams
+		want   *ClientCapabilities
+	}{
+		{
+			name: "uninitialized",
+			want: &ClientCapabilities{Experimental: map[string]any{}, Extensions: map[string]any{}},
+		},
+		{
+			name:   "nil capabilities",
+			params: &InitializeParams{},
+			want:   &ClientCapabilities{Experimental: map[string]any{}, Extensions: map[string]any{}},
+		},
+		{
+			name: "deprecated roots",
+			params: func() *InitializeParams {
+				caps := &ClientCapabilities{}
+				caps.Roots.ListChanged = true
+				return &InitializeParams{Capabilities: caps}
+			}(),
+			want: func() *ClientCapabilities {
+				caps := &ClientCapabilities{
+					Experimental: map[string]any{},
+					Extensions:   map[string]any{},
+					RootsV2:      &RootCapabilities{ListChanged: true},
+				}
+				caps.Roots.ListChanged = true
+				return caps
+			}(),
+		},
+		{
+			name: "roots v2",
+			params: &InitializeParams{Capabilities: &ClientCapabilities{
+				RootsV2:     &RootCapabilities{ListChanged: true},
+				Elicitation: &ElicitationCapabilities{},
+				Extensions:  map[string]any{"example.com/ext": map[string]any{}},
+			}},
+			want: func() *ClientCapabilities {
+				caps := &ClientCapabilities{
+					Experimental: map[string]any{},
+					Extensions:   map[string]any{"example.com/ext": map[string]any{}},
+					RootsV2:      &RootCapabilities{ListChanged: true},
+					Elicitation:  &ElicitationCapabilities{},
+				}
+				caps.Roots.ListChanged = true
+				return caps
+			}(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ss := &ServerSession{}
+			ss.state.InitializeParams = test.params
+			got := ss.ClientCapabilities()
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ClientCapabilities() mismatch (-want +got):\n%s", diff)
+			}
+			// Modifying the result does not affect the session.
+			got.Experimental["x"] = true
+			if diff := cmp.Diff(test.want, ss.ClientCapabilities()); diff != "" {
+				t.Errorf("ClientCapabilities() changed after modification (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// A connected client's roots capability is reported.
+	ctx := context.Background()
+	ct, st := NewInMemoryTransports()
+	ss, err := NewServer(testImpl, nil).Connect(ctx, st, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := NewClient(testImpl, nil).Connect(ctx, ct, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if got := ss.ClientCapabilities().RootsV2; got == nil || !got.ListChanged {
+		t.Errorf("ClientCapabilities().RootsV2 = %+v, want listChanged", got)
+	}
+}
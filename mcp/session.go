@@ -4,6 +4,8 @@
 
 package mcp
 
+import "log/slog"
+
 // hasSessionID is the interface which, if implemented by connections, informs
 // the session about their session ID.
 //
@@ -14,6 +16,12 @@ type hasSessionID interface {
 	SessionID() string
 }
 
+// hasLastEventID is the interface of connections that can resume a stream of
+// server-sent events.
+type hasLastEventID interface {
+	LastEventID() string
+}
+
 // ServerSessionState is the state of a session.
 type ServerSessionState struct {
 	// InitializeParams are the parameters from 'initialize'.
@@ -25,5 +33,43 @@ type ServerSessionState struct {
 	// LogLevel is the logging level for the session.
 	LogLevel LoggingLevel `json:"logLevel"`
 
+	// LoggerLevels holds the logging levels for individual loggers, which
+	// override LogLevel. See [SetLoggingLevelParams.SetLogger].
+	LoggerLevels map[string]LoggingLevel `json:"loggerLevels,omitempty"`
+
 	// TODO: resource subscriptions
 }
+
+// SessionInfo describes a session established by the initialization
+// handshake, as seen from one of its sides. It consolidates information
+// otherwise spread across [InitializeParams] and [InitializeResult], so that
+// the session can be recorded, for example in a single log entry.
+//
+// See [ServerOptions.SessionEstablishedHandler] and
+// [ClientOptions.SessionEstablishedHandler].
+type SessionInfo struct {
+	// Session is the [ServerSession] or [ClientSession] that was established.
+	Session Session
+	// ProtocolVersion is the negotiated protocol version.
+	ProtocolVersion    string
+	ClientInfo         *Implementation
+	ClientCapabilities *ClientCapabilities
+	ServerInfo         *Implementation
+	ServerCapabilities *ServerCapabilities
+}
+
+// LogValue implements [slog.LogValuer], summarizing the session.
+func (i *SessionInfo) LogValue() slog.Value {
+	var attrs []slog.Attr
+	if i.Session != nil {
+		attrs = append(attrs, slog.String("session_id", i.Session.ID()))
+	}
+	attrs = append(attrs, slog.String("protocol_version", i.ProtocolVersion))
+	if c := i.ClientInfo; c != nil {
+		attrs = append(attrs, slog.Group("client", "name", c.Name, "version", c.Version))
+	}
+	if s := i.ServerInfo; s != nil {
+		attrs = append(attrs, slog.Group("server", "name", s.Name, "version", s.Version))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -97,6 +97,16 @@ type Session interface {
 }
 
 // Middleware is a function from [MethodHandler] to [MethodHandler].
+//
+// Middleware need not call the handler it wraps: it may return a result or an
+// error directly, for example to serve cached results, enforce rate limits or
+// reject unauthorized requests. A result returned this way must be a pointer
+// to the result type of the method, such as *[ListToolsResult] for
+// "tools/list" or *[CallToolResult] for "tools/call".
+//
+// Middleware must not modify request params or handler results in place,
+// since they may be shared with other middleware. Use [CloneParams] and
+// [CloneResult] to make copies that are safe to rewrite.
 type Middleware func(MethodHandler) MethodHandler
 
 // addMiddleware wraps the handler in the middleware functions.
@@ -106,9 +116,39 @@ func addMiddleware(handlerp *MethodHandler, middleware []Middleware) {
 	}
 }
 
+// ChainReceiving composes receiving middleware into a single [Middleware].
+// The first middleware is outermost, so it runs first on each request.
+//
+// Server.AddReceivingMiddleware(ChainReceiving(m1, m2), m3) is equivalent to
+// Server.AddReceivingMiddleware(m1, m2, m3). Libraries can use ChainReceiving
+// to export a bundle of middleware as one value.
+func ChainReceiving(middleware ...Middleware) Middleware {
+	middleware = slices.Clone(middleware)
+	return func(h MethodHandler) MethodHandler {
+		addMiddleware(&h, middleware)
+		return h
+	}
+}
+
+// ChainSending composes sending middleware into a single [Middleware], with
+// the same ordering as [ChainReceiving]: the first middleware is outermost.
+// Receiving and sending middleware have the same type, so ChainSending is
+// ChainReceiving under another name, for readability at call sites.
+func ChainSending(middleware ...Middleware) Middleware {
+	return ChainReceiving(middleware...)
+}
+
+// customNotificationContextKey marks the context of a notification sent with
+// [ServerSession.Notify] or [ClientSession.Notify], which may use a method the
+// SDK doesn't know.
+type customNotificationContextKey struct{}
+
 func defaultSendingMethodHandler(ctx context.Context, method string, req Request) (Result, error) {
 	info, ok := req.GetSession().sendingMethodInfos()[method]
 	if !ok {
+		if ctx.Value(customNotificationContextKey{}) != nil {
+			return nil, req.GetSession().getConn().Notify(ctx, method, req.GetParams())
+		}
 		// This can be called from user code, with an arbitrary value for method.
 		return nil, jsonrpc2.ErrNotHandled
 	}
@@ -146,6 +186,15 @@ func orZero[T any, P *U, U any](p P) T {
 	return any(p).(T)
 }
 
+// withOptionalTimeout returns a context that is done after d, or ctx itself
+// if d is not positive.
+func withOptionalTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
 func handleNotify(ctx context.Context, method string, req Request) error {
 	mh := req.GetSession().sendingMethodHandler()
 	_, err := mh(ctx, method, req)
@@ -183,6 +232,11 @@ func handleReceive[S Session](ctx context.Context, session S, jreq *jsonrpc.Requ
 		return nil, fmt.Errorf("handling '%s': %w", jreq.Method, err)
 	}
 
+	if params != nil && !params.isNil() {
+		if pt := getProgressToken(params); pt != nil {
+			ctx = context.WithValue(ctx, progressTokenContextKey{}, pt)
+		}
+	}
 	mh := session.receivingMethodHandler()
 	re, _ := jreq.Extra.(*RequestExtra)
 	req := info.newRequest(session, params, re)
@@ -376,6 +430,9 @@ const (
 	// before processing the request. The client should execute the elicitation handler
 	// with the elicitations provided in the error data.
 	CodeURLElicitationRequired = -32042
+	// CodeRequestTimeout indicates that the server did not finish handling a
+	// request within the time allowed by [ServerOptions.MethodTimeouts].
+	CodeRequestTimeout = -32023
 )
 
 // CodeResourceNotFound indicates that a requested resource could not be found.
@@ -442,25 +499,30 @@ func notifySessions[S Session, P Params](sessions []S, method string, params P,
 	// TODO: there's a potential spec violation here, when the feature list
 	// changes before the session (client or server) is initialized.
 	for _, s := range sessions {
-		req := newRequest(s, params)
-		if err := handleNotify(ctx, method, req); err != nil {
+		if err := notify(ctx, s, method, params); err != nil {
 			logger.Warn(fmt.Sprintf("calling %s: %v", method, err))
 		}
 	}
 }
 
-func newRequest[S Session, P Params](s S, p P) Request {
+// notify sends a notification to s with its Notify method, so that the
+// SDK's own notifications are sent the same way as users' ones.
+func notify[S Session](ctx context.Context, s S, method string, params Params) error {
 	switch s := any(s).(type) {
 	case *ClientSession:
-		return &ClientRequest[P]{Session: s, Params: p}
+		return s.Notify(ctx, method, params)
 	case *ServerSession:
-		return &ServerRequest[P]{Session: s, Params: p}
+		return s.Notify(ctx, method, params)
 	default:
 		panic("bad session")
 	}
 }
 
 // Meta is additional metadata for requests, responses and other types.
+//
+// Metadata is sent as the "_meta" property of the value that holds it. Nil or
+// empty metadata is omitted, so "_meta" appears on the wire only if it has at
+// least one key.
 type Meta map[string]any
 
 // GetMeta returns metadata from a value.
@@ -475,6 +537,21 @@ func getProgressToken(p Params) any {
 	return p.GetMeta()[progressTokenKey]
 }
 
+// progressTokenContextKey is the context key for the progress token of the
+// request being handled. See [ProgressToken].
+type progressTokenContextKey struct{}
+
+// ProgressToken returns the progress token of the incoming request being
+// handled with ctx, and reports whether the request has one.
+//
+// A handler can use the token to report progress to the peer, for example
+// with [ServerSession.NotifyProgress], without inspecting its request's
+// params. Since the token is decoded from JSON, a numeric token is a float64.
+func ProgressToken(ctx context.Context) (any, bool) {
+	pt := ctx.Value(progressTokenContextKey{})
+	return pt, pt != nil
+}
+
 func setProgressToken(p Params, pt any) {
 	switch pt.(type) {
 	// Support int32 and int64 for atomic.IntNN.
@@ -490,6 +567,42 @@ func setProgressToken(p Params, pt any) {
 	m[progressTokenKey] = pt
 }
 
+const idempotencyKeyKey = MetaKeyPrefix + "idempotencyKey"
+
+func getIdempotencyKey(p Params) string {
+	key, _ := p.GetMeta()[idempotencyKeyKey].(string)
+	return key
+}
+
+func setIdempotencyKey(p Params, key string) {
+	m := p.GetMeta()
+	if m == nil {
+		m = map[string]any{}
+		p.SetMeta(m)
+	}
+	m[idempotencyKeyKey] = key
+}
+
+const dryRunKey = MetaKeyPrefix + "dryRun"
+
+func getDryRun(p Params) bool {
+	dryRun, _ := p.GetMeta()[dryRunKey].(bool)
+	return dryRun
+}
+
+func setDryRun(p Params, dryRun bool) {
+	m := p.GetMeta()
+	if !dryRun {
+		delete(m, dryRunKey)
+		return
+	}
+	if m == nil {
+		m = map[string]any{}
+		p.SetMeta(m)
+	}
+	m[dryRunKey] = true
+}
+
 // extractRequestMeta performs a lightweight partial unmarshal of the `_meta`
 // field from a JSON-RPC request's raw params.
 func extractRequestMeta(rawParams json.RawMessage) Meta {
@@ -565,6 +678,23 @@ type Request interface {
 	GetExtra() *RequestExtra
 }
 
+// rawParamsContextKey is the context key for the raw params of a request
+// received by a server.
+type rawParamsContextKey struct{}
+
+// RawParams returns the params of the request being handled exactly as they
+// were received, given the context passed to a server's receiving middleware
+// or request handlers. Unlike re-marshaling the decoded params, this
+// reproduces the request byte for byte, as an audit log may require.
+//
+// RawParams returns nil if the request had no params, or if ctx does not
+// belong to a request received by a server. The caller must not modify the
+// result.
+func RawParams(ctx context.Context) json.RawMessage {
+	raw, _ := ctx.Value(rawParamsContextKey{}).(json.RawMessage)
+	return raw
+}
+
 // A ClientRequest is a request to a client.
 type ClientRequest[P Params] struct {
 	Session *ClientSession
@@ -617,6 +747,45 @@ func (r *ServerRequest[P]) GetParams() Params { return r.Params }
 func (r *ClientRequest[P]) GetExtra() *RequestExtra { return nil }
 func (r *ServerRequest[P]) GetExtra() *RequestExtra { return r.Extra }
 
+// ReportProgress sends a progress notification for the request to the server,
+// using the progress token from the request's params. If the server did not
+// ask for progress, ReportProgress does nothing.
+//
+// It is intended for long-running client handlers, such as those for sampling
+// or elicitation. Pass the context of the handler, so that the notification
+// is associated with the request being handled. A total of zero means the
+// total is unknown.
+func (r *ClientRequest[P]) ReportProgress(ctx context.Context, progress, total float64, message string) error {
+	pt := requestProgressToken(r.Params)
+	if pt == nil {
+		return nil
+	}
+	return r.Session.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: pt, Progress: progress, Total: total, Message: message})
+}
+
+// ReportProgress sends a progress notification for the request to the client,
+// using the progress token from the request's params. If the client did not
+// ask for progress, ReportProgress does nothing.
+//
+// Pass the context of the handler, so that transports such as
+// [StreamableServerTransport] send the notification along with the response
+// to the request. A total of zero means the total is unknown.
+func (r *ServerRequest[P]) ReportProgress(ctx context.Context, progress, total float64, message string) error {
+	pt := requestProgressToken(r.Params)
+	if pt == nil {
+		return nil
+	}
+	return r.Session.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: pt, Progress: progress, Total: total, Message: message})
+}
+
+// requestProgressToken returns the progress token of p, or nil.
+func requestProgressToken(p Params) any {
+	if p == nil || p.isNil() {
+		return nil
+	}
+	return getProgressToken(p)
+}
+
 // ProtocolVersion returns the protocol version negotiated for this request.
 //
 // For requests following the >= 2026-07-28 protocol, the value is read from
@@ -781,6 +950,44 @@ type ResultBase struct {
 
 func (*ResultBase) isResult() {}
 
+// CloneParams returns a deep copy of p.
+//
+// Middleware should not modify the params of the request it receives, as
+// other middleware and the handler may share them. Instead, it can rewrite a
+// copy made by CloneParams, and pass a new request holding the copy to the
+// next handler.
+//
+// The copy is made by a JSON round trip, so it holds what would be sent on
+// the wire: fields that are not serialized are zero, and _meta values hold
+// their JSON form.
+func CloneParams[P Params](p P) (P, error) {
+	if any(p) == nil || p.isNil() {
+		return p, nil
+	}
+	return cloneJSON(p)
+}
+
+// CloneResult returns a deep copy of r, made in the same way as
+// [CloneParams]. Middleware should use it to modify a result returned by the
+// handler it wraps, since that result may be shared.
+func CloneResult[R Result](r R) (R, error) {
+	if v := reflect.ValueOf(r); !v.IsValid() || v.Kind() == reflect.Pointer && v.IsNil() {
+		return r, nil
+	}
+	return cloneJSON(r)
+}
+
+// cloneJSON copies the value pointed to by v through JSON. T must be a
+// pointer type.
+func cloneJSON[T any](v T) (T, error) {
+	clone := reflect.New(reflect.TypeFor[T]().Elem()).Interface().(T)
+	if err := remarshal(v, clone); err != nil {
+		var zero T
+		return zero, fmt.Errorf("cloning %T: %w", v, err)
+	}
+	return clone, nil
+}
+
 // emptyResult is returned by methods that have no result, like ping.
 // Those methods cannot return nil, because jsonrpc2 cannot handle nils.
 type emptyResult struct{}
@@ -818,7 +1025,7 @@ type keepaliveSession interface {
 // logger must be non-nil; ping failures (both the tolerated ones and the final
 // one that closes the session) are reported via logger so they are not silently
 // dropped.
-func startKeepalive(session keepaliveSession, interval time.Duration, failureThreshold int, cancelPtr *context.CancelFunc, logger *slog.Logger) {
+func startKeepalive(session keepaliveSession, clk clock, interval time.Duration, failureThreshold int, cancelPtr *context.CancelFunc, logger *slog.Logger) {
 	if failureThreshold < 1 {
 		failureThreshold = 1
 	}
@@ -830,7 +1037,7 @@ func startKeepalive(session keepaliveSession, interval time.Duration, failureThr
 	*cancelPtr = cancel
 
 	go func() {
-		ticker := time.NewTicker(interval)
+		ticker := clk.NewTicker(interval)
 		defer ticker.Stop()
 
 		consecutiveFailures := 0
@@ -838,7 +1045,7 @@ func startKeepalive(session keepaliveSession, interval time.Duration, failureThr
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
+			case <-ticker.C():
 				pingCtx, pingCancel := context.WithTimeout(context.Background(), interval/2)
 				err := session.Ping(pingCtx, nil)
 				pingCancel()
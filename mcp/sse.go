@@ -6,6 +6,7 @@ package mcp
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/rand"
 	"fmt"
@@ -14,6 +15,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/modelcontextprotocol/go-sdk/internal/jsonrpc2"
@@ -65,6 +68,16 @@ type SSEOptions struct {
 	// Only disable this if you understand the security implications.
 	// See: https://modelcontextprotocol.io/specification/2025-11-25/basic/security_best_practices#local-mcp-server-compromise
 	DisableLocalhostProtection bool
+
+	// EnableCompression enables gzip compression of the event stream for
+	// clients that accept it, as indicated by their Accept-Encoding header.
+	// Compression can substantially reduce the size of large results, such as
+	// prompts and resource contents. Each event is flushed as it is written,
+	// so compression does not delay delivery.
+	//
+	// By default, the event stream is not compressed.
+	// [SSEClientTransport] supports compressed streams.
+	EnableCompression bool
 }
 
 // NewSSEHandler returns a new [SSEHandler] that creates and manages MCP
@@ -79,7 +92,8 @@ type SSEOptions struct {
 //
 // The getServer function may return a distinct [Server] for each new
 // request, or reuse an existing server. If it returns nil, the handler
-// will return a 400 Bad Request.
+// responds with 404 Not Found: this is the supported way to reject a
+// connection, for example one whose path names an unknown server.
 func NewSSEHandler(getServer func(request *http.Request) *Server, opts *SSEOptions) *SSEHandler {
 	s := &SSEHandler{
 		getServer: getServer,
@@ -238,6 +252,12 @@ func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// GET requests create a new session, and serve messages over SSE.
+	server := h.getServer(req)
+	if server == nil {
+		// The getServer argument to NewSSEHandler returned nil.
+		http.Error(w, "no server available", http.StatusNotFound)
+		return
+	}
 
 	// TODO: it's not entirely documented whether we should check Accept here.
 	// Let's again be lax and assume the client will accept SSE.
@@ -253,7 +273,26 @@ func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	var gw *gzipResponseWriter
+	if h.opts.EnableCompression {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(req.Header) {
+			w.Header().Set("Content-Encoding", "gzip")
+			gw = newGzipResponseWriter(w)
+			w = gw
+		}
+	}
+
 	transport := &SSEServerTransport{Endpoint: endpoint.RequestURI(), Response: w}
+	if gw != nil {
+		// Terminate the gzip stream when the GET exits, after the session is
+		// closed (deferred functions run in reverse order).
+		defer func() {
+			transport.mu.Lock()
+			defer transport.mu.Unlock()
+			gw.Close()
+		}()
+	}
 
 	// The session is terminated when the request exits.
 	h.mu.Lock()
@@ -265,12 +304,6 @@ func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		h.mu.Unlock()
 	}()
 
-	server := h.getServer(req)
-	if server == nil {
-		// The getServer argument to NewSSEHandler returned nil.
-		http.Error(w, "no server available", http.StatusBadRequest)
-		return
-	}
 	ss, err := server.Connect(req.Context(), transport, nil)
 	if err != nil {
 		http.Error(w, "connection failed", http.StatusInternalServerError)
@@ -287,6 +320,55 @@ func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// acceptsGzip reports whether the Accept-Encoding header in h allows gzip.
+func acceptsGzip(h http.Header) bool {
+	for _, v := range h.Values("Accept-Encoding") {
+		for part := range strings.SplitSeq(v, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			coding = strings.TrimSpace(coding)
+			if coding != "gzip" && coding != "*" {
+				continue
+			}
+			// A q-value of zero means "not acceptable".
+			if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+				if f, err := strconv.ParseFloat(q, 64); err != nil || f == 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// A gzipResponseWriter gzip-compresses the body written to an
+// [http.ResponseWriter]. Flushing it flushes both the compressor and the
+// underlying writer, so that each SSE event is delivered promptly.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+}
+
+func newGzipResponseWriter(w http.ResponseWriter) *gzipResponseWriter {
+	return &gzipResponseWriter{ResponseWriter: w, gz: gzip.NewWriter(w)}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) { return w.gz.Write(p) }
+
+// FlushError is called by [http.ResponseController.Flush].
+func (w *gzipResponseWriter) FlushError() error {
+	if err := w.gz.Flush(); err != nil {
+		return err
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap supports [http.ResponseController].
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Close writes the end of the gzip stream.
+func (w *gzipResponseWriter) Close() error { return w.gz.Close() }
+
 // sseServerConn implements the [Connection] interface for a single [SSEServerTransport].
 // It hides the Connection interface from the SSEServerTransport API.
 type sseServerConn struct {
@@ -360,6 +442,17 @@ type SSEClientTransport struct {
 	// HTTPClient is the client to use for making HTTP requests. If nil,
 	// http.DefaultClient is used.
 	HTTPClient *http.Client
+
+	// UserAgent is the User-Agent header sent with each HTTP request.
+	// If empty, a default is used, as for [StreamableClientTransport.UserAgent].
+	UserAgent string
+
+	// MaxRetries is the maximum number of times to retry a request to which
+	// the server responds with 429 Too Many Requests or 503 Service
+	// Unavailable and a Retry-After header. Each retry waits for the
+	// requested delay, up to 30 seconds. Other failures are not retried.
+	// It defaults to 5. To disable retries, use a negative number.
+	MaxRetries int
 }
 
 // Connect connects through the client endpoint.
@@ -376,8 +469,18 @@ func (c *SSEClientTransport) Connect(ctx context.Context) (Connection, error) {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
+	maxRetries := c.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 5
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+	ua := userAgent(ctx, c.UserAgent)
 	req.Header.Set("Accept", "text/event-stream")
-	resp, err := httpClient.Do(req)
+	req.Header.Set("User-Agent", ua)
+	resp, err := doRetryLater(ctx, realClock{}, maxRetries, func() (*http.Response, error) {
+		return httpClient.Do(req)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -390,9 +493,22 @@ func (c *SSEClientTransport) Connect(ctx context.Context) (Connection, error) {
 		return nil, fmt.Errorf("failed to connect: %s", http.StatusText(resp.StatusCode))
 	}
 
+	// The HTTP client transparently decompresses gzip responses when it
+	// negotiated the encoding itself; handle the remaining case, where the
+	// Accept-Encoding header was set by a custom RoundTripper.
+	var body io.Reader = resp.Body
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("reading compressed event stream: %v", err)
+		}
+		body = gz
+	}
+
 	msgEndpoint, err := func() (*url.URL, error) {
 		var evt Event
-		for evt, err = range scanEvents(resp.Body) {
+		for evt, err = range scanEvents(body) {
 			break
 		}
 		if err != nil {
@@ -412,6 +528,8 @@ func (c *SSEClientTransport) Connect(ctx context.Context) (Connection, error) {
 	// From here on, the stream takes ownership of resp.Body.
 	s := &sseClientConn{
 		client:      httpClient,
+		userAgent:   ua,
+		maxRetries:  maxRetries,
 		msgEndpoint: msgEndpoint,
 		incoming:    make(chan []byte, 100),
 		body:        resp.Body,
@@ -421,7 +539,7 @@ func (c *SSEClientTransport) Connect(ctx context.Context) (Connection, error) {
 	go func() {
 		defer s.Close() // close the transport when the GET exits
 
-		for evt, err := range scanEvents(resp.Body) {
+		for evt, err := range scanEvents(body) {
 			if err != nil {
 				return
 			}
@@ -443,6 +561,8 @@ func (c *SSEClientTransport) Connect(ctx context.Context) (Connection, error) {
 //   - Close terminates the GET request.
 type sseClientConn struct {
 	client      *http.Client // HTTP client to use for requests
+	userAgent   string       // User-Agent header for requests
+	maxRetries  int          // from [SSEClientTransport.MaxRetries]
 	msgEndpoint *url.URL     // session endpoint for POSTs
 	incoming    chan []byte  // queue of incoming messages
 
@@ -490,12 +610,15 @@ func (c *sseClientConn) Write(ctx context.Context, msg jsonrpc.Message) error {
 	if c.isDone() {
 		return io.EOF
 	}
-	req, err := http.NewRequestWithContext(ctx, "POST", c.msgEndpoint.String(), bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.client.Do(req)
+	resp, err := doRetryLater(ctx, realClock{}, c.maxRetries, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.msgEndpoint.String(), bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		return c.client.Do(req)
+	})
 	if err != nil {
 		return err
 	}
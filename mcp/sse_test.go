@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 
@@ -319,3 +320,158 @@ func TestSSELocalhostProtection(t *testing.T) {
 		})
 	}
 }
+
+func TestSSENilServer(t *testing.T) {
+	handler := NewSSEHandler(func(*http.Request) *Server { return nil }, nil)
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusNotFound; got != want {
+		t.Errorf("status code: got %d, want %d", got, want)
+	}
+
+	// A client connection fails cleanly.
+	client := NewClient(testImpl, nil)
+	if _, err := client.Connect(context.Background(), &SSEClientTransport{Endpoint: httpServer.URL}, nil); err == nil {
+		t.Error("Connect succeeded unexpectedly")
+	}
+}
+
+// gzipRecorder is an http.RoundTripper that records whether event streams
+// were compressed. If setAcceptEncoding is set, it requests gzip itself,
+// disabling transparent decompression by the HTTP client.
+type gzipRecorder struct {
+	setAcceptEncoding bool
+	compressed        atomic.Bool
+}
+
+func (r *gzipRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.setAcceptEncoding {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err == nil && req.Method == http.MethodGet && (resp.Uncompressed || resp.Header.Get("Content-Encoding") == "gzip") {
+		r.compressed.Store(true)
+	}
+	return resp, err
+}
+
+func TestSSECompression(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	large := strings.Repeat("a large result ", 10000)
+	AddTool(server, &Tool{Name: "large"}, func(context.Context, *CallToolRequest, any) (*CallToolResult, any, error) {
+		return &CallToolResult{Content: []Content{&TextContent{Text: large}}}, nil, nil
+	})
+	for _, test := range []struct {
+		name              string
+		enable            bool
+		setAcceptEncoding bool
+	}{
+		{"disabled", false, false},
+		{"transparent", true, false},
+		{"explicit", true, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			handler := NewSSEHandler(func(*http.Request) *Server { return server }, &SSEOptions{EnableCompression: test.enable})
+			httpServer := httptest.NewServer(mustNotPanic(t, handler))
+			defer httpServer.Close()
+
+			rec := &gzipRecorder{setAcceptEncoding: test.setAcceptEncoding}
+			transport := &SSEClientTransport{Endpoint: httpServer.URL, HTTPClient: &http.Client{Transport: rec}}
+			cs, err := NewClient(testImpl, nil).Connect(ctx, transport, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cs.Close()
+			for range 2 {
+				res, err := cs.CallTool(ctx, &CallToolParams{Name: "large"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := res.Content[0].(*TextContent).Text; got != large {
+					t.Errorf("got %d bytes of text, want %d", len(got), len(large))
+				}
+			}
+			if got := rec.compressed.Load(); got != test.enable {
+				t.Errorf("event stream compressed: got %t, want %t", got, test.enable)
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"br, *", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0, br", false},
+		{"identity", false},
+	} {
+		h := http.Header{}
+		if test.header != "" {
+			h.Set("Accept-Encoding", test.header)
+		}
+		if got := acceptsGzip(h); got != test.want {
+			t.Errorf("acceptsGzip(%q) = %t, want %t", test.header, got, test.want)
+		}
+	}
+}
+
+func TestSSEClientRetryAfter(t *testing.T) {
+	// The client retries the GET and POST requests that the server asks it to
+	// retry later.
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	handler := NewSSEHandler(func(*http.Request) *Server { return server }, nil)
+	var gets, listPosts atomic.Int32
+	httpServer := httptest.NewServer(mustNotPanic(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			if gets.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+				return
+			}
+		case http.MethodPost:
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(methodListTools)) && listPosts.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})))
+	defer httpServer.Close()
+
+	cs, err := NewClient(testImpl, nil).Connect(ctx, &SSEClientTransport{Endpoint: httpServer.URL}, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if _, err := cs.ListTools(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := gets.Load(); got != 2 {
+		t.Errorf("got %d GET requests, want 2", got)
+	}
+	if got := listPosts.Load(); got != 2 {
+		t.Errorf("got %d tools/list POST requests, want 2", got)
+	}
+}
@@ -43,15 +43,24 @@ import (
 // A StreamableHTTPHandler is an http.Handler that serves streamable MCP
 // sessions, as defined by the [MCP spec].
 //
+// Server-sent event streams require an [http.ResponseWriter] that can flush
+// (see [http.ResponseController]). If the writer cannot flush, GET requests
+// fail with 500 Internal Server Error, and POST requests are answered with a
+// single application/json response instead of an event stream.
+//
 // [MCP spec]: https://modelcontextprotocol.io/2025/03/26/streamable-http-transport.html
 type StreamableHTTPHandler struct {
 	getServer func(*http.Request) *Server
 	opts      StreamableHTTPOptions
 
 	onTransportDeletion func(sessionID string) // for testing
+	clock               clock                  // source of time for session timeouts
 
 	mu       sync.Mutex
 	sessions map[string]*sessionInfo // keyed by session ID
+	// connecting counts sessions being created, which are not yet in
+	// sessions, for [StreamableHTTPOptions.MaxSessions].
+	connecting int
 }
 
 type sessionInfo struct {
@@ -66,7 +75,7 @@ type sessionInfo struct {
 	timeout time.Duration
 	timerMu sync.Mutex
 	refs    int // reference count
-	timer   *time.Timer
+	timer   timer
 }
 
 // startPOST signals that a POST request for this session is starting (which
@@ -153,6 +162,28 @@ type StreamableHTTPOptions struct {
 	// [§2.1.5]: https://modelcontextprotocol.io/specification/2025-06-18/basic/transports#sending-messages-to-the-server
 	JSONResponse bool
 
+	// ErrorHTTPStatus maps JSON-RPC error codes to HTTP status codes for
+	// error responses sent as application/json.
+	//
+	// By default, a JSON-RPC error response to a POST request is sent with
+	// HTTP status 200 OK, as the spec requires. Some deployments want errors
+	// to be visible to HTTP infrastructure such as load balancers, metrics and
+	// access logs; for them, an entry such as
+	// {jsonrpc.CodeInvalidParams: http.StatusBadRequest} causes the response
+	// to be sent with that status instead.
+	//
+	// The mapping applies only when the error is the sole response in the HTTP
+	// reply: that is, when JSONResponse is set and the POST carried a single
+	// request. Responses on an SSE stream or within a batch are unaffected,
+	// since the status is committed before they are written. Status codes
+	// mandated by the protocol version in use take precedence.
+	//
+	// Clients that follow the spec treat non-2xx responses as transport
+	// failures, so they may not surface the JSON-RPC error to the caller. Use
+	// this only when all clients are known to read the body regardless of
+	// status.
+	ErrorHTTPStatus map[int64]int
+
 	// Logger specifies the logger to use.
 	// If nil, do not log.
 	Logger *slog.Logger
@@ -163,6 +194,24 @@ type StreamableHTTPOptions struct {
 	// upon stream resumption.
 	EventStore EventStore
 
+	// EventIDCodec, if set, customizes the IDs of SSE events, which clients
+	// send back in the Last-Event-ID header to resume a stream. It only has
+	// an effect if EventStore is also set.
+	//
+	// If nil, event IDs have the form "<streamID>_<index>".
+	EventIDCodec EventIDCodec
+
+	// SSEHeartbeatInterval, if positive, causes the server to write an SSE
+	// comment line (": heartbeat") to the standalone SSE stream opened by a
+	// GET request whenever nothing else has been written to it for this
+	// duration.
+	//
+	// Heartbeats keep idle streams from being closed by proxies and load
+	// balancers, many of which drop connections that are idle for 30 to 60
+	// seconds. Unlike keepalive pings ([ServerOptions.KeepAlive]), they
+	// operate purely at the SSE framing layer and are ignored by clients.
+	SSEHeartbeatInterval time.Duration
+
 	// SessionTimeout configures a timeout for idle sessions.
 	//
 	// When sessions receive no new HTTP requests from the client for this
@@ -171,6 +220,33 @@ type StreamableHTTPOptions struct {
 	// If SessionTimeout is the zero value, idle sessions are never closed.
 	SessionTimeout time.Duration
 
+	// MaxSessions, if positive, limits the number of concurrent sessions.
+	//
+	// When the limit is reached, requests that would create a new session are
+	// rejected with 503 Service Unavailable, until an existing session is
+	// closed: by the client, with a DELETE request, or by the server, for
+	// example after SessionTimeout. Together with SessionTimeout, this bounds
+	// the state that the handler holds for clients.
+	//
+	// Sessions without a session ID, which last for a single request, are not
+	// counted. MaxSessions has no effect for stateless servers.
+	MaxSessions int
+
+	// SessionContext, if non-nil, returns the context for a new session, given
+	// the context and HTTP request that create it. The returned context must
+	// be derived from ctx.
+	//
+	// The values of the returned context, such as a tenant ID read from a
+	// header or an authenticated user, are visible to the getServer function
+	// passed to [NewStreamableHTTPHandler], and to every handler invocation in
+	// the session. (Cancellation of the HTTP request does not affect the
+	// session.) For stateless servers, whose sessions last for a single
+	// request, SessionContext is called for every request.
+	//
+	// Values that vary by request within a session, such as the request
+	// headers, are available in [RequestExtra].
+	SessionContext func(ctx context.Context, req *http.Request) context.Context
+
 	// DisableLocalhostProtection disables automatic DNS rebinding protection.
 	// By default, requests arriving via a localhost address (127.0.0.1, [::1])
 	// that have a non-localhost Host header are rejected with 403 Forbidden.
@@ -200,10 +276,13 @@ type StreamableHTTPOptions struct {
 //
 // The getServer function is used to create or look up servers for new
 // sessions. It is OK for getServer to return the same server multiple times.
-// If getServer returns nil, a 400 Bad Request will be served.
+// If getServer returns nil, a 404 Not Found will be served: this is the
+// supported way to reject a connection, for example one whose path names an
+// unknown server.
 func NewStreamableHTTPHandler(getServer func(*http.Request) *Server, opts *StreamableHTTPOptions) *StreamableHTTPHandler {
 	h := &StreamableHTTPHandler{
 		getServer: getServer,
+		clock:     realClock{},
 		sessions:  make(map[string]*sessionInfo),
 	}
 	if opts != nil {
@@ -219,6 +298,57 @@ func NewStreamableHTTPHandler(getServer func(*http.Request) *Server, opts *Strea
 	return h
 }
 
+// InfoHandler returns an http.Handler that reports, in response to a GET
+// request, the identity and capabilities of the server that h would serve for
+// the request. The response is the JSON encoding of a [DiscoverResult],
+// holding the server's [Implementation], capabilities and instructions, and
+// the protocol versions that h supports.
+//
+// The handler does not create a session or perform an MCP initialize, so
+// discovery tools and dashboards can inspect a server cheaply. Mount it at a
+// path separate from the MCP endpoint, for example:
+//
+//	mux.Handle("/mcp", handler)
+//	mux.Handle("/mcp/info", handler.InfoHandler())
+//
+// The info handler applies the same DNS rebinding and cross-origin
+// protections as h, and calls getServer with a request carrying the context
+// from [StreamableHTTPOptions.SessionContext]. Like the MCP endpoint, it
+// reveals information about the server, so protect it in the same way, for
+// example with the same authentication middleware.
+func (h *StreamableHTTPHandler) InfoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !h.protect(w, req) {
+			return
+		}
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		req = h.withSessionContext(req)
+		server := h.getServer(req)
+		if server == nil {
+			http.Error(w, "no server available", http.StatusNotFound)
+			return
+		}
+		t := &StreamableServerTransport{Stateless: h.opts.Stateless}
+		res := &DiscoverResult{
+			SupportedVersions: filterSupportedVersions(t),
+			Capabilities:      server.capabilities(),
+			ServerInfo:        server.impl,
+			Instructions:      server.opts.Instructions,
+		}
+		data, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("marshaling server info: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
+
 // closeAll closes all ongoing sessions, for tests.
 //
 // TODO(rfindley): investigate the best API for callers to configure their
@@ -290,22 +420,8 @@ func writeJSONRPCError(w http.ResponseWriter, status int, id jsonrpc.ID, jerr *j
 }
 
 func (h *StreamableHTTPHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	// DNS rebinding protection: auto-enabled for localhost servers.
-	// See: https://modelcontextprotocol.io/specification/2025-11-25/basic/security_best_practices#local-mcp-server-compromise
-	if !h.opts.DisableLocalhostProtection && disablelocalhostprotection != "1" {
-		if localAddr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && localAddr != nil {
-			if util.IsLoopback(localAddr.String()) && !util.IsLoopback(req.Host) {
-				http.Error(w, fmt.Sprintf("Forbidden: invalid Host header %q", req.Host), http.StatusForbidden)
-				return
-			}
-		}
-	}
-
-	if h.opts.CrossOriginProtection != nil {
-		if err := h.opts.CrossOriginProtection.Check(req); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
-			return
-		}
+	if !h.protect(w, req) {
+		return
 	}
 
 	// [§2.7] of the spec (2025-06-18): validate the MCP-Protocol-Version
@@ -327,6 +443,30 @@ func (h *StreamableHTTPHandler) ServeHTTP(w http.ResponseWriter, req *http.Reque
 	}
 }
 
+// protect applies the handler's DNS rebinding and cross-origin protections
+// to req. If req is forbidden, it writes an error response to w and
+// returns false.
+func (h *StreamableHTTPHandler) protect(w http.ResponseWriter, req *http.Request) bool {
+	// DNS rebinding protection: auto-enabled for localhost servers.
+	// See: https://modelcontextprotocol.io/specification/2025-11-25/basic/security_best_practices#local-mcp-server-compromise
+	if !h.opts.DisableLocalhostProtection && disablelocalhostprotection != "1" {
+		if localAddr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && localAddr != nil {
+			if util.IsLoopback(localAddr.String()) && !util.IsLoopback(req.Host) {
+				http.Error(w, fmt.Sprintf("Forbidden: invalid Host header %q", req.Host), http.StatusForbidden)
+				return false
+			}
+		}
+	}
+
+	if h.opts.CrossOriginProtection != nil {
+		if err := h.opts.CrossOriginProtection.Check(req); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
 // serveStateless handles requests for stateless servers.
 // Stateless servers only support POST. Each request creates a temporary
 // session that is closed when the request completes.
@@ -360,9 +500,10 @@ func (h *StreamableHTTPHandler) serveStateless(w http.ResponseWriter, req *http.
 		return
 	}
 
+	req = h.withSessionContext(req)
 	server := h.getServer(req)
 	if server == nil {
-		http.Error(w, "no server available", http.StatusBadRequest)
+		http.Error(w, "no server available", http.StatusNotFound)
 		return
 	}
 
@@ -384,7 +525,9 @@ func (h *StreamableHTTPHandler) serveStateless(w http.ResponseWriter, req *http.
 		SessionID:                   sessionID,
 		Stateless:                   true,
 		EventStore:                  h.opts.EventStore,
+		EventIDCodec:                h.opts.EventIDCodec,
 		jsonResponse:                h.opts.JSONResponse,
+		errorHTTPStatus:             h.opts.ErrorHTTPStatus,
 		logger:                      h.opts.Logger,
 		shouldPropagateCancellation: info.isSubscriptionsListen && info.usesNewProtocol,
 	}
@@ -481,6 +624,16 @@ func (h *StreamableHTTPHandler) ephemeralConnectOpts(req *http.Request) (*epheme
 	}, nil
 }
 
+// withSessionContext returns req with the session context of
+// [StreamableHTTPOptions.SessionContext], for a request that creates a
+// session.
+func (h *StreamableHTTPHandler) withSessionContext(req *http.Request) *http.Request {
+	if h.opts.SessionContext == nil {
+		return req
+	}
+	return req.WithContext(h.opts.SessionContext(req.Context(), req))
+}
+
 func connectStreamable(ctx context.Context, server *Server, transport *StreamableServerTransport, opts *ServerSessionOptions) (*ServerSession, error) {
 	s, err := server.Connect(ctx, transport, opts)
 	if err != nil {
@@ -602,19 +755,24 @@ func (h *StreamableHTTPHandler) serveStatefulPOST(w http.ResponseWriter, req *ht
 	}
 
 	// No session ID: create a new session.
+	req = h.withSessionContext(req)
 	server := h.getServer(req)
 	if server == nil {
-		http.Error(w, "no server available", http.StatusBadRequest)
+		http.Error(w, "no server available", http.StatusNotFound)
 		return
 	}
 	sessionID = server.opts.GetSessionID()
 
 	transport := &StreamableServerTransport{
-		SessionID:    sessionID,
-		Stateless:    false,
-		EventStore:   h.opts.EventStore,
-		jsonResponse: h.opts.JSONResponse,
-		logger:       h.opts.Logger,
+		SessionID:         sessionID,
+		Stateless:         false,
+		EventStore:        h.opts.EventStore,
+		EventIDCodec:      h.opts.EventIDCodec,
+		jsonResponse:      h.opts.JSONResponse,
+		errorHTTPStatus:   h.opts.ErrorHTTPStatus,
+		heartbeatInterval: h.opts.SSEHeartbeatInterval,
+		clock:             h.clock,
+		logger:            h.opts.Logger,
 	}
 
 	// Sessions without a session ID (GetSessionID returned "") are ephemeral:
@@ -640,6 +798,19 @@ func (h *StreamableHTTPHandler) serveStatefulPOST(w http.ResponseWriter, req *ht
 		return
 	}
 
+	if !h.reserveSession() {
+		http.Error(w, "too many sessions", http.StatusServiceUnavailable)
+		return
+	}
+	reserved := true
+	defer func() {
+		if reserved {
+			h.mu.Lock()
+			h.connecting--
+			h.mu.Unlock()
+		}
+	}()
+
 	connectOpts := &ServerSessionOptions{
 		onClose: func() {
 			h.mu.Lock()
@@ -677,12 +848,14 @@ func (h *StreamableHTTPHandler) serveStatefulPOST(w http.ResponseWriter, req *ht
 
 	if h.opts.SessionTimeout > 0 {
 		sessInfo.timeout = h.opts.SessionTimeout
-		sessInfo.timer = time.AfterFunc(sessInfo.timeout, func() {
+		sessInfo.timer = h.clock.AfterFunc(sessInfo.timeout, func() {
 			sessInfo.session.Close()
 		})
 	}
 	h.mu.Lock()
 	h.sessions[transport.SessionID] = sessInfo
+	h.connecting--
+	reserved = false
 	h.mu.Unlock()
 	defer func() {
 		// If initialization failed, clean up the session (#578).
@@ -696,6 +869,18 @@ func (h *StreamableHTTPHandler) serveStatefulPOST(w http.ResponseWriter, req *ht
 	sessInfo.transport.ServeHTTP(w, req)
 }
 
+// reserveSession reserves a slot for a new session, reporting whether one is
+// available under [StreamableHTTPOptions.MaxSessions].
+func (h *StreamableHTTPHandler) reserveSession() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.opts.MaxSessions > 0 && len(h.sessions)+h.connecting >= h.opts.MaxSessions {
+		return false
+	}
+	h.connecting++
+	return true
+}
+
 func streamableAccepts(values []string) (jsonOK, streamOK bool) {
 	for _, value := range values {
 		for _, raw := range strings.Split(value, ",") {
@@ -739,10 +924,35 @@ func baseMediaType(value string) string {
 //     appropriate HTTP response.
 //   - Requests or notifications made with a context.Context value derived from
 //     an incoming request handler, are routed to the HTTP response
-//     corresponding to that request, unless it has already terminated, in
-//     which case they are routed to the standalone SSE stream.
-//   - Requests or notifications made with a detached context.Context value are
-//     routed to the standalone SSE stream.
+//     corresponding to that request. If that request has already been
+//     responded to, they are rejected. (With JSONResponse, they are instead
+//     routed to the standalone SSE stream, since an application/json response
+//     carries only the response itself.)
+//   - Requests or notifications made with a detached context.Context value, or
+//     one returned by [WithStandaloneStream], are routed to the standalone SSE
+//     stream.
+//
+// A request routed to the standalone SSE stream, such as a sampling request
+// made by a tool handler when JSONResponse is set, reaches the client only
+// once it opens that stream. Without an [EventStore], such a request fails
+// immediately if the stream is not open, rather than waiting for a response
+// that can never arrive. With an EventStore, it is stored until the client
+// opens the stream, so the caller should bound the wait with its context.
+//
+// Notifications routed to the standalone SSE stream before the client opens
+// it, such as log messages sent right after initialization, are not dropped:
+// they are delivered once the client makes its GET request, either by
+// replaying them from the EventStore or, without one, from an in-memory
+// buffer bounded in both message count and size. Once that buffer is full,
+// further notifications fail with an error.
+//
+// A client may hold several GET streams open at once: at most one standalone
+// SSE stream (a GET without Last-Event-ID), plus any number of streams being
+// resumed with Last-Event-ID, each of which carries only the messages related
+// to the POST request that created it. A second standalone GET, or a second
+// GET resuming a stream that is already connected, is rejected with 409
+// Conflict. Since a message is never sent on more than one stream, the rules
+// above fully determine where each message is delivered.
 type StreamableServerTransport struct {
 	// SessionID is the ID of this session.
 	//
@@ -766,6 +976,11 @@ type StreamableServerTransport struct {
 	// upon stream resumption.
 	EventStore EventStore
 
+	// EventIDCodec customizes the format of SSE event IDs.
+	//
+	// See also [StreamableHTTPOptions.EventIDCodec].
+	EventIDCodec EventIDCodec
+
 	// jsonResponse, if set, tells the server to prefer to respond to requests
 	// using application/json responses rather than text/event-stream.
 	//
@@ -779,6 +994,17 @@ type StreamableServerTransport struct {
 	// to write their own streamable HTTP handler.
 	jsonResponse bool
 
+	// errorHTTPStatus maps JSON-RPC error codes to HTTP statuses for
+	// application/json responses. See [StreamableHTTPOptions.ErrorHTTPStatus].
+	errorHTTPStatus map[int64]int
+
+	// heartbeatInterval is the interval for SSE heartbeats on the standalone
+	// SSE stream. See [StreamableHTTPOptions.SSEHeartbeatInterval].
+	heartbeatInterval time.Duration
+
+	// clock is used for heartbeats. If nil, the real clock is used.
+	clock clock
+
 	// optional logger provided through the [StreamableHTTPOptions.Logger].
 	//
 	// TODO(rfindley): logger should be exported, since we want to allow users
@@ -798,11 +1024,23 @@ func (t *StreamableServerTransport) Connect(ctx context.Context) (Connection, er
 	if t.connection != nil {
 		return nil, fmt.Errorf("transport already connected")
 	}
+	eventIDs := t.EventIDCodec
+	if eventIDs == nil {
+		eventIDs = defaultEventIDCodec{}
+	}
+	clk := t.clock
+	if clk == nil {
+		clk = realClock{}
+	}
 	t.connection = &streamableServerConn{
 		sessionID:                   t.SessionID,
 		stateless:                   t.Stateless,
 		eventStore:                  t.EventStore,
+		eventIDs:                    eventIDs,
+		heartbeatInterval:           t.heartbeatInterval,
+		clock:                       clk,
 		jsonResponse:                t.jsonResponse,
+		errorHTTPStatus:             t.errorHTTPStatus,
 		logger:                      ensureLogger(t.logger), // see #556: must be non-nil
 		shouldPropagateCancellation: t.shouldPropagateCancellation,
 		incoming:                    make(chan jsonrpc.Message, 10),
@@ -837,6 +1075,12 @@ type streamableServerConn struct {
 	stateless    bool
 	jsonResponse bool
 	eventStore   EventStore
+	eventIDs     EventIDCodec
+
+	errorHTTPStatus map[int64]int
+
+	heartbeatInterval time.Duration
+	clock             clock
 
 	// shouldPropagateCancellation is true when the underlying HTTP request's
 	// lifetime IS the connection's cancellation signal (e.g., a stateless
@@ -952,8 +1196,25 @@ type stream struct {
 	// the duration of the subscription, and act as the target for
 	// out-of-band notifications routed through this connection.
 	isListen bool
+
+	// wroteSinceHeartbeat records whether an event was written since the last
+	// heartbeat tick, in which case the stream is not idle.
+	wroteSinceHeartbeat bool
+
+	// pending holds messages written to the standalone SSE stream while no
+	// GET request was connected to it, if there is no event store. They are
+	// delivered when a GET request claims the stream. pendingBytes is their
+	// total size.
+	pending      [][]byte
+	pendingBytes int
 }
 
+// maxPendingMessages and maxPendingBytes bound [stream.pending].
+const (
+	maxPendingMessages = 1000
+	maxPendingBytes    = 4 << 20
+)
+
 // close sends a 'close' event to the client (if protocolVersion >= 2025-11-25
 // and reconnectAfter > 0) and closes the done channel.
 //
@@ -1017,6 +1278,37 @@ func extractErrorStatus(ctx context.Context, msg jsonrpc.Message) int {
 	return 0
 }
 
+// configuredErrorStatus reports the HTTP status that statuses maps the error
+// code of msg to, or 0 if msg is not an error response or its code is not
+// mapped.
+func configuredErrorStatus(statuses map[int64]int, msg jsonrpc.Message) int {
+	if len(statuses) == 0 {
+		return 0
+	}
+	resp, ok := msg.(*jsonrpc.Response)
+	if !ok || resp.Error == nil {
+		return 0
+	}
+	var jerr *jsonrpc.Error
+	if !errors.As(resp.Error, &jerr) {
+		return 0
+	}
+	return statuses[jerr.Code]
+}
+
+// soleJSONResponseLocked reports whether a response to responseTo would be
+// the only message in an application/json reply, so that its HTTP status has
+// not yet been committed.
+//
+// s.mu must be held when calling this method.
+func (s *stream) soleJSONResponseLocked(responseTo jsonrpc.ID) bool {
+	if !responseTo.IsValid() || s.pendingJSONMessages == nil || len(s.pendingJSONMessages) > 0 {
+		return false
+	}
+	_, ok := s.requests[responseTo]
+	return ok && len(s.requests) == 1 && s.id != ""
+}
+
 // deliverLocked writes data to the stream (for SSE) or stores it in
 // pendingJSONMessages (for JSON mode). The eventID is used for SSE event ID;
 // pass "" to omit.
@@ -1085,6 +1377,7 @@ func (s *stream) deliverLocked(data []byte, eventID string, responseTo jsonrpc.I
 	} else {
 		// SSE mode: write event to response writer.
 		s.lastIdx++
+		s.wroteSinceHeartbeat = true
 		if _, err := writeEvent(s.w, Event{Name: "message", Data: data, ID: eventID}); err != nil {
 			return done, err
 		}
@@ -1117,15 +1410,44 @@ func (c *streamableServerConn) newStream(ctx context.Context, requests map[jsonr
 	}, nil
 }
 
+// abandonCalls stops tracking the calls among msgs, which belong to s but
+// were never published to the session. If s has no other outstanding
+// requests, it is forgotten.
+func (c *streamableServerConn) abandonCalls(s *stream, msgs []jsonrpc.Message) {
+	var ids []jsonrpc.ID
+	for _, msg := range msgs {
+		if jreq, ok := msg.(*jsonrpc.Request); ok && jreq.IsCall() {
+			ids = append(ids, jreq.ID)
+		}
+	}
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.requests, id)
+	}
+	empty := len(s.requests) == 0
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		delete(c.requestStreams, id)
+	}
+	if empty {
+		delete(c.streams, s.id)
+	}
+}
+
 // We track the incoming request ID inside the handler context using
 // idContextValue, so that notifications and server->client calls that occur in
 // the course of handling incoming requests are correlated with the incoming
 // request that caused them, and can be dispatched as server-sent events to the
 // correct HTTP request.
 //
-// Currently, this is implemented in [ServerSession.handle]. This is not ideal,
-// because it means that a user of the MCP package couldn't implement the
-// streamable transport, as they'd lack this privileged access.
+// Currently, this is implemented in [ServerSession.handle] (and, for
+// symmetry, in [ClientSession.handle], though no client transport uses it
+// yet). This is not ideal, because it means that a user of the MCP package
+// couldn't implement the streamable transport, as they'd lack this privileged
+// access.
 //
 // If we ever wanted to expose this mechanism, we have a few options:
 //  1. Make ServerSession an interface, and provide an implementation of
@@ -1140,6 +1462,30 @@ func (c *streamableServerConn) newStream(ctx context.Context, requests map[jsonr
 // ID, we avoid having to make this API decision.
 type idContextKey struct{}
 
+// standaloneStreamContextKey marks a context created by
+// [WithStandaloneStream].
+type standaloneStreamContextKey struct{}
+
+// WithStandaloneStream returns a copy of ctx that routes the server-to-client
+// requests and notifications sent with it over a [StreamableServerTransport]
+// to the session's standalone SSE stream, opened by the client's GET request,
+// rather than to the response stream of the client request being handled.
+//
+// By default, a request that the server makes while handling a client
+// request, such as a sampling request made by a tool, is sent on the stream of
+// the HTTP POST carrying the client request. A client that cannot keep that
+// stream open for the whole round trip would never see the server's request,
+// and the tool call would deadlock. Sending such requests with
+// WithStandaloneStream avoids this:
+//
+//	res, err := req.Session.CreateMessage(mcp.WithStandaloneStream(ctx), params)
+//
+// The client must have opened the standalone stream for the messages to be
+// delivered. Other transports ignore this routing.
+func WithStandaloneStream(ctx context.Context) context.Context {
+	return context.WithValue(ctx, standaloneStreamContextKey{}, true)
+}
+
 // protocolVersionContextKey stores the protocol version extracted from the
 // MCP-Protocol-Version HTTP header for use by lower layers.
 type protocolVersionContextKey struct{}
@@ -1176,15 +1522,18 @@ func (t *StreamableServerTransport) ServeHTTP(w http.ResponseWriter, req *http.R
 //
 // It returns an HTTP status code and error message.
 func (c *streamableServerConn) serveGET(w http.ResponseWriter, req *http.Request) {
-	// streamID "" corresponds to the default GET request.
+	// streamID "" corresponds to the default GET request: the standalone SSE
+	// stream, which carries messages not related to any request.
 	streamID := ""
 	// By default, we haven't seen a last index. Since indices start at 0, we represent
 	// that by -1. This is incremented just before each event is written.
 	lastIdx := -1
-	if len(req.Header.Values(lastEventIDHeader)) > 0 {
-		eid := req.Header.Get(lastEventIDHeader)
+	// An empty Last-Event-ID means the client has not seen any events (as with
+	// an EventSource that has not yet received an event ID), so it opens the
+	// standalone stream rather than resuming one.
+	if eid := req.Header.Get(lastEventIDHeader); eid != "" {
 		var ok bool
-		streamID, lastIdx, ok = parseEventID(eid)
+		streamID, lastIdx, ok = c.eventIDs.ParseEventID(eid)
 		if !ok {
 			http.Error(w, fmt.Sprintf("malformed Last-Event-ID %q", eid), http.StatusBadRequest)
 			return
@@ -1195,6 +1544,12 @@ func (c *streamableServerConn) serveGET(w http.ResponseWriter, req *http.Request
 		}
 	}
 
+	if !canFlush(w) {
+		c.logger.Warn("response writer cannot flush; rejecting GET stream")
+		http.Error(w, "streaming unsupported: response writer cannot flush", http.StatusInternalServerError)
+		return
+	}
+
 	ctx := req.Context()
 
 	protocolVersion := protocolVersionFromContext(ctx)
@@ -1207,7 +1562,28 @@ func (c *streamableServerConn) serveGET(w http.ResponseWriter, req *http.Request
 		return
 	}
 	defer stream.release()
-	c.hangResponse(ctx, done)
+	if c.heartbeatInterval > 0 {
+		c.hangResponseWithHeartbeat(ctx, stream, done)
+	} else {
+		c.hangResponse(ctx, done)
+	}
+}
+
+// canFlush reports whether w supports flushing, either directly or through
+// the Unwrap chain used by [http.ResponseController].
+func canFlush(w http.ResponseWriter) bool {
+	for {
+		switch t := w.(type) {
+		case http.Flusher:
+			return true
+		case interface{ FlushError() error }:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return false
+		}
+	}
 }
 
 // hangResponse blocks the HTTP response until one of three conditions is met:
@@ -1225,6 +1601,46 @@ func (c *streamableServerConn) hangResponse(ctx context.Context, done <-chan str
 	}
 }
 
+// hangResponseWithHeartbeat is like [streamableServerConn.hangResponse], but
+// also writes a heartbeat to s whenever it has been idle for the heartbeat
+// interval.
+func (c *streamableServerConn) hangResponseWithHeartbeat(ctx context.Context, s *stream, done <-chan struct{}) {
+	ticker := c.clock.NewTicker(c.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-c.done:
+			return
+		case <-ticker.C():
+			s.heartbeat()
+		}
+	}
+}
+
+// heartbeat writes an SSE comment to the stream, unless it is not connected
+// or an event was written since the previous call.
+func (s *stream) heartbeat() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil || s.pendingJSONMessages != nil {
+		return
+	}
+	if s.wroteSinceHeartbeat {
+		s.wroteSinceHeartbeat = false
+		return
+	}
+	if _, err := io.WriteString(s.w, ": heartbeat\n\n"); err != nil {
+		s.logger.Warn(fmt.Sprintf("Writing heartbeat: %v", err))
+		return
+	}
+	// Ignore returned error as flushing is best-effort.
+	_ = http.NewResponseController(s.w).Flush()
+}
+
 // acquireStream replays all events since lastIdx, and acquires the ongoing
 // stream, if any. If non-nil, the resulting stream will be registered for
 // receiving new messages, and the stream's done channel will be closed when
@@ -1275,7 +1691,11 @@ func (c *streamableServerConn) acquireStream(ctx context.Context, w http.Respons
 
 	// Check that this stream wasn't claimed by another request.
 	if !tempStream && s.w != nil {
-		http.Error(w, "stream ID conflicts with ongoing stream", http.StatusConflict)
+		if streamID == "" {
+			http.Error(w, "standalone SSE stream already open: a session has at most one GET stream without Last-Event-ID", http.StatusConflict)
+		} else {
+			http.Error(w, "stream ID conflicts with ongoing stream", http.StatusConflict)
+		}
 		return nil, nil
 	}
 
@@ -1305,6 +1725,10 @@ func (c *streamableServerConn) acquireStream(ctx context.Context, w http.Respons
 			}
 		}
 	}
+	// Deliver messages held while the standalone stream was not connected.
+	toReplay = append(toReplay, s.pending...)
+	s.pending = nil
+	s.pendingBytes = 0
 
 	w.Header().Set("Cache-Control", "no-cache, no-transform")
 	w.Header().Set("Content-Type", "text/event-stream") // Accept checked in [StreamableHTTPHandler]
@@ -1339,7 +1763,7 @@ func (c *streamableServerConn) acquireStream(ctx context.Context, w http.Respons
 		lastIdx++
 		e := Event{Name: "message", Data: data}
 		if c.eventStore != nil {
-			e.ID = formatEventID(s.id, lastIdx)
+			e.ID = c.eventIDs.FormatEventID(s.id, lastIdx)
 		}
 		if _, err := writeEvent(w, e); err != nil {
 			return nil, nil
@@ -1425,7 +1849,12 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 			if c.server != nil {
 				methodInfos = c.server.receivingMethodInfos()
 			}
-			if _, err := checkRequest(jreq, methodInfos); err != nil {
+			_, err := checkRequest(jreq, methodInfos)
+			if errors.Is(err, jsonrpc2.ErrNotHandled) && c.server != nil && c.server.opts.UnknownMethodHandler != nil {
+				// Unknown methods are left to the server's UnknownMethodHandler.
+				err = nil
+			}
+			if err != nil {
 				if protocolVersion >= protocolVersion20260728 && errors.Is(err, jsonrpc2.ErrNotHandled) && jreq.IsCall() {
 					writeJSONRPCError(w, http.StatusNotFound, jreq.ID, &jsonrpc.Error{
 						Code:    jsonrpc.CodeMethodNotFound,
@@ -1575,6 +2004,9 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 				// response, we can signal to the client that the session is gone.
 				http.Error(w, "session is closing", http.StatusNotFound)
 				return
+			case <-req.Context().Done():
+				// The client is gone: don't publish the rest of the batch.
+				return
 			}
 		}
 		w.WriteHeader(http.StatusAccepted)
@@ -1583,6 +2015,24 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 
 	// Invariant: we have at least one call.
 	//
+	// subscriptions/listen is inherently a long-lived SSE endpoint (SEP-2575):
+	// it has no synchronous result, the response stream stays open until the
+	// client cancels, and the server pushes notifications on it as they occur.
+	// Force SSE mode (bypassing JSONResponse) so the buffered application/json
+	// path doesn't deadlock waiting for a completion that won't come.
+	useSSE := !c.jsonResponse || isSubscriptionsListen
+	if useSSE && !canFlush(w) {
+		// Without flushing, events would sit in the writer's buffer
+		// indefinitely. Fall back to a single JSON response where possible.
+		if isSubscriptionsListen {
+			c.logger.Warn("response writer cannot flush; rejecting subscriptions/listen")
+			http.Error(w, "streaming unsupported: response writer cannot flush", http.StatusInternalServerError)
+			return
+		}
+		c.logger.Warn("response writer cannot flush; responding with application/json")
+		useSSE = false
+	}
+
 	// Create a logical stream to track its responses.
 	// Important: don't publish the incoming messages until the stream is
 	// registered, as the server may attempt to respond to incoming messages as
@@ -1594,13 +2044,6 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 	}
 	stream.isListen = isSubscriptionsListen
 
-	// subscriptions/listen is inherently a long-lived SSE endpoint (SEP-2575):
-	// it has no synchronous result, the response stream stays open until the
-	// client cancels, and the server pushes notifications on it as they occur.
-	// Force SSE mode (bypassing JSONResponse) so the buffered application/json
-	// path doesn't deadlock waiting for a completion that won't come.
-	useSSE := !c.jsonResponse || isSubscriptionsListen
-
 	// Set response headers. Accept was checked in [StreamableHTTPHandler].
 	w.Header().Set("Cache-Control", "no-cache, no-transform")
 	if useSSE {
@@ -1663,7 +2106,7 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 				c.logger.Warn(fmt.Sprintf("Storing priming event: %v", err))
 			}
 			stream.lastIdx++
-			e := Event{Name: "prime", ID: formatEventID(stream.id, stream.lastIdx)}
+			e := Event{Name: "prime", ID: c.eventIDs.FormatEventID(stream.id, stream.lastIdx)}
 			if _, err := writeEvent(w, e); err != nil {
 				c.logger.Warn(fmt.Sprintf("Writing priming event: %v", err))
 			}
@@ -1671,14 +2114,16 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 	}
 
 	// Publish incoming messages.
-	for _, msg := range incoming {
+	for i, msg := range incoming {
 		select {
 		case c.incoming <- msg:
-		// Note: don't select on req.Context().Done() here, since we've already
-		// received the requests and may have already published a response message
-		// or notification. The client could resume the stream.
-		//
-		// In fact, this send could be in a separate goroutine.
+		// Note: the messages published so far may already have produced
+		// responses or notifications, which the client could resume. But if
+		// the client goes away, there is no point in publishing the rest of
+		// the batch: the server never sees those requests, so forget them.
+		case <-req.Context().Done():
+			c.abandonCalls(stream, incoming[i:])
+			return
 		case <-c.done:
 			// Session closed: we don't know if any data has been written, so it's
 			// too late to write a status code here.
@@ -1689,8 +2134,35 @@ func (c *streamableServerConn) servePOST(w http.ResponseWriter, req *http.Reques
 	c.hangResponse(req.Context(), done)
 }
 
-// Event IDs: encode both the logical connection ID and the index, as
-// <streamID>_<idx>, to be consistent with the typescript implementation.
+// An EventIDCodec converts between SSE event IDs and positions in the logical
+// streams of a streamable HTTP session.
+//
+// Event IDs are sent to the client with each event on a resumable stream, and
+// returned in the Last-Event-ID header of a GET request to resume the stream
+// after the last event the client received. A custom codec may be used to
+// satisfy clients that expect a particular ID structure, or to embed
+// additional resumption metadata.
+type EventIDCodec interface {
+	// FormatEventID returns the ID of the event at index idx of the stream
+	// with the given ID.
+	FormatEventID(streamID string, idx int) string
+	// ParseEventID is the inverse of FormatEventID. It reports ok=false if
+	// eventID is malformed.
+	ParseEventID(eventID string) (streamID string, idx int, ok bool)
+}
+
+// defaultEventIDCodec is the default [EventIDCodec]. It encodes both the
+// logical connection ID and the index, as <streamID>_<idx>, to be consistent
+// with the typescript implementation.
+type defaultEventIDCodec struct{}
+
+func (defaultEventIDCodec) FormatEventID(streamID string, idx int) string {
+	return formatEventID(streamID, idx)
+}
+
+func (defaultEventIDCodec) ParseEventID(eventID string) (string, int, bool) {
+	return parseEventID(eventID)
+}
 
 // formatEventID returns the event ID to use for the logical connection ID
 // streamID and message index idx.
@@ -1769,8 +2241,9 @@ func (c *streamableServerConn) Write(ctx context.Context, msg jsonrpc.Message) e
 	}
 
 	// If the stream is application/json, but the message is not a response, we
-	// must send it out of band to the standalone SSE stream.
-	if c.jsonResponse && !responseTo.IsValid() {
+	// must send it out of band to the standalone SSE stream. The same goes for
+	// messages that the caller has routed there with WithStandaloneStream.
+	if !responseTo.IsValid() && (c.jsonResponse || ctx.Value(standaloneStreamContextKey{}) != nil) {
 		relatedRequest = jsonrpc.ID{}
 	}
 
@@ -1818,6 +2291,32 @@ func (c *streamableServerConn) Write(ctx context.Context, msg jsonrpc.Message) e
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A server->client request routed to the standalone SSE stream can only be
+	// answered once the client receives it. Without an event store to hold it
+	// until the client opens that stream, explain why it can't be delivered:
+	// typically, a tool handler is waiting on it while the response stream of
+	// the tool call can't carry it.
+	if req, ok := msg.(*jsonrpc.Request); ok && req.IsCall() && s.id == "" && s.done == nil && c.eventStore == nil {
+		return fmt.Errorf("%w: cannot send %q request: no standalone SSE stream is open, and there is no event store to hold the request until the client opens one with a GET request", jsonrpc2.ErrRejected, req.Method)
+	}
+
+	// Without an event store to replay them from, hold messages for the
+	// standalone SSE stream until the client opens it, rather than dropping
+	// them: servers commonly send notifications right after initialization,
+	// before the client has made its GET request.
+	if s.id == "" && s.done == nil && c.eventStore == nil && !c.stateless && c.sessionID != "" {
+		if len(s.pending) >= maxPendingMessages || s.pendingBytes+len(data) > maxPendingBytes {
+			method := ""
+			if req, ok := msg.(*jsonrpc.Request); ok {
+				method = req.Method
+			}
+			return fmt.Errorf("%w: cannot send %q: no standalone SSE stream is open, and the buffer holding messages until the client opens one with a GET request is full", jsonrpc2.ErrRejected, method)
+		}
+		s.pending = append(s.pending, data)
+		s.pendingBytes += len(data)
+		return nil
+	}
+
 	// Store in eventStore before delivering.
 	// TODO(rfindley): we should only append if the response is SSE, not JSON, by
 	// pushing down into the delivery layer.
@@ -1836,13 +2335,16 @@ func (c *streamableServerConn) Write(ctx context.Context, msg jsonrpc.Message) e
 	// Use s.lastIdx + 1 because deliverLocked increments before writing.
 	var eventID string
 	if c.eventStore != nil && protocolVersion < protocolVersion20260728 {
-		eventID = formatEventID(s.id, s.lastIdx+1)
+		eventID = c.eventIDs.FormatEventID(s.id, s.lastIdx+1)
 	}
 
 	// SEP-2575: map protocol-level JSON-RPC error codes to HTTP status codes
 	// on the new protocol (>= 2026-07-28). When non-zero, deliverLocked will
 	// write the body as raw application/json with the override status.
 	overrideStatus := extractErrorStatus(ctx, msg)
+	if overrideStatus == 0 && s.soleJSONResponseLocked(responseTo) {
+		overrideStatus = configuredErrorStatus(c.errorHTTPStatus, msg)
+	}
 
 	done, err := s.deliverLocked(data, eventID, responseTo, overrideStatus)
 	if err != nil {
@@ -1883,10 +2385,40 @@ func (c *streamableServerConn) Close() error {
 // endpoint serving the streamable HTTP transport defined by the 2025-03-26
 // version of the spec.
 type StreamableClientTransport struct {
-	Endpoint   string
+	Endpoint string
+	// HTTPClient is the client used to make HTTP requests. If nil,
+	// [http.DefaultClient] is used.
+	//
+	// Underlying TCP connections are pooled by the client's [http.Transport],
+	// so sharing a single HTTPClient across transports lets sessions reuse
+	// connections: a client that reconnects frequently need not pay for a new
+	// TCP (and TLS) handshake with each [Client.Connect].
+	//
+	// Each in-flight request (including the standalone SSE stream, and any
+	// call whose result is streamed) holds its own connection. The default
+	// transport keeps at most two idle connections per host, which causes
+	// connection churn for clients with many concurrent calls to one server;
+	// such clients should use a transport with a larger
+	// [http.Transport.MaxIdleConnsPerHost].
+	//
+	// Idle pooled connections are closed after
+	// [http.Transport.IdleConnTimeout]. To keep a session (and its
+	// connections) warm across idle periods, set [ClientOptions.KeepAlive].
 	HTTPClient *http.Client
 	// MaxRetries is the maximum number of times to attempt a reconnect before giving up.
 	// It defaults to 5. To disable retries, use a negative number.
+	//
+	// Reconnection attempts back off exponentially. If the server responds
+	// to the GET request reconnecting an SSE stream with 429 Too Many
+	// Requests or 503 Service Unavailable and a Retry-After header, the next
+	// attempt is made after the requested delay instead, up to 30 seconds.
+	//
+	// MaxRetries also bounds the retries of a POST request to which the
+	// server responds in the same way, including a request resent after
+	// authorization with [StreamableClientTransport.OAuthHandler]. Each retry
+	// waits for the requested delay, again up to 30 seconds. Without
+	// Retry-After, or once the retries run out, such a response fails the
+	// request, but not the session.
 	MaxRetries int
 
 	// DisableStandaloneSSE controls whether the client establishes a standalone SSE stream
@@ -1912,6 +2444,27 @@ type StreamableClientTransport struct {
 	// OAuthHandler is an optional field that, if provided, will be used to authorize the requests.
 	OAuthHandler auth.OAuthHandler
 
+	// SessionID, if set, is the ID of an existing session to resume, rather
+	// than creating a new one: for example, one that the client recorded with
+	// [ClientSession.ID] before it restarted. The server must still hold the
+	// session. Since the session is already initialized, connect with
+	// [ClientSessionOptions.InitializeResult] set to its recorded
+	// initialization result.
+	SessionID string
+
+	// LastEventID, if set along with SessionID, is the ID of the last event
+	// that the client received on the session's standalone SSE stream, as
+	// reported by [ClientSession.LastEventID]. The client resumes the stream
+	// after that event, so that the server can replay the events sent while
+	// the client was away, if it has an [EventStore].
+	LastEventID string
+
+	// UserAgent is the User-Agent header sent with each HTTP request.
+	// If empty, the header identifies the client and the SDK, as in
+	// "my-client/1.0.0 mcp-go-sdk/v1.2.0". The client part is omitted if the
+	// transport is not connected by [Client.Connect].
+	UserAgent string
+
 	// TODO(rfindley): propose exporting these.
 	// If strict is set, the transport is in 'strict mode', where any violation
 	// of the MCP spec causes a failure.
@@ -1919,6 +2472,8 @@ type StreamableClientTransport struct {
 	// If logger is set, it is used to log aspects of the transport, such as spec
 	// violations that were ignored.
 	logger *slog.Logger
+	// If clock is set, it is the source of time for reconnection delays.
+	clock clock
 }
 
 // These settings are not (yet) exposed to the user in
@@ -1981,6 +2536,10 @@ func (t *StreamableClientTransport) Connect(ctx context.Context) (Connection, er
 	// allows us to preserve context values (which may be necessary for auth
 	// middleware), yet only cancel the standalone stream when the connection is closed.
 	connCtx, cancel := context.WithCancel(xcontext.Detach(ctx))
+	clk := t.clock
+	if clk == nil {
+		clk = realClock{}
+	}
 	conn := &streamableClientConn{
 		url:                  t.Endpoint,
 		client:               client,
@@ -1989,11 +2548,17 @@ func (t *StreamableClientTransport) Connect(ctx context.Context) (Connection, er
 		maxRetries:           maxRetries,
 		strict:               t.strict,
 		logger:               ensureLogger(t.logger), // must be non-nil for safe logging
+		clock:                clk,
 		ctx:                  connCtx,
 		cancel:               cancel,
 		failed:               make(chan struct{}),
 		disableStandaloneSSE: t.DisableStandaloneSSE,
 		oauthHandler:         t.OAuthHandler,
+		userAgent:            userAgent(ctx, t.UserAgent),
+		sessionID:            t.SessionID,
+	}
+	if t.SessionID != "" {
+		conn.standaloneEventID = t.LastEventID
 	}
 	return conn, nil
 }
@@ -2007,6 +2572,7 @@ type streamableClientConn struct {
 	maxRetries int
 	strict     bool         // from [StreamableClientTransport.strict]
 	logger     *slog.Logger // from [StreamableClientTransport.logger]
+	clock      clock        // from [StreamableClientTransport.clock]
 
 	// disableStandaloneSSE controls whether to disable the standalone SSE stream
 	// for receiving server-to-client notifications when no request is in flight.
@@ -2015,6 +2581,8 @@ type streamableClientConn struct {
 	// oauthHandler is the OAuth handler for the connection.
 	oauthHandler auth.OAuthHandler // from [StreamableClientTransport.OAuthHandler]
 
+	userAgent string // from [StreamableClientTransport.UserAgent], with the default applied
+
 	// Guard calls to Close, as it may be called multiple times.
 	closeOnce sync.Once
 	closeErr  error
@@ -2035,6 +2603,9 @@ type streamableClientConn struct {
 	mu                sync.Mutex
 	initializedResult *InitializeResult
 	sessionID         string
+	// standaloneEventID is the ID of the last event received on the
+	// standalone SSE stream, if any.
+	standaloneEventID string
 }
 
 var _ clientConnection = (*streamableClientConn)(nil)
@@ -2069,8 +2640,16 @@ func (c *streamableClientConn) sessionUpdated(state clientSessionState) {
 	}
 }
 
+// LastEventID returns the ID of the last event received on the standalone
+// SSE stream.
+func (c *streamableClientConn) LastEventID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.standaloneEventID
+}
+
 func (c *streamableClientConn) connectStandaloneSSE() {
-	resp, err := c.connectSSE(c.ctx, "", 0, true)
+	resp, err := c.connectSSE(c.ctx, c.LastEventID(), 0, true)
 	if err != nil {
 		// If the client didn't cancel the request, and failure breaks the logical
 		// session.
@@ -2193,26 +2772,28 @@ func (c *streamableClientConn) Write(ctx context.Context, msg jsonrpc.Message) e
 		return fmt.Errorf("%s: %v", requestSummary, err)
 	}
 
-	doRequest := func() (*http.Request, *http.Response, error) {
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
-		if err != nil {
-			return nil, nil, err
-		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json, text/event-stream")
-
-		if err := c.setMCPHeaders(req); err != nil {
-			// Failure to set headers means that the request was not sent.
-			// Wrap with ErrRejected so the jsonrpc2 connection doesn't set writeErr
-			// and permanently break the connection.
-			return nil, nil, fmt.Errorf("%s: %w: %w", requestSummary, jsonrpc2.ErrRejected, err)
-		}
-		// Keep this after the setMCPHeaders call to ensure that the
-		// protocol version header is set.
-		setStandardHeaders(ctx, req.Header, msg)
-		resp, err := c.client.Do(req)
+	// doRequest sends the request, retrying it if the server asks the client
+	// to retry later.
+	doRequest := func() (req *http.Request, resp *http.Response, err error) {
+		resp, err = doRetryLater(ctx, c.clock, c.maxRetries, func() (*http.Response, error) {
+			r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Accept", "application/json, text/event-stream")
+			if err := c.setMCPHeaders(r); err != nil {
+				return nil, err
+			}
+			// Keep this after the setMCPHeaders call to ensure that the
+			// protocol version header is set.
+			setStandardHeaders(ctx, r.Header, msg)
+			req = r
+			return c.client.Do(r)
+		})
 		if err != nil {
-			// Any error from client.Do means the request didn't reach the server.
+			// Any error here means that the request didn't reach the server,
+			// or that we stopped waiting to retry it.
 			// Wrap with ErrRejected so the jsonrpc2 connection doesn't set writeErr
 			// and permanently break the connection.
 			err = fmt.Errorf("%s: %w: %w", requestSummary, jsonrpc2.ErrRejected, err)
@@ -2249,6 +2830,7 @@ func (c *streamableClientConn) Write(ctx context.Context, msg jsonrpc.Message) e
 			return fmt.Errorf("%s: %w: %w", requestSummary, jsonrpc2.ErrRejected, err)
 		}
 		// Retry the request after successful authorization.
+		drainAndClose(resp.Body)
 		_, resp, err = doRequest()
 		if err != nil {
 			return err
@@ -2283,12 +2865,15 @@ func (c *streamableClientConn) Write(ctx context.Context, msg jsonrpc.Message) e
 	}
 
 	if forCall == nil {
-		resp.Body.Close()
+		drainAndClose(resp.Body)
 
 		// [§2.1.4]: "If the input is a JSON-RPC response or notification:
 		// If the server accepts the input, the server MUST return HTTP status code 202 Accepted with no body."
 		//
 		// [§2.1.4]: https://modelcontextprotocol.io/specification/2025-06-18/basic/transports#listening-for-messages-from-the-server
+		//
+		// Since no message is expected in reply, the body and Content-Type of
+		// the response, if any, are ignored.
 		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
 			errMsg := fmt.Sprintf("unexpected status code %d from non-call", resp.StatusCode)
 			// Some servers return 200, even with an empty json body.
@@ -2329,6 +2914,8 @@ func (c *streamableClientConn) setMCPHeaders(req *http.Request) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	req.Header.Set("User-Agent", c.userAgent)
+
 	if c.oauthHandler != nil {
 		ts, err := c.oauthHandler.TokenSource(c.ctx)
 		if err != nil {
@@ -2458,7 +3045,7 @@ func (c *streamableClientConn) handleSSE(ctx context.Context, requestSummary str
 func (c *streamableClientConn) checkResponse(ctx context.Context, requestSummary string, resp *http.Response) (err error) {
 	defer func() {
 		if err != nil {
-			resp.Body.Close()
+			drainAndClose(resp.Body)
 		}
 	}()
 	// §2.5.3: "The server MAY terminate the session at any time, after
@@ -2491,6 +3078,18 @@ func (c *streamableClientConn) checkResponse(ctx context.Context, requestSummary
 	return nil
 }
 
+// maxDrainBytes bounds how much of an unwanted response body
+// [drainAndClose] reads.
+const maxDrainBytes = 4 << 10
+
+// drainAndClose reads (a bounded amount of) any remainder of body before
+// closing it, so that the underlying connection can be reused by the
+// [http.Client]'s connection pool.
+func drainAndClose(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrainBytes)
+	body.Close()
+}
+
 // processStream reads from a single response body, sending events to the
 // incoming channel. It returns the ID of the last processed event and a flag
 // indicating if the connection was closed by the client. If resp is nil, it
@@ -2548,6 +3147,11 @@ func (c *streamableClientConn) processStream(ctx context.Context, requestSummary
 
 		select {
 		case c.incoming <- msg:
+			if forCall == nil && evt.ID != "" {
+				c.mu.Lock()
+				c.standaloneEventID = evt.ID
+				c.mu.Unlock()
+			}
 			// Check if this is the response to our call, which terminates the request.
 			// (it could also be a server->client request or notification).
 			if jsonResp, ok := msg.(*jsonrpc.Response); ok && forCall != nil {
@@ -2623,7 +3227,7 @@ func (c *streamableClientConn) connectSSE(ctx context.Context, lastEventID strin
 			// succeed anyway.
 			return nil, ctx.Err()
 
-		case <-time.After(delay):
+		case <-c.clock.After(delay):
 			req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
 			if err != nil {
 				return nil, err
@@ -2641,6 +3245,14 @@ func (c *streamableClientConn) connectSSE(ctx context.Context, lastEventID strin
 				delay = calculateReconnectDelay(attempt + 1)
 				continue
 			}
+			if d, ok := retryLaterDelay(resp, c.clock.Now()); ok {
+				// The server is overloaded: try again after the delay it
+				// requests. Other failures are left to the caller.
+				drainAndClose(resp.Body)
+				finalErr = errors.New(resp.Status)
+				delay = d
+				continue
+			}
 			return resp, nil
 		}
 	}
@@ -2669,7 +3281,7 @@ func (c *streamableClientConn) Close() error {
 				} else if resp, err := c.client.Do(req); err != nil {
 					c.closeErr = err
 				} else {
-					resp.Body.Close()
+					drainAndClose(resp.Body)
 				}
 			}
 		}
@@ -2697,6 +3309,64 @@ func calculateReconnectDelay(attempt int) time.Duration {
 	return backoffDuration + jitter
 }
 
+// retryAfter returns the delay requested by the Retry-After header in h, in
+// either its delay-seconds or its HTTP-date form ([RFC 9110, section 10.2.3]),
+// relative to now. It reports whether h has a valid Retry-After header.
+//
+// [RFC 9110, section 10.2.3]: https://www.rfc-editor.org/rfc/rfc9110#section-10.2.3
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseUint(v, 10, 64); err == nil {
+		if secs > uint64(math.MaxInt64/time.Second) {
+			return time.Duration(math.MaxInt64), true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// retryLaterDelay reports whether resp asks the client to retry its request
+// later: that is, whether it is a 429 Too Many Requests or 503 Service
+// Unavailable response with a Retry-After header. If so, it returns the
+// requested delay, capped at reconnectMaxDelay so that a server cannot stall
+// the client indefinitely.
+func retryLaterDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	d, ok := retryAfter(resp.Header, now)
+	return min(d, reconnectMaxDelay), ok
+}
+
+// doRetryLater calls do to send a request, and calls it again, up to
+// maxRetries times, while the response asks the client to retry later (see
+// [retryLaterDelay]), after the delay that the response requests. It returns
+// the last response, or the error from do or from ctx.
+func doRetryLater(ctx context.Context, clk clock, maxRetries int, do func() (*http.Response, error)) (*http.Response, error) {
+	for retries := 0; ; retries++ {
+		resp, err := do()
+		if err != nil || retries >= maxRetries {
+			return resp, err
+		}
+		delay, ok := retryLaterDelay(resp, clk.Now())
+		if !ok {
+			return resp, nil
+		}
+		drainAndClose(resp.Body)
+		select {
+		case <-clk.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
 // isTransientHTTPStatus reports whether the HTTP status code indicates a
 // transient server error that should not permanently break the connection.
 func isTransientHTTPStatus(statusCode int) bool {
@@ -207,7 +207,8 @@ This header (set by [streamableClientConn.setMCPHeaders]):
 [StreamableClientTransport] configuration:
   - [StreamableClientTransport.Endpoint]: URL of the MCP server
   - [StreamableClientTransport.HTTPClient]: Custom HTTP client (optional)
-  - [StreamableClientTransport.MaxRetries]: Reconnection attempts (default 5)
+  - [StreamableClientTransport.MaxRetries]: Reconnection attempts, and retries of
+    requests that the server asks to retry later with Retry-After (default 5)
 
 [streamableClientConn] handles the [Connection] interface:
   - [streamableClientConn.Read]: Returns messages from incoming channel
@@ -5,13 +5,17 @@
 package mcp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -351,6 +355,73 @@ func TestStreamableClientGETHandling(t *testing.T) {
 	}
 }
 
+func TestStreamableClientNotificationAccepted(t *testing.T) {
+	// Per §2.1.4 of the spec, servers accept a POST carrying only
+	// notifications or responses with 202 Accepted and no body. The client must
+	// handle such a response cleanly, even in strict mode, and even if the
+	// server sets a Content-Type for the empty body.
+	ctx := context.Background()
+	tests := []struct {
+		label  string
+		status int
+		header header
+	}{
+		{"accepted", http.StatusAccepted, nil},
+		{"accepted with content type", http.StatusAccepted, header{"Content-Type": "application/json"}},
+		{"no content", http.StatusNoContent, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.label, func(t *testing.T) {
+			fake := &fakeStreamableServer{
+				t: t,
+				responses: fakeResponses{
+					{"POST", "", methodInitialize, ""}: {
+						header: header{
+							"Content-Type":  "application/json",
+							sessionIDHeader: "123",
+						},
+						body: jsonBody(t, initResp),
+					},
+					{"POST", "123", notificationInitialized, ""}: {
+						status: http.StatusAccepted,
+					},
+					{"POST", "123", notificationProgress, ""}: {
+						status: test.status,
+						header: test.header,
+					},
+					{"POST", "123", methodListTools, ""}: {
+						header: header{"Content-Type": "application/json"},
+						body:   jsonBody(t, resp(2, &ListToolsResult{Tools: []*Tool{}}, nil)),
+					},
+					{"DELETE", "123", "", ""}: {},
+				},
+			}
+			httpServer := httptest.NewServer(fake)
+			defer httpServer.Close()
+
+			transport := &StreamableClientTransport{Endpoint: httpServer.URL, DisableStandaloneSSE: true, strict: true}
+			// Pin to 2025-11-25, as in TestStreamableClientStrictness.
+			session, err := NewClient(testImpl, nil).Connect(ctx, transport, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := session.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: "t", Progress: 1}); err != nil {
+				t.Errorf("NotifyProgress: %v", err)
+			}
+			// The session remains usable.
+			if _, err := session.ListTools(ctx, nil); err != nil {
+				t.Errorf("ListTools after notification: %v", err)
+			}
+			if err := session.Close(); err != nil {
+				t.Errorf("closing session: %v", err)
+			}
+			if missing := fake.missingRequests(); len(missing) > 0 {
+				t.Errorf("did not receive expected requests: %v", missing)
+			}
+		})
+	}
+}
+
 func TestStreamableClientStrictness(t *testing.T) {
 	ctx := context.Background()
 
@@ -1673,3 +1744,398 @@ func TestStreamableClientConnect_DiscoverUnsupportedVersionNegotiation(t *testin
 		t.Errorf("InitializeResult.ProtocolVersion = %q, want %q", got, protocolVersion20260728)
 	}
 }
+
+func TestStreamableClientConnectionReuse(t *testing.T) {
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "greet"}, sayHi)
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil)
+	httpServer := httptest.NewUnstartedServer(handler)
+	var newConns atomic.Int32
+	httpServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	httpServer.Start()
+	defer httpServer.Close()
+
+	// Sequential sessions sharing an HTTPClient should share its connections,
+	// including after 202 Accepted responses.
+	httpClient := &http.Client{Transport: &http.Transport{}}
+	ctx := context.Background()
+	for _, version := range []string{protocolVersion20251125, latestProtocolVersion, protocolVersion20251125} {
+		transport := &StreamableClientTransport{
+			Endpoint:             httpServer.URL,
+			HTTPClient:           httpClient,
+			DisableStandaloneSSE: true,
+		}
+		cs, err := NewClient(testImpl, nil).Connect(ctx, transport, &ClientSessionOptions{protocolVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "greet", Arguments: map[string]any{"Name": "user"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cs.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Requests within a session may overlap, so a session may use more than
+	// one connection, but later sessions must not need any new connections.
+	if got, want := newConns.Load(), int32(2); got > want {
+		t.Errorf("opened %d connections, want at most %d", got, want)
+	}
+}
+
+// recordUserAgents wraps h to record the User-Agent of each request.
+func recordUserAgents(h http.Handler) (http.Handler, func() []string) {
+	var (
+		mu  sync.Mutex
+		uas []string
+	)
+	record := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		uas = append(uas, req.Method+" "+req.UserAgent())
+		mu.Unlock()
+		h.ServeHTTP(w, req)
+	})
+	return record, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(uas)
+	}
+}
+
+func TestClientTransportUserAgent(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	defaultUA := "test/v1.0.0 mcp-go-sdk/" + sdkVersion()
+	for _, test := range []struct {
+		name    string
+		handler http.Handler
+		newT    func(url, ua string) Transport
+	}{
+		{
+			"streamable",
+			NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil),
+			func(url, ua string) Transport { return &StreamableClientTransport{Endpoint: url, UserAgent: ua} },
+		},
+		{
+			"sse",
+			NewSSEHandler(func(*http.Request) *Server { return server }, nil),
+			func(url, ua string) Transport { return &SSEClientTransport{Endpoint: url, UserAgent: ua} },
+		},
+	} {
+		for _, ua := range []string{"", "custom/2.0"} {
+			t.Run(fmt.Sprintf("%s/%q", test.name, ua), func(t *testing.T) {
+				h, got := recordUserAgents(test.handler)
+				httpServer := httptest.NewServer(mustNotPanic(t, h))
+				defer httpServer.Close()
+
+				cs, err := NewClient(testImpl, nil).Connect(ctx, test.newT(httpServer.URL, ua), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := cs.Ping(ctx, nil); err != nil {
+					t.Fatal(err)
+				}
+				cs.Close()
+
+				want := ua
+				if want == "" {
+					want = defaultUA
+				}
+				uas := got()
+				if len(uas) == 0 {
+					t.Fatal("no requests recorded")
+				}
+				for _, got := range uas {
+					if _, gotUA, _ := strings.Cut(got, " "); gotUA != want {
+						t.Errorf("request %q: got User-Agent %q, want %q", got, gotUA, want)
+					}
+				}
+			})
+		}
+	}
+
+	// Without a client, the default omits the client part.
+	if got, want := userAgent(ctx, ""), "mcp-go-sdk/"+sdkVersion(); got != want {
+		t.Errorf("userAgent() = %q, want %q", got, want)
+	}
+	ctx = context.WithValue(ctx, clientInfoContextKey{}, &Implementation{Name: "my client (beta)"})
+	if got, want := userAgent(ctx, ""), "my-client--beta- mcp-go-sdk/"+sdkVersion(); got != want {
+		t.Errorf("userAgent() = %q, want %q", got, want)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{" 3 ", 3 * time.Second, true},
+		{"99999999999999999999", 0, false},
+		{"9999999999999", time.Duration(math.MaxInt64), true},
+		{"-1", 0, false},
+		{"1.5", 0, false},
+		{"Thu, 01 Jan 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 01 Jan 2026 11:59:00 GMT", 0, true}, // in the past
+		{"tomorrow", 0, false},
+	}
+	for _, test := range tests {
+		h := http.Header{}
+		if test.value != "" {
+			h.Set("Retry-After", test.value)
+		}
+		got, ok := retryAfter(h, now)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %t, want %v, %t", test.value, got, ok, test.want, test.wantOK)
+		}
+	}
+
+	// Only 429 and 503 responses with Retry-After ask to retry later, and the
+	// delay is capped.
+	for _, test := range []struct {
+		status int
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{http.StatusTooManyRequests, "3", 3 * time.Second, true},
+		{http.StatusServiceUnavailable, "3600", reconnectMaxDelay, true},
+		{http.StatusServiceUnavailable, "Fri, 02 Jan 2026 12:00:00 GMT", reconnectMaxDelay, true},
+		{http.StatusServiceUnavailable, "", 0, false},
+		{http.StatusInternalServerError, "3", 0, false},
+		{http.StatusUnauthorized, "3", 0, false},
+	} {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		if test.value != "" {
+			resp.Header.Set("Retry-After", test.value)
+		}
+		if got, ok := retryLaterDelay(resp, now); got != test.want || ok != test.wantOK {
+			t.Errorf("retryLaterDelay(%d, %q) = %v, %t, want %v, %t", test.status, test.value, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestStreamableClientGETRetryAfter(t *testing.T) {
+	// Make the default backoff long enough that the test would time out if the
+	// client ignored Retry-After.
+	defer func(delay int64) {
+		reconnectInitialDelay.Store(delay)
+	}(reconnectInitialDelay.Load())
+	reconnectInitialDelay.Store(int64(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	streamDone := make(chan struct{})
+	fake := &fakeStreamableServer{
+		t: t,
+		responses: fakeResponses{
+			{"POST", "", methodInitialize, ""}: {
+				header: header{
+					"Content-Type":  "application/json",
+					sessionIDHeader: "123",
+				},
+				body: jsonBody(t, initResp),
+			},
+			{"POST", "123", notificationInitialized, ""}: {
+				status: http.StatusAccepted,
+			},
+			{"GET", "123", "", ""}: {
+				header: header{"Content-Type": "text/event-stream"},
+				body: `data: {"jsonrpc": "2.0", "method": "notifications/tools/list_changed", "params": {}}
+
+`,
+				done: streamDone,
+			},
+			{"DELETE", "123", "", ""}: {optional: true},
+		},
+	}
+	// The first two GET requests are rejected, with Retry-After in each of
+	// its forms.
+	var gets atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			switch gets.Add(1) {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+				return
+			case 2:
+				w.Header().Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		fake.ServeHTTP(w, req)
+	})
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+	defer close(streamDone) // must be deferred after httpServer.Close, to avoid deadlock
+
+	listChanged := make(chan struct{}, 1)
+	client := NewClient(testImpl, &ClientOptions{
+		ToolListChangedHandler: func(context.Context, *ToolListChangedRequest) {
+			listChanged <- struct{}{}
+		},
+	})
+	// Pin to 2025-11-25, as in TestStreamableClientStrictness.
+	cs, err := client.Connect(ctx, &StreamableClientTransport{Endpoint: httpServer.URL}, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	select {
+	case <-listChanged:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the standalone stream")
+	}
+	if got := gets.Load(); got != 3 {
+		t.Errorf("got %d GET requests, want 3", got)
+	}
+}
+
+func TestStreamableClientGETRetryAfterExhausted(t *testing.T) {
+	// Once the retries run out, the session fails with an error that reports
+	// the status of the last response.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server := NewServer(testImpl, nil)
+	streamHandler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil)
+	var gets atomic.Int32
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			gets.Add(1)
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		streamHandler.ServeHTTP(w, req)
+	}))
+	defer httpServer.Close()
+
+	transport := &StreamableClientTransport{Endpoint: httpServer.URL, MaxRetries: 1}
+	// The standalone stream is opened during Connect, so the failure may be
+	// reported by Connect or by a later call.
+	cs, err := NewClient(testImpl, nil).Connect(ctx, transport, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err == nil {
+		defer cs.Close()
+		for err == nil && ctx.Err() == nil {
+			time.Sleep(10 * time.Millisecond)
+			_, err = cs.ListTools(ctx, nil)
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "503 Service Unavailable") {
+		t.Errorf("got error %v, want one with the status of the last response", err)
+	}
+	if got := gets.Load(); got != 2 {
+		t.Errorf("got %d GET requests, want 2", got)
+	}
+}
+
+func TestStreamableClientPOSTRetryAfter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server := NewServer(testImpl, nil)
+	streamHandler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{JSONResponse: true})
+	// Each of the following responses is sent, in order, to a tools/list
+	// request, before the server handles it.
+	var (
+		mu       sync.Mutex
+		rejected []func(http.ResponseWriter)
+		posts    int
+	)
+	reject := func(status int, retryAfter string) func(http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "busy", status)
+		}
+	}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(methodListTools)) {
+				mu.Lock()
+				posts++
+				var r func(http.ResponseWriter)
+				if len(rejected) > 0 {
+					r, rejected = rejected[0], rejected[1:]
+				}
+				mu.Unlock()
+				if r != nil {
+					r(w)
+					return
+				}
+			}
+		}
+		streamHandler.ServeHTTP(w, req)
+	}))
+	defer httpServer.Close()
+
+	transport := &StreamableClientTransport{Endpoint: httpServer.URL, DisableStandaloneSSE: true}
+	cs, err := NewClient(testImpl, nil).Connect(ctx, transport, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	for _, test := range []struct {
+		name      string
+		responses []func(http.ResponseWriter)
+		wantPosts int
+		wantErr   string // if empty, the request succeeds
+	}{
+		{
+			name: "retry after",
+			responses: []func(http.ResponseWriter){
+				reject(http.StatusTooManyRequests, "0"),
+				reject(http.StatusServiceUnavailable, time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)),
+			},
+			wantPosts: 3,
+		},
+		{
+			name:      "no retry after",
+			responses: []func(http.ResponseWriter){reject(http.StatusServiceUnavailable, "")},
+			wantPosts: 1,
+			wantErr:   "Service Unavailable",
+		},
+		{
+			name:      "other status",
+			responses: []func(http.ResponseWriter){reject(http.StatusBadGateway, "0")},
+			wantPosts: 1,
+			wantErr:   "Bad Gateway",
+		},
+	} {
+		mu.Lock()
+		rejected, posts = test.responses, 0
+		mu.Unlock()
+		_, err := cs.ListTools(ctx, nil)
+		if test.wantErr == "" && err != nil {
+			t.Errorf("%s: ListTools failed: %v", test.name, err)
+		} else if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+			t.Errorf("%s: ListTools = %v, want error containing %q", test.name, err, test.wantErr)
+		}
+		mu.Lock()
+		if posts != test.wantPosts {
+			t.Errorf("%s: got %d POST requests, want %d", test.name, posts, test.wantPosts)
+		}
+		mu.Unlock()
+	}
+	// Failed requests do not break the session.
+	if _, err := cs.ListTools(ctx, nil); err != nil {
+		t.Errorf("ListTools after failures: %v", err)
+	}
+}
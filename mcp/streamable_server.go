@@ -81,6 +81,14 @@ There are two types of streams:
    - Completed when all responses have been sent
    - Can be resumed via GET with Last-Event-ID if interrupted
 
+Each stream is delivered to at most one HTTP response at a time. A client may
+therefore hold several GET requests open concurrently: the standalone stream,
+and one resumption of each interrupted request stream. A long-lived
+notification channel is simply the standalone stream; POST requests made while
+it is open get their own request streams. Concurrent GETs for the same stream
+ID (including two standalone GETs) are rejected with 409 Conflict, since
+messages are never broadcast across streams.
+
 # Message Routing
 
 When the server writes a message, it must be routed to the correct [stream]:
@@ -89,7 +97,10 @@ When the server writes a message, it must be routed to the correct [stream]:
   - Requests/Notifications made during request handling: Routed to the same
     stream as the triggering request (via context)
   - Requests/Notifications made outside request handling: Routed to the
-    standalone SSE stream
+    standalone SSE stream (or, in stateless mode, to a subscriptions/listen
+    stream)
+  - Requests/Notifications made during handling of a request that has
+    already been responded to: Rejected, rather than rerouted
 
 This routing is implemented using:
   - [streamableServerConn.requestStreams] maps request IDs to stream IDs
@@ -109,6 +120,7 @@ This requires an [EventStore] to be configured on the server.
 
 Event IDs are formatted as "<streamID>_<index>" to identify both the
 stream and position within that stream (see [formatEventID] and [parseEventID]).
+The format may be customized with [StreamableHTTPOptions.EventIDCodec].
 
 # Stateless Mode
 
@@ -7,6 +7,7 @@
 package mcp
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -876,6 +877,35 @@ func TestStreamableServerTransport(t *testing.T) {
 			},
 			wantSessions: 1,
 		},
+		{
+			// JSON-RPC IDs may be strings, such as UUIDs, or integers that
+			// exceed the precision of a float64.
+			name: "string and large IDs",
+			requests: []streamableRequest{
+				initialize,
+				initialized,
+				{
+					method: "POST",
+					messages: []jsonrpc.Message{&jsonrpc.Request{
+						ID:     jsonrpc2.StringID("6f1c2a9e-3b7d-4e58-9c0a-1d2e3f4a5b6c"),
+						Method: "tools/call",
+						Params: mustMarshal(&CallToolParams{Name: "tool"}),
+					}},
+					wantStatusCode: http.StatusOK,
+					wantMessages: []jsonrpc.Message{&jsonrpc.Response{
+						ID:     jsonrpc2.StringID("6f1c2a9e-3b7d-4e58-9c0a-1d2e3f4a5b6c"),
+						Result: mustMarshal(&CallToolResult{Content: []Content{}}),
+					}},
+				},
+				{
+					method:         "POST",
+					messages:       []jsonrpc.Message{req(1<<53+1, "tools/call", &CallToolParams{Name: "tool"})},
+					wantStatusCode: http.StatusOK,
+					wantMessages:   []jsonrpc.Message{resp(1<<53+1, &CallToolResult{Content: []Content{}}, nil)},
+				},
+			},
+			wantSessions: 1,
+		},
 		{
 			name: "uninitialized",
 			requests: []streamableRequest{
@@ -1646,6 +1676,238 @@ func TestEventID(t *testing.T) {
 	}
 }
 
+func TestStreamableSSEHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const interval = 30 * time.Second
+	server := NewServer(testImpl, nil)
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{
+		SSEHeartbeatInterval: interval,
+	})
+	clock := newFakeClock()
+	handler.clock = clock
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	initialize := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+	}
+	sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialized := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+	}
+	if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open the standalone SSE stream, and report the lines it receives. The
+	// stream must be closed before the server.
+	getCtx, cancelGet := context.WithCancel(ctx)
+	defer cancelGet()
+	lines := make(chan string, 10)
+	go func() {
+		defer close(lines)
+		getReq, err := http.NewRequestWithContext(getCtx, http.MethodGet, httpServer.URL, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		getReq.Header.Set(sessionIDHeader, sessionID)
+		getReq.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(getReq)
+		if err != nil {
+			return // canceled at the end of the test
+		}
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// The heartbeat ticker starts when the GET request is handled, so keep
+	// advancing the clock until a heartbeat arrives.
+	for {
+		clock.Advance(interval)
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("SSE stream closed before heartbeat")
+			}
+			if line == ": heartbeat" {
+				return
+			}
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for heartbeat")
+		}
+	}
+}
+
+// nonFlushingWriter hides every optional interface of the wrapped
+// ResponseWriter, including http.Flusher.
+type nonFlushingWriter struct {
+	http.ResponseWriter
+}
+
+func TestStreamableNonFlushingWriter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "greet"}, sayHi)
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil)
+	httpServer := httptest.NewServer(mustNotPanic(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(nonFlushingWriter{w}, req)
+	})))
+	defer httpServer.Close()
+
+	post := func(sessionID string, msg jsonrpc.Message) *http.Response {
+		t.Helper()
+		data, err := jsonrpc2.EncodeMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, httpServer.URL, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			httpReq.Header.Set(sessionIDHeader, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(httpReq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// POST requests fall back to application/json.
+	resp := post("", req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618}))
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got, want := resp.Header.Get("Content-Type"), "application/json"; got != want {
+		t.Fatalf("initialize Content-Type = %q, want %q", got, want)
+	}
+	if !bytes.Contains(body, []byte(`"id":1`)) {
+		t.Errorf("initialize response = %s, want response with id 1", body)
+	}
+	sessionID := resp.Header.Get(sessionIDHeader)
+	post(sessionID, req(0, notificationInitialized, &InitializedParams{})).Body.Close()
+
+	resp = post(sessionID, req(2, "tools/list", &ListToolsParams{}))
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got, want := resp.Header.Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("tools/list Content-Type = %q, want %q", got, want)
+	}
+	if !bytes.Contains(body, []byte(`"greet"`)) {
+		t.Errorf("tools/list response = %s, want tool \"greet\"", body)
+	}
+
+	// The standalone SSE stream cannot work without flushing.
+	getReq, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getReq.Header.Set(sessionIDHeader, sessionID)
+	getReq.Header.Set("Accept", "text/event-stream")
+	resp, err = http.DefaultClient.Do(getReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("GET status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+	if !bytes.Contains(body, []byte("cannot flush")) {
+		t.Errorf("GET body = %q, want mention of flushing", body)
+	}
+}
+
+func TestCanFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if !canFlush(rec) {
+		t.Error("canFlush(recorder) = false, want true")
+	}
+	if canFlush(nonFlushingWriter{rec}) {
+		t.Error("canFlush(nonFlushingWriter) = true, want false")
+	}
+	if !canFlush(unwrappingWriter{nonFlushingWriter{rec}, rec}) {
+		t.Error("canFlush(unwrappingWriter) = false, want true")
+	}
+}
+
+// unwrappingWriter exposes an underlying writer through Unwrap, as
+// middleware commonly does.
+type unwrappingWriter struct {
+	nonFlushingWriter
+	inner http.ResponseWriter
+}
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter { return w.inner }
+
+// prefixedEventIDCodec is an EventIDCodec that prefixes the default event IDs.
+type prefixedEventIDCodec struct{}
+
+func (prefixedEventIDCodec) FormatEventID(streamID string, idx int) string {
+	return "evt-" + formatEventID(streamID, idx)
+}
+
+func (prefixedEventIDCodec) ParseEventID(eventID string) (string, int, bool) {
+	rest, ok := strings.CutPrefix(eventID, "evt-")
+	if !ok {
+		return "", 0, false
+	}
+	return parseEventID(rest)
+}
+
+func TestStreamableEventIDCodec(t *testing.T) {
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "greet", Description: "say hi"}, sayHi)
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{
+		EventStore:   NewMemoryEventStore(nil),
+		EventIDCodec: prefixedEventIDCodec{},
+	})
+
+	initReq := req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})
+	testStreamableHandler(t, handler, []streamableRequest{
+		{
+			method:             "POST",
+			messages:           []jsonrpc.Message{initReq},
+			wantStatusCode:     http.StatusOK,
+			wantBodyContaining: "id: evt-",
+			wantSessionID:      true,
+		},
+		{
+			method:         "POST",
+			messages:       []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+			wantStatusCode: http.StatusAccepted,
+		},
+		{
+			method:             "POST",
+			messages:           []jsonrpc.Message{req(2, "tools/list", struct{}{})},
+			wantStatusCode:     http.StatusOK,
+			wantBodyContaining: "id: evt-",
+		},
+		{
+			// Event IDs in the default format are rejected.
+			method:         "GET",
+			headers:        http.Header{lastEventIDHeader: {"0_0"}},
+			wantStatusCode: http.StatusBadRequest,
+		},
+	})
+}
+
 func TestStreamableStateless(t *testing.T) {
 	initReq := req(1, methodInitialize, &InitializeParams{})
 	initResp := resp(1, &InitializeResult{
@@ -2398,6 +2660,33 @@ func TestStreamableFilterValidToolsIntegration(t *testing.T) {
 	}
 }
 
+func TestStreamableUnknownMethodHandler(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, &ServerOptions{
+		UnknownMethodHandler: func(context.Context, *ServerSession, *jsonrpc.Request) (any, error) {
+			return map[string]any{"ok": true}, nil
+		},
+	})
+	handler := NewStreamableHTTPHandler(func(req *http.Request) *Server { return server }, nil)
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	client := NewClient(testImpl, nil)
+	session, err := client.Connect(ctx, &StreamableClientTransport{Endpoint: httpServer.URL}, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	var res map[string]any
+	if err := session.getConn().Call(ctx, "acme/experimental", map[string]any{}).Await(ctx, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res["ok"] != true {
+		t.Errorf("got result %v, want ok", res)
+	}
+}
+
 // TestStreamable405AllowHeader verifies RFC 9110 §15.5.6 compliance:
 // 405 Method Not Allowed responses MUST include an Allow header.
 func TestStreamable405AllowHeader(t *testing.T) {
@@ -2693,6 +2982,75 @@ func TestStreamableSessionTimeout(t *testing.T) {
 	handler.mu.Unlock()
 }
 
+func TestStreamableSessionTimeoutFakeClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const timeout = time.Minute
+	server := NewServer(testImpl, nil)
+	deleted := make(chan string, 1)
+	handler := NewStreamableHTTPHandler(
+		func(req *http.Request) *Server { return server },
+		&StreamableHTTPOptions{SessionTimeout: timeout},
+	)
+	clock := newFakeClock()
+	handler.clock = clock
+	handler.onTransportDeletion = func(sessionID string) {
+		deleted <- sessionID
+	}
+
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	// Pin to 2025-11-25 to avoid the discover probe: see
+	// TestStreamableSessionTimeout.
+	client := NewClient(testImpl, nil)
+	session, err := client.Connect(ctx, &StreamableClientTransport{Endpoint: httpServer.URL}, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatalf("client.Connect() failed: %v", err)
+	}
+	defer session.Close()
+	if _, err := session.ListTools(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client may observe a response before the handler has finished the
+	// POST, so wait for the idle timer to be armed before advancing the clock.
+	handler.mu.Lock()
+	info := handler.sessions[session.ID()]
+	handler.mu.Unlock()
+	for {
+		info.timerMu.Lock()
+		idle := info.refs == 0
+		info.timerMu.Unlock()
+		if idle {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for requests to finish")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	clock.Advance(timeout - time.Second)
+	select {
+	case id := <-deleted:
+		t.Fatalf("session %q deleted before timeout", id)
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case id := <-deleted:
+		if id != session.ID() {
+			t.Errorf("deleted session ID = %q, want %q", id, session.ID())
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for session cleanup")
+	}
+}
+
 // mustNotPanic is a helper to enforce that test handlers do not panic (see
 // issue #556).
 func mustNotPanic(t *testing.T, h http.Handler) http.Handler {
@@ -3802,3 +4160,945 @@ func TestStreamableServerRejectsDuplicateInFlightRequestID(t *testing.T) {
 	default:
 	}
 }
+
+func TestStreamableSessionContext(t *testing.T) {
+	type tenantKey struct{}
+	tenantOf := func(ctx context.Context) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	}
+
+	for _, stateless := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stateless=%t", stateless), func(t *testing.T) {
+			ctx := context.Background()
+			server := NewServer(testImpl, nil)
+			AddTool(server, &Tool{Name: "tenant"}, func(ctx context.Context, _ *CallToolRequest, _ any) (*CallToolResult, any, error) {
+				return &CallToolResult{Content: []Content{&TextContent{Text: tenantOf(ctx)}}}, nil, nil
+			})
+			var serverTenants sync.Map
+			handler := NewStreamableHTTPHandler(func(req *http.Request) *Server {
+				serverTenants.Store(tenantOf(req.Context()), true)
+				return server
+			}, &StreamableHTTPOptions{
+				Stateless: stateless,
+				SessionContext: func(ctx context.Context, req *http.Request) context.Context {
+					return context.WithValue(ctx, tenantKey{}, req.Header.Get("X-Tenant"))
+				},
+			})
+			httpServer := httptest.NewServer(handler)
+			defer httpServer.Close()
+
+			for _, tenant := range []string{"acme", "globex"} {
+				transport := &StreamableClientTransport{
+					Endpoint: httpServer.URL,
+					HTTPClient: &http.Client{
+						Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+							req = req.Clone(req.Context())
+							req.Header.Set("X-Tenant", tenant)
+							return http.DefaultTransport.RoundTrip(req)
+						}),
+					},
+				}
+				cs, err := NewClient(testImpl, nil).Connect(ctx, transport, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res, err := cs.CallTool(ctx, &CallToolParams{Name: "tenant"})
+				cs.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := res.Content[0].(*TextContent).Text; got != tenant {
+					t.Errorf("tool saw tenant %q, want %q", got, tenant)
+				}
+				if _, ok := serverTenants.Load(tenant); !ok {
+					t.Errorf("getServer did not see tenant %q", tenant)
+				}
+			}
+		})
+	}
+}
+
+func TestRawParams(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "audit"}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+		return nil, nil, nil
+	})
+	raws := make(chan json.RawMessage, 1)
+	server.AddReceivingMiddleware(func(next MethodHandler) MethodHandler {
+		return func(ctx context.Context, method string, req Request) (Result, error) {
+			if method == methodCallTool {
+				raws <- RawParams(ctx)
+			}
+			return next(ctx, method, req)
+		}
+	})
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{JSONResponse: true})
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	initialize := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+	}
+	sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialized := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+	}
+	if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Whitespace and key order that re-marshaling would not reproduce.
+	const params = `{ "arguments": {"z": 1,  "a": 2}, "name" : "audit" }`
+	body := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":` + params + `}`
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, httpServer.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	httpReq.Header.Set(sessionIDHeader, sessionID)
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("tools/call status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := string(<-raws); got != params {
+		t.Errorf("RawParams = %s, want %s", got, params)
+	}
+	if got := RawParams(ctx); got != nil {
+		t.Errorf("RawParams(ctx) outside a request = %s, want nil", got)
+	}
+}
+
+func TestStreamableErrorHTTPStatus(t *testing.T) {
+	ctx := context.Background()
+	for _, jsonResponse := range []bool{true, false} {
+		t.Run(fmt.Sprintf("jsonResponse=%t", jsonResponse), func(t *testing.T) {
+			server := NewServer(testImpl, nil)
+			AddTool(server, &Tool{Name: "broken"}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+				return nil, nil, &jsonrpc.Error{Code: jsonrpc.CodeInternalError, Message: "broken"}
+			})
+			handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{
+				JSONResponse:    jsonResponse,
+				ErrorHTTPStatus: map[int64]int{jsonrpc.CodeInvalidParams: http.StatusUnprocessableEntity},
+			})
+			httpServer := httptest.NewServer(mustNotPanic(t, handler))
+			defer httpServer.Close()
+
+			initialize := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+			}
+			sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			initialized := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+			}
+			if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+				t.Fatal(err)
+			}
+
+			wantStatus := http.StatusOK
+			if jsonResponse {
+				wantStatus = http.StatusUnprocessableEntity
+			}
+			tests := []struct {
+				name   string
+				msg    jsonrpc.Message
+				status int
+			}{
+				{"unknown tool", req(2, methodCallTool, &CallToolParams{Name: "missing"}), wantStatus},
+				// Unmapped codes keep the default status.
+				{"internal error", req(3, methodCallTool, &CallToolParams{Name: "broken"}), http.StatusOK},
+				{"success", req(4, methodListTools, &ListToolsParams{}), http.StatusOK},
+			}
+			for _, test := range tests {
+				r := streamableRequest{method: "POST", messages: []jsonrpc.Message{test.msg}}
+				out := make(chan jsonrpc.Message, 10)
+				_, status, _, err := r.do(ctx, httpServer.URL, sessionID, out)
+				if err != nil {
+					t.Fatalf("%s: %v", test.name, err)
+				}
+				if status != test.status {
+					t.Errorf("%s: status = %d, want %d", test.name, status, test.status)
+				}
+				if len(out) != 1 {
+					t.Errorf("%s: got %d messages, want 1", test.name, len(out))
+				}
+			}
+		})
+	}
+}
+
+func TestStreamableConcurrentGET(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "notify", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		// A notification with a detached context goes to the standalone stream;
+		// one made with the request context goes to the request's stream.
+		if err := req.Session.NotifyProgress(context.Background(), &ProgressNotificationParams{ProgressToken: "t", Message: "standalone"}); err != nil {
+			return nil, err
+		}
+		if err := req.Session.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: "t", Message: "request"}); err != nil {
+			return nil, err
+		}
+		return &CallToolResult{Content: []Content{}}, nil
+	})
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil)
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	initialize := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+	}
+	sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialized := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+	}
+	if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open the standalone stream, and keep it open.
+	getResp := openStandaloneStream(t, httpServer.URL, sessionID, nil)
+	defer getResp.Body.Close()
+
+	// A second standalone stream is rejected.
+	second := streamableRequest{method: "GET"}
+	_, status, body, err := second.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusConflict || !strings.Contains(string(body), "standalone SSE stream already open") {
+		t.Errorf("second standalone GET = %d %q, want %d", status, body, http.StatusConflict)
+	}
+
+	// POSTs are served on their own streams while the standalone stream is open.
+	call := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(2, methodCallTool, &CallToolParams{Name: "notify"})},
+	}
+	out := make(chan jsonrpc.Message, 10)
+	if _, _, _, err := call.do(ctx, httpServer.URL, sessionID, out); err != nil {
+		t.Fatal(err)
+	}
+	var got []jsonrpc.Message
+	for m := range out {
+		got = append(got, m)
+	}
+	want := []jsonrpc.Message{
+		req(0, notificationProgress, &ProgressNotificationParams{ProgressToken: "t", Message: "request"}),
+		resp(2, &CallToolResult{Content: []Content{}}, nil),
+	}
+	transform := cmpopts.AcyclicTransformer("jsonrpcid", func(id jsonrpc.ID) any { return id.Raw() })
+	if diff := cmp.Diff(want, got, transform); diff != "" {
+		t.Errorf("POST stream: unexpected messages (-want +got):\n%s", diff)
+	}
+
+	wantStandalone := req(0, notificationProgress, &ProgressNotificationParams{ProgressToken: "t", Message: "standalone"})
+	if diff := cmp.Diff(wantStandalone, nextStreamMessage(t, getResp.Body), transform); diff != "" {
+		t.Errorf("standalone stream: unexpected message (-want +got):\n%s", diff)
+	}
+}
+
+// openStandaloneStream opens the standalone SSE stream of the given session
+// with a GET request, overlaying headers on the default headers. The stream
+// is closed when the test ends, if not before.
+func openStandaloneStream(t *testing.T, serverURL, sessionID string, headers http.Header) *http.Response {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(sessionIDHeader, sessionID)
+	maps.Copy(req.Header, headers)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("standalone GET status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	return resp
+}
+
+// nextStreamMessage reads the next JSON-RPC message from an SSE stream.
+func nextStreamMessage(t *testing.T, r io.Reader) jsonrpc.Message {
+	t.Helper()
+	for evt, err := range scanEvents(r) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evt.Name != "" && evt.Name != "message" {
+			continue
+		}
+		msg, err := jsonrpc2.DecodeMessage(evt.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	t.Fatal("stream ended without a message")
+	return nil
+}
+
+func TestStreamableStandaloneResourceUpdated(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		headers http.Header
+	}{
+		{"no Last-Event-ID", nil},
+		{"empty Last-Event-ID", http.Header{lastEventIDHeader: {""}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			noop := func(context.Context, *SubscribeRequest) error { return nil }
+			server := NewServer(testImpl, &ServerOptions{
+				SubscribeHandler:   noop,
+				UnsubscribeHandler: func(context.Context, *UnsubscribeRequest) error { return nil },
+			})
+			const uri = "file:///info.txt"
+			server.AddResource(&Resource{Name: "info", URI: uri}, func(context.Context, *ReadResourceRequest) (*ReadResourceResult, error) {
+				return &ReadResourceResult{}, nil
+			})
+			handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{EventStore: NewMemoryEventStore(nil)})
+			httpServer := httptest.NewServer(mustNotPanic(t, handler))
+			defer httpServer.Close()
+
+			initialize := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+			}
+			sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			initialized := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+			}
+			if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+				t.Fatal(err)
+			}
+
+			getResp := openStandaloneStream(t, httpServer.URL, sessionID, test.headers)
+			defer getResp.Body.Close()
+
+			subscribe := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(2, methodSubscribe, &SubscribeParams{URI: uri})},
+			}
+			if _, status, _, err := subscribe.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil || status != http.StatusOK {
+				t.Fatalf("subscribe: status %d, err %v", status, err)
+			}
+			if err := server.ResourceUpdated(ctx, &ResourceUpdatedNotificationParams{URI: uri}); err != nil {
+				t.Fatal(err)
+			}
+
+			want := req(0, notificationResourceUpdated, &ResourceUpdatedNotificationParams{URI: uri})
+			transform := cmpopts.AcyclicTransformer("jsonrpcid", func(id jsonrpc.ID) any { return id.Raw() })
+			if diff := cmp.Diff(want, nextStreamMessage(t, getResp.Body), transform); diff != "" {
+				t.Errorf("standalone stream: unexpected message (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStreamableInfoHandler(t *testing.T) {
+	impl := &Implementation{Name: "info-server", Title: "Info", Version: "v1.2.3", WebsiteURL: "https://example.com"}
+	server := NewServer(impl, &ServerOptions{Instructions: "be nice"})
+	AddTool(server, &Tool{Name: "greet"}, sayHi)
+
+	for _, stateless := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stateless=%t", stateless), func(t *testing.T) {
+			handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{Stateless: stateless})
+			httpServer := httptest.NewServer(handler.InfoHandler())
+			defer httpServer.Close()
+
+			resp, err := http.Get(httpServer.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var got DiscoverResult
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(impl, got.ServerInfo); diff != "" {
+				t.Errorf("serverInfo mismatch (-want +got):\n%s", diff)
+			}
+			if got.Capabilities == nil || got.Capabilities.Tools == nil {
+				t.Errorf("capabilities = %+v, want tools", got.Capabilities)
+			}
+			if got.Instructions != "be nice" {
+				t.Errorf("instructions = %q, want %q", got.Instructions, "be nice")
+			}
+			if supportsNew := slices.Contains(got.SupportedVersions, protocolVersion20260728); supportsNew != stateless {
+				t.Errorf("supportedVersions = %v: includes %s = %t, want %t", got.SupportedVersions, protocolVersion20260728, supportsNew, stateless)
+			}
+
+			resp, err = http.Post(httpServer.URL, "application/json", strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+			}
+		})
+	}
+
+	t.Run("protection", func(t *testing.T) {
+		type tenantKey struct{}
+		var gotTenant any
+		handler := NewStreamableHTTPHandler(func(req *http.Request) *Server {
+			gotTenant = req.Context().Value(tenantKey{})
+			return server
+		}, &StreamableHTTPOptions{
+			SessionContext: func(ctx context.Context, req *http.Request) context.Context {
+				return context.WithValue(ctx, tenantKey{}, "acme")
+			},
+		})
+		httpServer := httptest.NewServer(handler.InfoHandler())
+		defer httpServer.Close()
+
+		resp, err := http.Get(httpServer.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if gotTenant != "acme" {
+			t.Errorf("getServer saw tenant %v, want the SessionContext value", gotTenant)
+		}
+
+		// A localhost server rejects requests with a non-local Host header.
+		req, err := http.NewRequest(http.MethodGet, httpServer.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = "attacker.example"
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("GET with Host %q: status = %d, want %d", req.Host, resp.StatusCode, http.StatusForbidden)
+		}
+	})
+}
+
+func TestStreamableBatchCancellation(t *testing.T) {
+	ctx := context.Background()
+	tr := &StreamableServerTransport{}
+	conn, err := tr.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sc := conn.(*streamableServerConn)
+
+	// Nothing reads from the connection, so publishing blocks once the
+	// incoming buffer is full.
+	const n = 30
+	var batch []string
+	for i := range n {
+		batch = append(batch, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, i))
+	}
+	body := "[" + strings.Join(batch, ",") + "]"
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	httpReq := httptest.NewRequestWithContext(reqCtx, http.MethodPost, "/", strings.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		tr.ServeHTTP(httptest.NewRecorder(), httpReq)
+	}()
+	for len(sc.incoming) < cap(sc.incoming) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The client disconnects mid-batch.
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("servePOST did not return after the request was cancelled")
+	}
+
+	sc.mu.Lock()
+	pending := len(sc.requestStreams)
+	sc.mu.Unlock()
+	if published := len(sc.incoming); pending != published {
+		t.Errorf("tracking %d requests after cancellation, want the %d published ones", pending, published)
+	}
+}
+
+func TestStreamableNilServer(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	getServer := func(req *http.Request) *Server {
+		if req.URL.Path == "/known" {
+			return server
+		}
+		return nil
+	}
+	initReq := req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})
+
+	for _, stateless := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stateless=%t", stateless), func(t *testing.T) {
+			handler := NewStreamableHTTPHandler(getServer, &StreamableHTTPOptions{Stateless: stateless})
+			httpServer := httptest.NewServer(mustNotPanic(t, handler))
+			defer httpServer.Close()
+
+			for path, want := range map[string]int{"/known": http.StatusOK, "/unknown": http.StatusNotFound} {
+				r := streamableRequest{method: "POST", messages: []jsonrpc.Message{initReq}}
+				_, status, _, err := r.do(ctx, httpServer.URL+path, "", make(chan jsonrpc.Message, 1))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if status != want {
+					t.Errorf("POST %s: status = %d, want %d", path, status, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamableWithStandaloneStream(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "sample", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		res, err := req.Session.CreateMessage(WithStandaloneStream(ctx), &CreateMessageParams{})
+		if err != nil {
+			return nil, err
+		}
+		return &CallToolResult{Content: []Content{&TextContent{Text: res.Model}}}, nil
+	})
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil)
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	initialize := streamableRequest{
+		method: "POST",
+		messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{
+			ProtocolVersion: protocolVersion20250618,
+			Capabilities:    &ClientCapabilities{Sampling: &SamplingCapabilities{}},
+		})},
+	}
+	sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialized := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+	}
+	if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+	getResp := openStandaloneStream(t, httpServer.URL, sessionID, nil)
+	defer getResp.Body.Close()
+
+	// Call the tool. Its sampling request arrives on the standalone stream.
+	call := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(2, methodCallTool, &CallToolParams{Name: "sample"})},
+	}
+	out := make(chan jsonrpc.Message, 10)
+	callDone := make(chan error, 1)
+	go func() {
+		_, _, _, err := call.do(ctx, httpServer.URL, sessionID, out)
+		callDone <- err
+	}()
+	sampling, ok := nextStreamMessage(t, getResp.Body).(*jsonrpc.Request)
+	if !ok || sampling.Method != methodCreateMessage {
+		t.Fatalf("standalone stream: got %v, want %s request", sampling, methodCreateMessage)
+	}
+
+	reply := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{resp(sampling.ID.Raw().(int64), &CreateMessageResult{Model: "aModel", Content: &TextContent{}}, nil)},
+	}
+	if _, _, _, err := reply.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-callDone; err != nil {
+		t.Fatal(err)
+	}
+	var got []jsonrpc.Message
+	for m := range out {
+		got = append(got, m)
+	}
+	want := []jsonrpc.Message{resp(2, &CallToolResult{Content: []Content{&TextContent{Text: "aModel"}}}, nil)}
+	transform := cmpopts.AcyclicTransformer("jsonrpcid", func(id jsonrpc.ID) any { return id.Raw() })
+	if diff := cmp.Diff(want, got, transform); diff != "" {
+		t.Errorf("POST stream: unexpected messages (-want +got):\n%s", diff)
+	}
+}
+
+func TestStreamableSamplingWithoutStandaloneStream(t *testing.T) {
+	// With JSON responses, a sampling request made by a tool can't be sent on
+	// the tool call's response stream. If the client hasn't opened the
+	// standalone stream, the request must fail rather than wait forever.
+	// Notifications, by contrast, are held until the client opens it.
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	server.AddTool(&Tool{Name: "sample", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		if err := req.Session.NotifyProgress(ctx, &ProgressNotificationParams{ProgressToken: "sample", Progress: 1}); err != nil {
+			return nil, fmt.Errorf("NotifyProgress: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		_, err := req.Session.CreateMessage(ctx, &CreateMessageParams{})
+		return nil, err
+	})
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{JSONResponse: true})
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	initialize := streamableRequest{
+		method: "POST",
+		messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{
+			ProtocolVersion: protocolVersion20250618,
+			Capabilities:    &ClientCapabilities{Sampling: &SamplingCapabilities{}},
+		})},
+	}
+	sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialized := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+	}
+	if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	call := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(2, methodCallTool, &CallToolParams{Name: "sample"})},
+	}
+	out := make(chan jsonrpc.Message, 10)
+	if _, _, _, err := call.do(ctx, httpServer.URL, sessionID, out); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("tool call took %v, want it to fail promptly", d)
+	}
+	var got []jsonrpc.Message
+	for m := range out {
+		got = append(got, m)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d messages, want 1", len(got))
+	}
+	if r, ok := got[0].(*jsonrpc.Response); !ok || r.Error == nil || !strings.Contains(r.Error.Error(), "standalone SSE stream") {
+		t.Errorf("tool call response = %v, want an error about the standalone SSE stream", got[0])
+	}
+
+	getResp := openStandaloneStream(t, httpServer.URL, sessionID, nil)
+	defer getResp.Body.Close()
+	if n, ok := nextStreamMessage(t, getResp.Body).(*jsonrpc.Request); !ok || n.Method != notificationProgress {
+		t.Errorf("standalone stream: got %v, want the held %s notification", n, notificationProgress)
+	}
+}
+
+func TestStreamableNotificationsBeforeGET(t *testing.T) {
+	// Notifications sent before the client opens the standalone SSE stream are
+	// delivered once it does, with or without an event store.
+	for _, test := range []struct {
+		name  string
+		store EventStore
+	}{
+		{"no event store", nil},
+		{"event store", NewMemoryEventStore(nil)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			notified := make(chan error, 1)
+			server := NewServer(testImpl, &ServerOptions{
+				InitializedHandler: func(_ context.Context, req *InitializedRequest) {
+					notified <- req.Session.NotifyProgress(context.Background(), &ProgressNotificationParams{ProgressToken: "early", Progress: 1})
+				},
+			})
+			handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{EventStore: test.store})
+			httpServer := httptest.NewServer(mustNotPanic(t, handler))
+			defer httpServer.Close()
+
+			initialize := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+			}
+			sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+			if err != nil {
+				t.Fatal(err)
+			}
+			initialized := streamableRequest{
+				method:   "POST",
+				messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+			}
+			if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-notified; err != nil {
+				t.Fatalf("NotifyProgress before GET: %v", err)
+			}
+
+			getResp := openStandaloneStream(t, httpServer.URL, sessionID, nil)
+			defer getResp.Body.Close()
+			got, ok := nextStreamMessage(t, getResp.Body).(*jsonrpc.Request)
+			if !ok || got.Method != notificationProgress {
+				t.Fatalf("standalone stream: got %v, want %s notification", got, notificationProgress)
+			}
+		})
+	}
+}
+
+func TestStreamablePendingMessagesLimit(t *testing.T) {
+	// Without an event store, messages held for the standalone SSE stream are
+	// bounded in size.
+	ctx := context.Background()
+	notified := make(chan []error, 1)
+	server := NewServer(testImpl, &ServerOptions{
+		InitializedHandler: func(_ context.Context, req *InitializedRequest) {
+			notify := func(token string, message string) error {
+				return req.Session.NotifyProgress(context.Background(), &ProgressNotificationParams{ProgressToken: token, Message: message})
+			}
+			notified <- []error{
+				notify("large", strings.Repeat("x", maxPendingBytes)),
+				notify("small", ""),
+			}
+		},
+	})
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, nil)
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	initialize := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20250618})},
+	}
+	sessionID, _, _, err := initialize.do(ctx, httpServer.URL, "", make(chan jsonrpc.Message, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialized := streamableRequest{
+		method:   "POST",
+		messages: []jsonrpc.Message{req(0, notificationInitialized, &InitializedParams{})},
+	}
+	if _, _, _, err := initialized.do(ctx, httpServer.URL, sessionID, make(chan jsonrpc.Message, 10)); err != nil {
+		t.Fatal(err)
+	}
+	errs := <-notified
+	if !errors.Is(errs[0], jsonrpc2.ErrRejected) {
+		t.Errorf("large notification: got error %v, want rejection", errs[0])
+	}
+	if errs[1] != nil {
+		t.Errorf("small notification: %v", errs[1])
+	}
+
+	getResp := openStandaloneStream(t, httpServer.URL, sessionID, nil)
+	defer getResp.Body.Close()
+	got, ok := nextStreamMessage(t, getResp.Body).(*jsonrpc.Request)
+	if !ok || got.Method != notificationProgress || !strings.Contains(string(got.Params), `"small"`) {
+		t.Fatalf("standalone stream: got %v, want the small notification", got)
+	}
+}
+
+func TestStreamableMaxSessions(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	deleted := make(chan string, 1)
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{MaxSessions: 1})
+	handler.onTransportDeletion = func(sessionID string) { deleted <- sessionID }
+	defer handler.closeAll()
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	// Pin to 2025-11-25 to avoid the extra session created by the
+	// server/discover probe (see TestStreamableSessionTimeout).
+	connect := func() (*ClientSession, error) {
+		client := NewClient(testImpl, nil)
+		return client.Connect(ctx, &StreamableClientTransport{Endpoint: httpServer.URL, DisableStandaloneSSE: true}, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	}
+	cs, err := connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A request creating a second session is rejected.
+	body, err := json.Marshal(req(1, methodInitialize, &InitializeParams{ProtocolVersion: protocolVersion20251125}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, httpServer.URL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("second session: got status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	// Requests to the existing session still succeed.
+	if _, err := cs.ListTools(ctx, nil); err != nil {
+		t.Errorf("ListTools: %v", err)
+	}
+
+	// Closing the session frees its slot.
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-deleted
+	cs2, err := connect()
+	if err != nil {
+		t.Fatalf("connecting after close: %v", err)
+	}
+	cs2.Close()
+}
+
+func TestStreamableClientResumeSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server := NewServer(testImpl, nil)
+	handler := NewStreamableHTTPHandler(func(*http.Request) *Server { return server }, &StreamableHTTPOptions{EventStore: NewMemoryEventStore(nil)})
+	defer handler.closeAll()
+	httpServer := httptest.NewServer(mustNotPanic(t, handler))
+	defer httpServer.Close()
+
+	newClient := func(progress chan<- string) *Client {
+		return NewClient(testImpl, &ClientOptions{
+			ProgressNotificationHandler: func(_ context.Context, req *ProgressNotificationClientRequest) {
+				progress <- req.Params.Message
+			},
+		})
+	}
+
+	// The first client receives a notification on the standalone stream, then
+	// loses its connection without ending the session, as if it crashed.
+	progress1 := make(chan string, 10)
+	var crashed atomic.Bool
+	crashClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if crashed.Load() {
+			return nil, errors.New("crashed")
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	cs1, err := newClient(progress1).Connect(ctx, &StreamableClientTransport{Endpoint: httpServer.URL, HTTPClient: crashClient, MaxRetries: -1}, &ClientSessionOptions{protocolVersion: protocolVersion20251125})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs1.Close() // the DELETE request fails after the crash
+	ss := slices.Collect(server.Sessions())[0]
+	notify := func(msg string) {
+		t.Helper()
+		if err := ss.NotifyProgress(context.Background(), &ProgressNotificationParams{ProgressToken: "p", Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notify("first")
+	if got := <-progress1; got != "first" {
+		t.Fatalf("first client got %q, want %q", got, "first")
+	}
+	sessionID, lastEventID, initRes := cs1.ID(), cs1.LastEventID(), cs1.InitializeResult()
+	if lastEventID == "" {
+		t.Fatal("no last event ID after receiving an event")
+	}
+	crashed.Store(true)
+	httpServer.CloseClientConnections()
+
+	// Wait for the server to release the standalone stream.
+	handler.mu.Lock()
+	conn := handler.sessions[sessionID].transport.connection
+	handler.mu.Unlock()
+	for {
+		conn.mu.Lock()
+		s := conn.streams[""]
+		conn.mu.Unlock()
+		s.mu.Lock()
+		released := s.w == nil
+		s.mu.Unlock()
+		if released {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// While the client is away, the server sends another notification.
+	notify("second")
+
+	// The restarted client resumes the session, and receives the notification
+	// it missed, but not the one it already saw.
+	progress2 := make(chan string, 10)
+	cs2, err := newClient(progress2).Connect(ctx, &StreamableClientTransport{
+		Endpoint:    httpServer.URL,
+		SessionID:   sessionID,
+		LastEventID: lastEventID,
+	}, &ClientSessionOptions{InitializeResult: initRes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs2.Close()
+	if got := cs2.ID(); got != sessionID {
+		t.Errorf("resumed session ID = %q, want %q", got, sessionID)
+	}
+	select {
+	case got := <-progress2:
+		if got != "second" {
+			t.Errorf("resumed client got %q, want %q", got, "second")
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the missed notification")
+	}
+	if _, err := cs2.ListTools(ctx, nil); err != nil {
+		t.Errorf("ListTools on resumed session: %v", err)
+	}
+	if n := len(slices.Collect(server.Sessions())); n != 1 {
+		t.Errorf("server has %d sessions, want 1", n)
+	}
+}
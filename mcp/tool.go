@@ -7,7 +7,12 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/google/jsonschema-go/jsonschema"
@@ -43,9 +48,16 @@ type ToolHandler func(context.Context, *CallToolRequest) (*CallToolResult, error
 //   - If the Out type is not the empty interface [any], it provides the
 //     default output schema for the tool (which again may be overridden in
 //     [AddTool]).
-//   - The Out value is used to populate result.StructuredOutput.
+//   - The Out value is used to populate [CallToolResult.StructuredContent],
+//     after validating it against the output schema. If Out is [any] and the
+//     handler returns a nil output, StructuredContent is left unset; use this
+//     for tools without structured output. (An Out of struct{} always
+//     produces an empty object.)
 //   - If [CallToolResult.Content] is unset, it is populated with the JSON
-//     content of the output.
+//     content of the output. As an exception, if the tool has a non-JSON
+//     content MIME type (see [Tool.SetContentMIMEType]), such as
+//     "text/markdown", and the output is a JSON string, the content is the
+//     string itself, without JSON quoting or escaping.
 //   - An error result is treated as a tool error, rather than a protocol
 //     error, and is therefore packed into CallToolResult.Content, with
 //     [IsError] set.
@@ -62,6 +74,24 @@ type serverTool struct {
 	handler ToolHandler
 }
 
+// A ToolOutputError reports that the output of a tool added with [AddTool]
+// does not match the tool's output schema. This indicates a bug in the
+// server, rather than invalid input from the client: invalid arguments are
+// instead reported in a [CallToolResult] with IsError set.
+//
+// The server logs the error to [ServerOptions.Logger], and the client receives
+// it as an internal error.
+type ToolOutputError struct {
+	Tool string // the name of the tool
+	Err  error  // the schema validation error, describing the mismatch
+}
+
+func (e *ToolOutputError) Error() string {
+	return fmt.Sprintf("validating tool output of %q: %v", e.Tool, e.Err)
+}
+
+func (e *ToolOutputError) Unwrap() error { return e.Err }
+
 // applySchema validates whether data is valid JSON according to the provided
 // schema, after applying schema defaults.
 //
@@ -141,6 +171,389 @@ func applySchema(data json.RawMessage, resolved *jsonschema.Resolved, forOutput
 	return out, nil
 }
 
+// ValidateToolArguments validates args against the input schema of tool,
+// using the same rules as a server does for tools added with [AddTool]:
+// schema defaults are applied, then the result is validated against the JSON
+// Schema 2020-12 semantics of the github.com/google/jsonschema-go package.
+//
+// Clients can use it to check arguments before calling a tool, avoiding a
+// round trip for invalid input. The error message matches the one reported in
+// the tool's error result.
+//
+// The args value may be a [json.RawMessage] or any value that marshals to a
+// JSON object. ValidateToolArguments returns the arguments with defaults
+// applied. If tool has no input schema, the arguments are returned as is.
+func ValidateToolArguments(tool *Tool, args any) (json.RawMessage, error) {
+	data, ok := args.(json.RawMessage)
+	if !ok && args != nil {
+		var err error
+		data, err = json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling arguments: %w", err)
+		}
+	}
+	resolved, err := resolveInputSchema(tool)
+	if err != nil {
+		return nil, err
+	}
+	if resolved == nil {
+		return data, nil
+	}
+	data, err = applySchema(data, resolved, false)
+	if err != nil {
+		return nil, fmt.Errorf("validating \"arguments\": %v", err)
+	}
+	return data, nil
+}
+
+// resolveInputSchema resolves the input schema of tool, whatever its Go type.
+// It returns nil if the tool has no input schema.
+func resolveInputSchema(tool *Tool) (*jsonschema.Resolved, error) {
+	if tool.InputSchema == nil {
+		return nil, nil
+	}
+	var schema *jsonschema.Schema
+	if err := remarshal(tool.InputSchema, &schema); err != nil {
+		return nil, fmt.Errorf("tool %q: invalid input schema: %w", tool.Name, err)
+	}
+	if schema == nil {
+		return nil, nil
+	}
+	resolved, err := schema.Resolve(&jsonschema.ResolveOptions{ValidateDefaults: true})
+	if err != nil {
+		return nil, fmt.Errorf("tool %q: resolving input schema: %w", tool.Name, err)
+	}
+	return resolved, nil
+}
+
+// requiredClientCapabilitiesKey is the _meta key listing the client
+// capabilities that a [Tool] needs while it runs.
+const requiredClientCapabilitiesKey = MetaKeyPrefix + "requiredClientCapabilities"
+
+// Client capabilities that a tool may require. See
+// [Tool.SetRequiredClientCapabilities].
+const (
+	ClientCapabilitySampling    = "sampling"
+	ClientCapabilityElicitation = "elicitation"
+)
+
+// SetRequiredClientCapabilities records that the tool needs the given client
+// capabilities, such as [ClientCapabilitySampling], while it runs.
+//
+// A server omits the tool from tools/list results sent to clients that lack
+// any of the capabilities, since calls to it would always fail. The list is
+// also visible to clients in the tool's _meta. Unknown capability names are
+// ignored by the server.
+func (t *Tool) SetRequiredClientCapabilities(caps ...string) {
+	if t.Meta == nil {
+		t.Meta = Meta{}
+	}
+	t.Meta[requiredClientCapabilitiesKey] = caps
+}
+
+// GetRequiredClientCapabilities returns the client capabilities recorded by
+// [Tool.SetRequiredClientCapabilities].
+func (t *Tool) GetRequiredClientCapabilities() []string {
+	switch v := t.Meta[requiredClientCapabilitiesKey].(type) {
+	case []string:
+		return v
+	case []any:
+		// After unmarshaling, the value is a []any.
+		var caps []string
+		for _, c := range v {
+			if s, ok := c.(string); ok {
+				caps = append(caps, s)
+			}
+		}
+		return caps
+	}
+	return nil
+}
+
+// supportsDryRunKey is the _meta key marking a [Tool] that supports dry runs.
+const supportsDryRunKey = MetaKeyPrefix + "supportsDryRun"
+
+// SetSupportsDryRun records whether the tool supports dry runs, as requested
+// by [CallToolParams.SetDryRun]. The handler of such a tool must check
+// [CallToolParamsRaw.GetDryRun] and, if it reports true, describe what the
+// call would do without doing it. This is particularly useful for tools
+// annotated with [ToolAnnotations.DestructiveHint].
+//
+// A server rejects dry runs of tools that do not support them, so that they
+// are never executed by mistake.
+func (t *Tool) SetSupportsDryRun(supported bool) {
+	if !supported {
+		delete(t.Meta, supportsDryRunKey)
+		return
+	}
+	if t.Meta == nil {
+		t.Meta = Meta{}
+	}
+	t.Meta[supportsDryRunKey] = true
+}
+
+// GetSupportsDryRun reports whether the tool supports dry runs. See
+// [Tool.SetSupportsDryRun].
+func (t *Tool) GetSupportsDryRun() bool {
+	supported, _ := t.Meta[supportsDryRunKey].(bool)
+	return supported
+}
+
+// structuredContentOnlyKey is the _meta key marking a [Tool] whose results
+// carry structured content without a text copy.
+const structuredContentOnlyKey = MetaKeyPrefix + "structuredContentOnly"
+
+// SetStructuredContentOnly records whether results of the tool should carry
+// only structured content. By default, [AddTool] fills in
+// [CallToolResult.Content] with the JSON text of the structured output when
+// the handler leaves it unset. For a tool with this flag set, Content is left
+// empty instead, avoiding a noisy duplicate for clients that understand
+// structured content. Clients can render such results with
+// [CallToolResult.DisplayContent].
+//
+// The flag must be set before the tool is added to a server. It is visible to
+// clients in the tool's _meta.
+func (t *Tool) SetStructuredContentOnly(only bool) {
+	if !only {
+		delete(t.Meta, structuredContentOnlyKey)
+		return
+	}
+	if t.Meta == nil {
+		t.Meta = Meta{}
+	}
+	t.Meta[structuredContentOnlyKey] = true
+}
+
+// GetStructuredContentOnly reports whether results of the tool carry only
+// structured content. See [Tool.SetStructuredContentOnly].
+func (t *Tool) GetStructuredContentOnly() bool {
+	only, _ := t.Meta[structuredContentOnlyKey].(bool)
+	return only
+}
+
+// contentMIMETypeKey is the _meta key recording the MIME type of the text
+// content of a [Tool]'s results.
+const contentMIMETypeKey = MetaKeyPrefix + "contentMimeType"
+
+// SetContentMIMEType records the MIME type of the text content of the tool's
+// results, such as "text/markdown", so that clients can render it
+// appropriately. An empty mimeType removes it.
+//
+// For a tool added with [AddTool], each [TextContent] block of a result that
+// does not have a MIME type (see [TextContent.MIMEType]) is given this one,
+// unless the result reports an error.
+// When AddTool fills in the text content from the structured output, the
+// text is the output itself if the output is a string and mimeType is not a
+// JSON type; otherwise it is the output's JSON, with MIME type
+// "application/json".
+//
+// The MIME type must be set before the tool is added to a server. It is
+// visible to clients in the tool's _meta.
+func (t *Tool) SetContentMIMEType(mimeType string) {
+	if mimeType == "" {
+		delete(t.Meta, contentMIMETypeKey)
+		return
+	}
+	if t.Meta == nil {
+		t.Meta = Meta{}
+	}
+	t.Meta[contentMIMETypeKey] = mimeType
+}
+
+// GetContentMIMEType returns the MIME type of the text content of the tool's
+// results, or "" if it is unspecified. See [Tool.SetContentMIMEType].
+func (t *Tool) GetContentMIMEType() string {
+	mimeType, _ := t.Meta[contentMIMETypeKey].(string)
+	return mimeType
+}
+
+// isJSONMIMEType reports whether mimeType denotes JSON text.
+func isJSONMIMEType(mimeType string) bool {
+	base, _, _ := strings.Cut(mimeType, ";")
+	base = strings.ToLower(strings.TrimSpace(base))
+	return base == "application/json" || strings.HasSuffix(base, "+json")
+}
+
+// fallbackTextContent returns the text content that [AddTool] adds for the
+// structured output outJSON, for a tool whose text content has the given MIME
+// type (see [Tool.SetContentMIMEType]).
+func fallbackTextContent(outJSON json.RawMessage, mimeType string) *TextContent {
+	if mimeType == "" {
+		return &TextContent{Text: string(outJSON)}
+	}
+	if !isJSONMIMEType(mimeType) {
+		var text string
+		if err := json.Unmarshal(outJSON, &text); err == nil {
+			return &TextContent{Text: text, Meta: Meta{contentMIMETypeMetaKey: mimeType}}
+		}
+		mimeType = "application/json"
+	}
+	return &TextContent{Text: string(outJSON), Meta: Meta{contentMIMETypeMetaKey: mimeType}}
+}
+
+// withContentMIMEType returns a copy of content in which each [TextContent]
+// without a MIME type is replaced by one with the given MIME type. It returns
+// content itself if there is no such TextContent.
+func withContentMIMEType(content []Content, mimeType string) []Content {
+	cloned := false
+	for i, c := range content {
+		tc, ok := c.(*TextContent)
+		if !ok || tc == nil || tc.MIMEType() != "" {
+			continue
+		}
+		cp := *tc
+		cp.Meta = maps.Clone(tc.Meta)
+		if cp.Meta == nil {
+			cp.Meta = Meta{}
+		}
+		cp.Meta[contentMIMETypeMetaKey] = mimeType
+		if !cloned {
+			content = slices.Clone(content)
+			cloned = true
+		}
+		content[i] = &cp
+	}
+	return content
+}
+
+// continuationKey is the _meta key for a [ToolContinuation] in a
+// "tools/call" result.
+const continuationKey = MetaKeyPrefix + "continuation"
+
+// A ToolContinuation describes the next step of a multi-step tool flow, such
+// as a wizard that gathers input over several calls. A tool returns one by
+// calling [CallToolResult.SetContinuation].
+//
+// Continuations are advisory: they are carried in the result's _meta, so
+// clients that don't understand them see an ordinary result. Clients that do
+// can follow them with [ClientSession.CallToolFlow].
+type ToolContinuation struct {
+	// Tool is the name of the tool to call next.
+	Tool string `json:"tool"`
+	// Arguments holds arguments for the next call, such as a token
+	// identifying the state of the flow. The client may add to them, for
+	// example with input from the user.
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// Message, if set, describes the next step, for display to the user.
+	Message string `json:"message,omitempty"`
+}
+
+// SetContinuation records that the flow the result belongs to continues with
+// the step described by c.
+func (r *CallToolResult) SetContinuation(c ToolContinuation) {
+	if r.Meta == nil {
+		r.Meta = Meta{}
+	}
+	r.Meta[continuationKey] = c
+}
+
+// GetContinuation returns the next step of the flow the result belongs to,
+// if any. If it reports false, the flow is complete.
+func (r *CallToolResult) GetContinuation() (ToolContinuation, bool) {
+	switch v := r.Meta[continuationKey].(type) {
+	case nil:
+		return ToolContinuation{}, false
+	case ToolContinuation:
+		return v, true
+	default:
+		// After unmarshaling, the value is a map[string]any.
+		var c ToolContinuation
+		if err := remarshal(v, &c); err != nil || c.Tool == "" {
+			return ToolContinuation{}, false
+		}
+		return c, true
+	}
+}
+
+// A CallToolResultBuilder builds a [CallToolResult].
+// Create one with [NewCallToolResult], chain calls to add content, and
+// finish with [CallToolResultBuilder.Result]:
+//
+//	return mcp.NewCallToolResult().Text("done").Structured(out).Result(), nil
+//
+// Using a builder is optional; a CallToolResult literal works just as well.
+type CallToolResultBuilder struct {
+	res CallToolResult
+	err error
+}
+
+// NewCallToolResult returns a builder for an empty [CallToolResult].
+func NewCallToolResult() *CallToolResultBuilder {
+	return &CallToolResultBuilder{}
+}
+
+// Text appends a [TextContent] holding s.
+func (b *CallToolResultBuilder) Text(s string) *CallToolResultBuilder {
+	return b.Content(&TextContent{Text: s})
+}
+
+// Image appends an [ImageContent] with the given raw (not base64-encoded)
+// data and MIME type.
+func (b *CallToolResultBuilder) Image(data []byte, mimeType string) *CallToolResultBuilder {
+	return b.Content(&ImageContent{Data: data, MIMEType: mimeType})
+}
+
+// Audio appends an [AudioContent] with the given raw (not base64-encoded)
+// data and MIME type.
+func (b *CallToolResultBuilder) Audio(data []byte, mimeType string) *CallToolResultBuilder {
+	return b.Content(&AudioContent{Data: data, MIMEType: mimeType})
+}
+
+// Content appends arbitrary content.
+func (b *CallToolResultBuilder) Content(c ...Content) *CallToolResultBuilder {
+	b.res.Content = append(b.res.Content, c...)
+	return b
+}
+
+// Structured sets the structured content of the result.
+//
+// When the result is returned from a handler added with [AddTool] and has no
+// other content, the SDK adds the JSON of v as text content, as usual.
+func (b *CallToolResultBuilder) Structured(v any) *CallToolResultBuilder {
+	b.res.StructuredContent = v
+	return b
+}
+
+// Error marks the result as a tool error, as with [CallToolResult.SetError].
+// The error is applied when the result is built, so content added before or
+// after Error is kept as the user-facing message; if there is none, the
+// error text is used. Error(nil) does nothing.
+func (b *CallToolResultBuilder) Error(err error) *CallToolResultBuilder {
+	if err != nil {
+		b.err = err
+	}
+	return b
+}
+
+// Result returns the built result. Each call returns a new result, so a
+// builder may be used as a template.
+func (b *CallToolResultBuilder) Result() *CallToolResult {
+	res := b.res
+	res.Content = slices.Clone(b.res.Content)
+	if b.err != nil {
+		res.SetError(b.err)
+	}
+	return &res
+}
+
+// clientSupportsTool reports whether a client with the given capabilities
+// satisfies the required client capabilities of t.
+func clientSupportsTool(caps *ClientCapabilities, t *Tool) bool {
+	for _, c := range t.GetRequiredClientCapabilities() {
+		switch c {
+		case ClientCapabilitySampling:
+			if caps == nil || caps.Sampling == nil {
+				return false
+			}
+		case ClientCapabilityElicitation:
+			if caps == nil || caps.Elicitation == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // isObjectJSON reports whether data is a JSON object (i.e., starts with '{'
 // after any leading whitespace). Returns false for arrays, primitives, null,
 // or empty input.
@@ -158,6 +571,94 @@ func isObjectJSON(data json.RawMessage) bool {
 	return false
 }
 
+// Validate reports whether t is a complete and self-consistent tool
+// definition, returning an error joining every problem found. It checks that:
+//
+//   - Name is a valid tool name: 1 to 128 ASCII letters, digits, '_', '-' or
+//     '.'.
+//   - InputSchema is set, and is a valid JSON Schema of type "object".
+//   - OutputSchema, if set, is a valid JSON Schema.
+//   - Each icon has an http, https or data URI as its source, sizes of the
+//     form "48x48" or "any", and a theme, if any, of "light" or "dark".
+//   - The annotations do not mark a read-only tool as destructive.
+//
+// Title and Annotations.Title may differ: [Tool.DisplayName] prefers Title.
+//
+// [Server.AddTool] panics only for a subset of these problems, so that
+// existing servers keep working. Validate lets code that builds tools
+// programmatically, for example from a database catalog, check them up front.
+func (t *Tool) Validate() error {
+	var errs []error
+	if err := validateToolName(t.Name); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkTool(t); err != nil {
+		errs = append(errs, err)
+	} else {
+		if err := checkSchema(t.InputSchema, true); err != nil {
+			errs = append(errs, fmt.Errorf("invalid input schema: %v", err))
+		}
+		if t.OutputSchema != nil {
+			if err := checkSchema(t.OutputSchema, false); err != nil {
+				errs = append(errs, fmt.Errorf("invalid output schema: %v", err))
+			}
+		}
+	}
+	for i, icon := range t.Icons {
+		if err := checkIcon(icon); err != nil {
+			errs = append(errs, fmt.Errorf("icon %d: %v", i, err))
+		}
+	}
+	if a := t.Annotations; a != nil && a.ReadOnlyHint && a.DestructiveHint != nil && *a.DestructiveHint {
+		errs = append(errs, errors.New("annotations mark a read-only tool as destructive"))
+	}
+	return errors.Join(errs...)
+}
+
+// checkSchema reports whether schema, which may be of any type that marshals
+// to a JSON Schema, can be resolved.
+func checkSchema(schema any, validateDefaults bool) error {
+	var s *jsonschema.Schema
+	if err := remarshal(schema, &s); err != nil {
+		return err
+	}
+	if s == nil {
+		return errors.New("schema is null")
+	}
+	_, err := s.Resolve(&jsonschema.ResolveOptions{ValidateDefaults: validateDefaults})
+	return err
+}
+
+// iconSizeRegexp matches the sizes allowed in [Icon.Sizes], other than "any".
+var iconSizeRegexp = regexp.MustCompile(`^[1-9][0-9]*x[1-9][0-9]*$`)
+
+// checkIcon reports whether icon is well-formed.
+func checkIcon(icon Icon) error {
+	if icon.Source == "" {
+		return errors.New("missing source")
+	}
+	u, err := url.Parse(icon.Source)
+	if err != nil {
+		return fmt.Errorf("invalid source: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "data":
+	default:
+		return fmt.Errorf("source %q is not an http, https or data URI", icon.Source)
+	}
+	for _, size := range icon.Sizes {
+		if size != "any" && !iconSizeRegexp.MatchString(size) {
+			return fmt.Errorf("invalid size %q", size)
+		}
+	}
+	switch icon.Theme {
+	case "", IconThemeLight, IconThemeDark:
+	default:
+		return fmt.Errorf("invalid theme %q", icon.Theme)
+	}
+	return nil
+}
+
 // validateToolName checks whether name is a valid tool name, reporting a
 // non-nil error if not.
 func validateToolName(name string) error {
@@ -9,9 +9,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
@@ -223,6 +228,97 @@ func TestToolErrorHandling(t *testing.T) {
 	})
 }
 
+func TestValidateToolArguments(t *testing.T) {
+	ctx := context.Background()
+	type args struct {
+		Name  string `json:"name"`
+		Count int    `json:"count,omitempty"`
+	}
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "count"}, func(context.Context, *CallToolRequest, args) (*CallToolResult, any, error) {
+		return nil, nil, nil
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	// Validate against the schema as the client sees it.
+	res, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := res.Tools[0]
+
+	if got, err := ValidateToolArguments(tool, map[string]any{"name": "x"}); err != nil {
+		t.Errorf("ValidateToolArguments(valid) failed: %v", err)
+	} else if !strings.Contains(string(got), `"name":"x"`) {
+		t.Errorf("ValidateToolArguments(valid) = %s, want name preserved", got)
+	}
+
+	bad := json.RawMessage(`{"name": 1}`)
+	_, validateErr := ValidateToolArguments(tool, bad)
+	if validateErr == nil {
+		t.Fatal("ValidateToolArguments(invalid) succeeded unexpectedly")
+	}
+	callRes, err := cs.CallTool(ctx, &CallToolParams{Name: "count", Arguments: bad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !callRes.IsError {
+		t.Fatal("CallTool(invalid) did not return an error result")
+	}
+	if got, want := callRes.Content[0].(*TextContent).Text, validateErr.Error(); got != want {
+		t.Errorf("server error %q does not match client-side error %q", got, want)
+	}
+
+	if got, err := ValidateToolArguments(&Tool{Name: "noschema"}, bad); err != nil || string(got) != string(bad) {
+		t.Errorf("ValidateToolArguments(no schema) = %s, %v, want arguments unchanged", got, err)
+	}
+}
+
+func TestToolSchemaRefs(t *testing.T) {
+	// Intra-document references are resolved.
+	tool := &Tool{
+		Name: "refs",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"point": map[string]any{"$ref": "#/$defs/point"},
+			},
+			"$defs": map[string]any{
+				"point": map[string]any{
+					"type":     "object",
+					"required": []any{"x", "y"},
+					"properties": map[string]any{
+						"x": map[string]any{"type": "number"},
+						"y": map[string]any{"type": "number"},
+					},
+				},
+			},
+		},
+	}
+	if _, err := ValidateToolArguments(tool, map[string]any{"point": map[string]any{"x": 1, "y": 2}}); err != nil {
+		t.Errorf("valid arguments: %v", err)
+	}
+	if _, err := ValidateToolArguments(tool, map[string]any{"point": map[string]any{"x": 1}}); err == nil {
+		t.Error("arguments missing a referenced required property: got nil error")
+	}
+
+	// External references are rejected when the schema is resolved.
+	external := &Tool{
+		Name: "external",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"point": map[string]any{"$ref": "https://example.com/point.json"},
+			},
+		},
+	}
+	_, err := ValidateToolArguments(external, map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "resolving input schema") {
+		t.Errorf("external $ref: got error %v, want resolution error", err)
+	}
+}
+
 func TestValidateToolName(t *testing.T) {
 	t.Run("valid", func(t *testing.T) {
 		validTests := []struct {
@@ -272,3 +368,541 @@ func TestValidateToolName(t *testing.T) {
 	})
 
 }
+
+func TestToolRequiredClientCapabilities(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(testImpl, nil)
+	handler := func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+		return nil, nil, nil
+	}
+	AddTool(server, &Tool{Name: "plain"}, handler)
+	sampling := &Tool{Name: "needs_sampling"}
+	sampling.SetRequiredClientCapabilities(ClientCapabilitySampling)
+	AddTool(server, sampling, handler)
+	elicitation := &Tool{Name: "needs_elicitation"}
+	elicitation.SetRequiredClientCapabilities(ClientCapabilityElicitation)
+	AddTool(server, elicitation, handler)
+
+	elicitHandler := func(context.Context, *ElicitRequest) (*ElicitResult, error) {
+		return &ElicitResult{Action: ElicitActionCancel}, nil
+	}
+	tests := []struct {
+		name   string
+		client *Client
+		want   []string
+	}{
+		{"no capabilities", NewClient(testImpl, nil), []string{"plain"}},
+		{"elicitation", NewClient(testImpl, &ClientOptions{ElicitationHandler: elicitHandler}), []string{"needs_elicitation", "plain"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cs, _, cleanup := basicClientServerConnection(t, test.client, server, nil)
+			defer cleanup()
+			res, err := cs.ListTools(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tool := range res.Tools {
+				got = append(got, tool.Name)
+				if tool.Name == "needs_elicitation" {
+					if caps := tool.GetRequiredClientCapabilities(); !reflect.DeepEqual(caps, []string{ClientCapabilityElicitation}) {
+						t.Errorf("GetRequiredClientCapabilities() = %v, want [elicitation]", caps)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ListTools() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// Hidden tools don't leave pages short.
+	paged := NewServer(testImpl, &ServerOptions{PageSize: 1})
+	AddTool(paged, elicitation, handler)
+	AddTool(paged, &Tool{Name: "plain1"}, handler)
+	AddTool(paged, sampling, handler)
+	AddTool(paged, &Tool{Name: "plain2"}, handler)
+	cs, _, cleanup := basicClientServerConnection(t, nil, paged, nil)
+	defer cleanup()
+	var pages [][]string
+	params := &ListToolsParams{}
+	for {
+		res, err := cs.ListTools(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []string
+		for _, tool := range res.Tools {
+			page = append(page, tool.Name)
+		}
+		pages = append(pages, page)
+		if res.NextCursor == "" {
+			break
+		}
+		params.Cursor = res.NextCursor
+	}
+	if want := [][]string{{"plain1"}, {"plain2"}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("ListTools pages = %v, want %v", pages, want)
+	}
+}
+
+func TestToolStructuredContentFromOut(t *testing.T) {
+	ctx := context.Background()
+	type weather struct {
+		City string  `json:"city"`
+		Temp float64 `json:"temp"`
+	}
+	server := NewServer(testImpl, nil)
+	AddTool(server, &Tool{Name: "struct"}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, weather, error) {
+		return nil, weather{City: "Paris", Temp: 21}, nil
+	})
+	AddTool(server, &Tool{Name: "any"}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+		return &CallToolResult{Content: []Content{&TextContent{Text: "hi"}}}, nil, nil
+	})
+	AddTool(server, &Tool{Name: "empty"}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, struct{}, error) {
+		return nil, struct{}{}, nil
+	})
+	AddTool(server, &Tool{
+		Name: "invalid",
+		OutputSchema: &jsonschema.Schema{
+			Type:       "object",
+			Properties: map[string]*jsonschema.Schema{"temp": {Type: "number", Maximum: jsonschema.Ptr(10.0)}},
+		},
+	}, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, weather, error) {
+		return nil, weather{Temp: 21}, nil
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	call := func(name string) (*CallToolResult, error) {
+		return cs.CallTool(ctx, &CallToolParams{Name: name, Arguments: map[string]any{}})
+	}
+
+	res, err := call("struct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"city": "Paris", "temp": 21.0}
+	if !reflect.DeepEqual(res.StructuredContent, want) {
+		t.Errorf("struct: StructuredContent = %v, want %v", res.StructuredContent, want)
+	}
+	if text := res.Content[0].(*TextContent).Text; text != `{"city":"Paris","temp":21}` {
+		t.Errorf("struct: Content = %q, want the JSON of the output", text)
+	}
+
+	res, err = call("any")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StructuredContent != nil {
+		t.Errorf("any: StructuredContent = %v, want nil", res.StructuredContent)
+	}
+	if len(res.Content) != 1 || res.Content[0].(*TextContent).Text != "hi" {
+		t.Errorf("any: Content = %v, want only the handler's content", res.Content)
+	}
+
+	res, err = call("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.StructuredContent, map[string]any{}) {
+		t.Errorf("struct{}: StructuredContent = %v, want {}", res.StructuredContent)
+	}
+
+	if _, err := call("invalid"); err == nil || !strings.Contains(err.Error(), "validating tool output") {
+		t.Errorf("invalid: got error %v, want output validation error", err)
+	}
+}
+
+func TestToolOutputError(t *testing.T) {
+	ctx := context.Background()
+	type out struct {
+		Count int `json:"count"`
+	}
+	tool := &Tool{
+		Name: "counter",
+		OutputSchema: &jsonschema.Schema{
+			Type:       "object",
+			Properties: map[string]*jsonschema.Schema{"count": {Type: "integer", Minimum: jsonschema.Ptr(0.0)}},
+		},
+	}
+	handler := func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, out, error) {
+		return nil, out{Count: -1}, nil
+	}
+
+	_, th, err := toolForErr(tool, handler, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = th(ctx, &CallToolRequest{Params: &CallToolParamsRaw{Name: "counter"}})
+	var outErr *ToolOutputError
+	if !errors.As(err, &outErr) {
+		t.Fatalf("handler error = %v, want *ToolOutputError", err)
+	}
+	if outErr.Tool != "counter" || !strings.Contains(outErr.Err.Error(), "count") {
+		t.Errorf("ToolOutputError = %+v, want tool %q and details about %q", outErr, "counter", "count")
+	}
+
+	// The server logs the mismatch for the developer.
+	var logbuf safeBuffer
+	server := NewServer(testImpl, &ServerOptions{Logger: slog.New(slog.NewTextHandler(&logbuf, nil))})
+	AddTool(server, tool, handler)
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+	if _, err := cs.CallTool(ctx, &CallToolParams{Name: "counter", Arguments: map[string]any{}}); err == nil {
+		t.Fatal("CallTool succeeded unexpectedly")
+	}
+	if log := string(logbuf.Bytes()); !strings.Contains(log, "tool output does not match its output schema") || !strings.Contains(log, "tool=counter") {
+		t.Errorf("server log = %q, want output schema mismatch for tool counter", log)
+	}
+}
+
+func TestToolStructuredContentOnly(t *testing.T) {
+	ctx := context.Background()
+	type out struct {
+		Sum int `json:"sum"`
+	}
+	server := NewServer(testImpl, nil)
+	tool := &Tool{Name: "add"}
+	tool.SetStructuredContentOnly(true)
+	AddTool(server, tool, func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, out, error) {
+		return nil, out{Sum: 3}, nil
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	tools, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tools.Tools[0].GetStructuredContentOnly() {
+		t.Error("GetStructuredContentOnly() = false on the client, want true")
+	}
+	res, err := cs.CallTool(ctx, &CallToolParams{Name: "add", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Content) != 0 {
+		t.Errorf("Content = %v, want empty", res.Content)
+	}
+	if !reflect.DeepEqual(res.StructuredContent, map[string]any{"sum": 3.0}) {
+		t.Errorf("StructuredContent = %v, want {sum: 3}", res.StructuredContent)
+	}
+	display := res.DisplayContent()
+	if len(display) != 1 || display[0].(*TextContent).Text != `{"sum":3}` {
+		t.Errorf("DisplayContent() = %v, want the JSON of the structured content", display)
+	}
+
+	withContent := &CallToolResult{Content: []Content{&TextContent{Text: "three"}}, StructuredContent: map[string]any{"sum": 3}}
+	if got := withContent.DisplayContent(); !reflect.DeepEqual(got, withContent.Content) {
+		t.Errorf("DisplayContent() = %v, want Content", got)
+	}
+}
+
+func TestToolContentMIMEType(t *testing.T) {
+	ctx := context.Background()
+	type out struct {
+		Sum int `json:"sum"`
+	}
+	server := NewServer(testImpl, nil)
+	newTool := func(name string) *Tool {
+		tool := &Tool{Name: name}
+		tool.SetContentMIMEType("text/markdown")
+		return tool
+	}
+	AddTool(server, newTool("report"), func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, string, error) {
+		return nil, "# Report", nil
+	})
+	AddTool(server, newTool("sum"), func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, out, error) {
+		return nil, out{Sum: 3}, nil
+	})
+	shared := []Content{
+		&TextContent{Text: "*three*"},
+		&TextContent{Text: "3", Meta: Meta{contentMIMETypeMetaKey: "text/plain"}},
+	}
+	AddTool(server, newTool("explicit"), func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+		return &CallToolResult{Content: shared}, nil, nil
+	})
+	AddTool(server, newTool("failing"), func(context.Context, *CallToolRequest, map[string]any) (*CallToolResult, any, error) {
+		return nil, nil, errors.New("boom")
+	})
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+
+	tools, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools.Tools {
+		if got := tool.GetContentMIMEType(); got != "text/markdown" {
+			t.Errorf("%s: GetContentMIMEType() = %q on the client, want %q", tool.Name, got, "text/markdown")
+		}
+		// The MIME type is recorded under the SDK's _meta prefix.
+		if _, ok := tool.Meta[MetaKeyPrefix+"contentMimeType"]; !ok {
+			t.Errorf("%s: _meta = %v, want a %scontentMimeType key", tool.Name, tool.Meta, MetaKeyPrefix)
+		}
+	}
+
+	type text struct{ Text, MIMEType string }
+	call := func(name string) []text {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &CallToolParams{Name: name, Arguments: map[string]any{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []text
+		for c := range TextContents(res.Content) {
+			got = append(got, text{c.Text, c.MIMEType()})
+		}
+		return got
+	}
+	for _, test := range []struct {
+		tool string
+		want []text
+	}{
+		// A string output is the text itself.
+		{"report", []text{{"# Report", "text/markdown"}}},
+		// Other outputs are JSON.
+		{"sum", []text{{`{"sum":3}`, "application/json"}}},
+		// Content without a MIME type gets the tool's.
+		{"explicit", []text{{"*three*", "text/markdown"}, {"3", "text/plain"}}},
+		// Error messages are not given the tool's MIME type.
+		{"failing", []text{{"boom", ""}}},
+	} {
+		if got := call(test.tool); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got text content %v, want %v", test.tool, got, test.want)
+		}
+	}
+	// The handler's content is not modified.
+	if got := shared[0].(*TextContent).MIMEType(); got != "" {
+		t.Errorf("handler content was modified: MIME type %q", got)
+	}
+}
+
+func TestToolConfigWatcher(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tools.json")
+	write := func(config string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	echo := func(_ context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		return &CallToolResult{Content: []Content{&TextContent{Text: string(req.Params.Arguments)}}}, nil
+	}
+	handlers := map[string]ToolHandler{"echo": echo}
+
+	server := NewServer(testImpl, nil)
+	clock := newFakeClock()
+	server.clock = clock
+	cs, _, cleanup := basicClientServerConnection(t, nil, server, nil)
+	defer cleanup()
+	toolNames := func() []string {
+		t.Helper()
+		res, err := cs.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	write(`{"tools": [
+		{"name": "a", "inputSchema": {"type": "object", "properties": {"n": {"type": "integer", "default": 1}}}, "handler": "echo"},
+		{"name": "b", "inputSchema": {"type": "object"}, "handler": "echo"}
+	]}`)
+	w := NewToolConfigWatcher(server, path, handlers, &ToolConfigOptions{PollInterval: time.Second})
+	if err := w.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := toolNames(), []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("after load, tools = %v, want %v", got, want)
+	}
+
+	// Arguments are validated, and defaults applied.
+	res, err := cs.CallTool(ctx, &CallToolParams{Name: "a", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Content[0].(*TextContent).Text, `{"n":1}`; got != want {
+		t.Errorf("a({}) = %s, want %s", got, want)
+	}
+	res, err = cs.CallTool(ctx, &CallToolParams{Name: "a", Arguments: map[string]any{"n": "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsError {
+		t.Errorf("a with invalid arguments: got success, want error result")
+	}
+
+	// An invalid file is rejected as a whole, reporting every problem.
+	write(`{"tools": [
+		{"name": "a", "inputSchema": {"type": "object"}, "handler": "echo"},
+		{"name": "c", "inputSchema": {"type": "object"}, "handler": "missing"},
+		{"name": "d", "inputSchema": {"type": "string"}, "handler": "echo"}
+	]}`)
+	err = w.Load()
+	if err == nil {
+		t.Fatal("loading invalid config succeeded unexpectedly")
+	}
+	for _, want := range []string{`tool "c": unknown handler "missing"`, `tool "d": input schema must have type "object"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if got, want := toolNames(), []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("after invalid load, tools = %v, want %v", got, want)
+	}
+
+	// Run picks up changes to the file, removing tools that are gone.
+	write(`{"tools": [
+		{"name": "a", "inputSchema": {"type": "object"}, "handler": "echo"},
+		{"name": "c", "inputSchema": {"type": "object"}, "handler": "echo"}
+	]}`)
+	runCtx, cancel := context.WithCancel(ctx)
+	runErr := make(chan error, 1)
+	go func() { runErr <- w.Run(runCtx) }()
+	waitForTools := func(want ...string) {
+		t.Helper()
+		for got := toolNames(); !slices.Equal(got, want); got = toolNames() {
+			clock.Advance(time.Second)
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitForTools("a", "c")
+	write(`{"tools": [{"name": "a", "inputSchema": {"type": "object"}, "handler": "echo"}]}`)
+	waitForTools("a")
+
+	// A tool added by other means is not removed, even if it replaced one
+	// loaded from the file.
+	server.AddTool(&Tool{Name: "a", InputSchema: &jsonschema.Schema{Type: "object"}}, echo)
+	write(`{"tools": [{"name": "b", "inputSchema": {"type": "object"}, "handler": "echo"}]}`)
+	waitForTools("a", "b")
+	cancel()
+	if err := <-runErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+}
+
+func TestToolValidate(t *testing.T) {
+	object := &jsonschema.Schema{Type: "object"}
+	valid := func() *Tool {
+		return &Tool{
+			Name:         "get_weather",
+			Title:        "Weather",
+			InputSchema:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+			OutputSchema: &jsonschema.Schema{Type: "string"},
+			Icons: []Icon{
+				{Source: "https://example.com/icon.png", Sizes: []string{"48x48", "any"}, Theme: IconThemeDark},
+				{Source: "data:image/png;base64,AAAA"},
+			},
+			Annotations: &ToolAnnotations{Title: "Weather", ReadOnlyHint: true},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate() of valid tool = %v", err)
+	}
+	// Title takes precedence over Annotations.Title, so they may differ.
+	differentTitles := valid()
+	differentTitles.Annotations.Title = "Forecast"
+	if err := differentTitles.Validate(); err != nil {
+		t.Errorf("Validate() of tool with different titles = %v", err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		modify  func(*Tool)
+		wantErr []string
+	}{
+		{"bad name", func(t *Tool) { t.Name = "get weather" }, []string{"invalid characters"}},
+		{"no input schema", func(t *Tool) { t.InputSchema = nil }, []string{"missing input schema"}},
+		{"input not object", func(t *Tool) { t.InputSchema = &jsonschema.Schema{Type: "string"} }, []string{`type "object"`}},
+		{"bad input default", func(t *Tool) {
+			t.InputSchema = &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{
+				"n": {Type: "integer", Default: json.RawMessage(`"x"`)},
+			}}
+		}, []string{"invalid input schema"}},
+		{"bad output schema", func(t *Tool) { t.OutputSchema = json.RawMessage(`{"$ref":"#/$defs/missing"}`) }, []string{"invalid output schema"}},
+		{"icon source", func(t *Tool) { t.Icons[0].Source = "file:///icon.png" }, []string{"icon 0", "not an http"}},
+		{"icon size", func(t *Tool) { t.Icons[1].Sizes = []string{"big"} }, []string{"icon 1", `invalid size "big"`}},
+		{"icon theme", func(t *Tool) { t.Icons[0].Theme = "blue" }, []string{`invalid theme "blue"`}},
+		{"read-only destructive", func(t *Tool) { t.Annotations.DestructiveHint = jsonschema.Ptr(true) }, []string{"read-only tool as destructive"}},
+		{"several", func(t *Tool) {
+			t.Name = ""
+			t.InputSchema = object
+			t.Icons[0].Source = ""
+		}, []string{"name cannot be empty", "missing source"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tool := valid()
+			test.modify(tool)
+			err := tool.Validate()
+			if err == nil {
+				t.Fatal("Validate() succeeded unexpectedly")
+			}
+			for _, want := range test.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %v, want error containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCallToolResultBuilder(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name string
+		b    *CallToolResultBuilder
+		want *CallToolResult
+	}{
+		{"empty", NewCallToolResult(), &CallToolResult{}},
+		{
+			"content",
+			NewCallToolResult().Text("hi").Image([]byte("img"), "image/png").Audio([]byte("aud"), "audio/wav").Structured(map[string]any{"x": 1}),
+			&CallToolResult{
+				Content: []Content{
+					&TextContent{Text: "hi"},
+					&ImageContent{Data: []byte("img"), MIMEType: "image/png"},
+					&AudioContent{Data: []byte("aud"), MIMEType: "audio/wav"},
+				},
+				StructuredContent: map[string]any{"x": 1},
+			},
+		},
+		{
+			"error text",
+			NewCallToolResult().Error(errBoom),
+			&CallToolResult{Content: []Content{&TextContent{Text: "boom"}}, IsError: true},
+		},
+		{
+			"error with message",
+			NewCallToolResult().Error(errBoom).Text("something went wrong"),
+			&CallToolResult{Content: []Content{&TextContent{Text: "something went wrong"}}, IsError: true},
+		},
+		{"nil error", NewCallToolResult().Text("ok").Error(nil), &CallToolResult{Content: []Content{&TextContent{Text: "ok"}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.b.Result()
+			if got.IsError != test.want.IsError || !reflect.DeepEqual(got.Content, test.want.Content) || !reflect.DeepEqual(got.StructuredContent, test.want.StructuredContent) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if test.want.IsError && !errors.Is(got.GetError(), errBoom) {
+				t.Errorf("GetError() = %v, want %v", got.GetError(), errBoom)
+			}
+		})
+	}
+
+	// Results built from the same builder don't share content.
+	b := NewCallToolResult().Text("a")
+	r1 := b.Result()
+	b.Text("b")
+	if r2 := b.Result(); len(r1.Content) != 1 || len(r2.Content) != 2 {
+		t.Errorf("got %d and %d content items, want 1 and 2", len(r1.Content), len(r2.Content))
+	}
+}
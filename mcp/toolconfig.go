@@ -0,0 +1,246 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// ToolConfigOptions configures a [ToolConfigWatcher].
+type ToolConfigOptions struct {
+	// PollInterval is how often [ToolConfigWatcher.Run] checks the config file
+	// for changes. If zero, it defaults to one second.
+	PollInterval time.Duration
+
+	// Unmarshal decodes the contents of the config file. If nil, the file must
+	// be JSON and is decoded with [json.Unmarshal].
+	//
+	// To read another format, such as YAML, provide a function that honors
+	// json struct tags and [json.RawMessage], for example by converting the
+	// input to JSON before calling json.Unmarshal.
+	Unmarshal func(data []byte, v any) error
+}
+
+// A ToolConfigWatcher keeps the tools of a [Server] in sync with a config
+// file, so that a server's tool catalog can be changed without restarting it.
+//
+// The config file holds an object with a "tools" array. Each element is a
+// [Tool], as it appears in a tools/list response, with an additional
+// "handler" field naming the entry in the handlers map that implements it:
+//
+//	{
+//	  "tools": [
+//	    {
+//	      "name": "search",
+//	      "description": "Search the catalog",
+//	      "inputSchema": {"type": "object", "properties": {"q": {"type": "string"}}},
+//	      "handler": "search"
+//	    }
+//	  ]
+//	}
+//
+// Arguments are validated against the tool's input schema before its handler
+// is called, as for tools added with [AddTool]; the handler receives them as
+// a [json.RawMessage], with defaults applied.
+//
+// Each load replaces the tools added by the previous load, removing those no
+// longer in the file, and triggers a single tools/list_changed notification.
+// A tool in the file replaces any tool of the same name added by other means.
+// Conversely, a tool added by other means after a load is never removed by
+// the watcher, even if it has the name of a tool from that load.
+// A file that fails to parse or validate is rejected as a whole, leaving the
+// server's tools unchanged.
+type ToolConfigWatcher struct {
+	server   *Server
+	path     string
+	handlers map[string]ToolHandler
+	opts     ToolConfigOptions
+
+	mu      sync.Mutex
+	loaded  map[string]*Tool // tools added by the last load, by name
+	modTime time.Time        // of the file when last loaded
+	size    int64            // of the file when last loaded
+}
+
+// NewToolConfigWatcher returns a watcher that loads the tools described by
+// the config file at path into server, looking up their handlers by key in
+// handlers. Call [ToolConfigWatcher.Run] to start watching the file, or
+// [ToolConfigWatcher.Load] to load it once.
+//
+// If non-nil, the provided options configure the watcher.
+func NewToolConfigWatcher(server *Server, path string, handlers map[string]ToolHandler, opts *ToolConfigOptions) *ToolConfigWatcher {
+	w := &ToolConfigWatcher{
+		server:   server,
+		path:     path,
+		handlers: handlers,
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.PollInterval <= 0 {
+		w.opts.PollInterval = time.Second
+	}
+	if w.opts.Unmarshal == nil {
+		w.opts.Unmarshal = json.Unmarshal
+	}
+	return w
+}
+
+// Load reads the config file and applies it to the server.
+// If the file is invalid, Load returns an error describing every problem
+// found, and the server's tools are unchanged.
+func (w *ToolConfigWatcher) Load() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	return w.loadLocked(info)
+}
+
+// Run loads the config file, then polls it for changes until ctx is done,
+// reloading it whenever its modification time or size changes.
+//
+// If the initial load fails, Run returns its error. Errors from later loads
+// are logged to [ServerOptions.Logger], and the previously loaded tools remain
+// in place. Otherwise, Run returns ctx.Err() when ctx is done.
+func (w *ToolConfigWatcher) Run(ctx context.Context) error {
+	if err := w.Load(); err != nil {
+		return err
+	}
+	ticker := w.server.clock.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			if err := w.reloadIfChanged(); err != nil {
+				w.server.opts.Logger.Error("reloading tool config", "path", w.path, "error", err)
+			}
+		}
+	}
+}
+
+// reloadIfChanged loads the config file if it has changed since it was last
+// loaded.
+func (w *ToolConfigWatcher) reloadIfChanged() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil
+	}
+	return w.loadLocked(info)
+}
+
+// loadLocked loads the config file, whose current state is described by info.
+//
+// w.mu must be held when calling this method.
+func (w *ToolConfigWatcher) loadLocked(info os.FileInfo) error {
+	// Record the file state even if loading fails, so that an invalid file is
+	// reported once rather than on every poll.
+	w.modTime, w.size = info.ModTime(), info.Size()
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	tools, err := w.parse(data)
+	if err != nil {
+		return fmt.Errorf("tool config %s: %w", w.path, err)
+	}
+	loaded := make(map[string]*Tool)
+	for _, t := range tools {
+		loaded[t.Tool.Name] = t.Tool
+	}
+	w.server.changeAndNotify(notificationToolListChanged, func() bool {
+		var stale []string
+		for name, tool := range w.loaded {
+			// Remove only the tools that this watcher installed: a tool of
+			// the same name may since have been added by other means.
+			if st, ok := w.server.tools.get(name); ok && st.tool == tool && loaded[name] == nil {
+				stale = append(stale, name)
+			}
+		}
+		w.server.tools.remove(stale...)
+		for _, t := range tools {
+			w.server.tools.add(&serverTool{tool: t.Tool, handler: t.Handler})
+		}
+		return true
+	})
+	w.loaded = loaded
+	return nil
+}
+
+// parse decodes and validates the tools in a config file.
+func (w *ToolConfigWatcher) parse(data []byte) ([]*ServerTool, error) {
+	var file struct {
+		Tools []json.RawMessage `json:"tools"`
+	}
+	if err := w.opts.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var (
+		reg  Registry
+		errs []error
+	)
+	for i, raw := range file.Tools {
+		var entry struct {
+			Handler string `json:"handler"`
+		}
+		t := new(Tool)
+		if err := json.Unmarshal(raw, t); err != nil {
+			errs = append(errs, fmt.Errorf("tool %d: %v", i, err))
+			continue
+		}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			errs = append(errs, fmt.Errorf("tool %q: %v", t.Name, err))
+			continue
+		}
+		h, ok := w.handlers[entry.Handler]
+		if !ok {
+			errs = append(errs, fmt.Errorf("tool %q: unknown handler %q", t.Name, entry.Handler))
+			continue
+		}
+		resolved, err := resolveInputSchema(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reg.Tools = append(reg.Tools, &ServerTool{Tool: t, Handler: validatingToolHandler(resolved, h)})
+	}
+	errs = append(errs, reg.check())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return reg.Tools, nil
+}
+
+// validatingToolHandler returns a handler that validates arguments against
+// the resolved input schema, and applies its defaults, before calling h.
+func validatingToolHandler(resolved *jsonschema.Resolved, h ToolHandler) ToolHandler {
+	return func(ctx context.Context, req *CallToolRequest) (*CallToolResult, error) {
+		args, err := applySchema(req.Params.Arguments, resolved, false)
+		if err != nil {
+			var errRes CallToolResult
+			errRes.SetError(fmt.Errorf("validating \"arguments\": %v", err))
+			return &errRes, nil
+		}
+		req.Params.Arguments = args
+		return h(ctx, req)
+	}
+}
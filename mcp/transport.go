@@ -6,12 +6,14 @@ package mcp
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"sync"
 	"time"
@@ -67,6 +69,11 @@ type Connection interface {
 	//
 	// Connections must allow Read to be called concurrently with Close. In
 	// particular, calling Close should unblock a Read waiting for input.
+	//
+	// Read must also return promptly, with the context's error, when its
+	// context is done. Connections built on a read that cannot be cancelled,
+	// such as reading a websocket message, can use a [ContextReader] to
+	// satisfy this requirement.
 	Read(context.Context) (jsonrpc.Message, error)
 
 	// Write writes a new message to the connection.
@@ -81,10 +88,104 @@ type Connection interface {
 	// Close may be called multiple times, potentially concurrently.
 	Close() error
 
+	// SessionID returns the ID of the session carried by the connection, or
+	// the empty string if the connection has no session ID, as for stdio.
+	//
+	// A non-empty session ID must not change over the life of the connection,
+	// and must be unique among the connections of a server or client: a
+	// [Server] refuses to connect a transport whose session ID is already in
+	// use. Values derived from the peer, such as its network address, are
+	// neither stable nor unique; use [NewSessionID] to generate an ID instead.
+	//
 	// TODO(#148): remove SessionID from this interface.
 	SessionID() string
 }
 
+// NewSessionID returns a new random session ID, suitable for the result of
+// [Connection.SessionID] or [ServerOptions.GetSessionID].
+//
+// The ID has at least 128 bits of randomness, and consists of visible ASCII
+// characters, so that it can be used as the value of the Mcp-Session-Id
+// header.
+func NewSessionID() string {
+	return rand.Text()
+}
+
+// A ContextReader adapts a blocking read, which cannot be cancelled, to the
+// context-aware [Connection.Read] method.
+//
+// [ContextReader.Read] performs the blocking read in a goroutine, and returns
+// when either the read completes or its context is done. In the latter case,
+// if SetReadDeadline is set, it is used to interrupt the blocked read, so that
+// the goroutine exits. Otherwise, the read continues in the background, and
+// its result is returned by the next call to Read. Either way, at most one read
+// is in flight, and a message that has been read is never dropped.
+//
+// A ContextReader must not be copied after first use.
+type ContextReader struct {
+	// ReadMessage reads the next message, blocking until one is available or
+	// the underlying connection is closed. It must not be nil.
+	ReadMessage func() (jsonrpc.Message, error)
+
+	// SetReadDeadline, if non-nil, sets the deadline for ReadMessage, as with
+	// [net.Conn.SetReadDeadline]. When the context of a blocked Read is done,
+	// Read sets a deadline in the past to interrupt ReadMessage, and clears
+	// the deadline once it has returned.
+	//
+	// Some connections can't be read after a deadline expires. Since Read is
+	// usually cancelled only when the connection is being shut down, this is
+	// rarely a problem.
+	SetReadDeadline func(time.Time) error
+
+	mu      sync.Mutex
+	pending chan readMessageResult // result of the in-flight read, if any
+}
+
+type readMessageResult struct {
+	msg jsonrpc.Message
+	err error
+}
+
+// Read returns the next message read by r.ReadMessage, or the context's
+// error if ctx is done first.
+func (r *ContextReader) Read(ctx context.Context) (jsonrpc.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if r.pending == nil {
+		pending := make(chan readMessageResult, 1)
+		r.pending = pending
+		go func() {
+			msg, err := r.ReadMessage()
+			pending <- readMessageResult{msg, err}
+		}()
+	}
+	select {
+	case res := <-r.pending:
+		r.pending = nil
+		return res.msg, res.err
+	case <-ctx.Done():
+	}
+
+	if r.SetReadDeadline == nil || r.SetReadDeadline(time.Now()) != nil {
+		// Leave the read in flight, for the next call to Read.
+		return nil, ctx.Err()
+	}
+	res := <-r.pending
+	r.pending = nil
+	if res.err == nil {
+		// The read completed before it was interrupted: keep the message for
+		// the next call to Read.
+		r.pending = make(chan readMessageResult, 1)
+		r.pending <- res
+	}
+	r.SetReadDeadline(time.Time{})
+	return nil, ctx.Err()
+}
+
 // A ClientConnection is a [Connection] that is specific to the MCP client.
 //
 // If client connections implement this interface, they may receive information
@@ -111,11 +212,23 @@ type serverConnection interface {
 
 // A StdioTransport is a [Transport] that communicates over stdin/stdout using
 // newline-delimited JSON.
-type StdioTransport struct{}
+//
+// When stdin is closed, for example because the host process exited, the
+// session ends gracefully: [Server.Run] returns nil once in-progress requests
+// have completed.
+type StdioTransport struct {
+	// IdleTimeout, if positive, ends the session after no messages have been
+	// exchanged for the given duration while no request is in progress. The
+	// session ends as if stdin were closed. Use it to ensure that a server run
+	// as a subprocess exits even if its host fails to close stdin.
+	IdleTimeout time.Duration
+}
 
 // Connect implements the [Transport] interface.
-func (*StdioTransport) Connect(context.Context) (Connection, error) {
-	return newIOConn(rwc{os.Stdin, nopCloserWriter{os.Stdout}}), nil
+func (t *StdioTransport) Connect(context.Context) (Connection, error) {
+	c := newIOConn(rwc{os.Stdin, nopCloserWriter{os.Stdout}})
+	c.idle = newIdleTracker(realClock{}, t.IdleTimeout)
+	return c, nil
 }
 
 // nopCloserWriter is an io.WriteCloser with a trivial Close method.
@@ -130,11 +243,141 @@ func (nopCloserWriter) Close() error { return nil }
 type IOTransport struct {
 	Reader io.ReadCloser
 	Writer io.WriteCloser
+
+	// IdleTimeout, if positive, ends the session after no messages have been
+	// exchanged for the given duration while no request is in progress, as
+	// for [StdioTransport.IdleTimeout].
+	IdleTimeout time.Duration
 }
 
 // Connect implements the [Transport] interface.
 func (t *IOTransport) Connect(context.Context) (Connection, error) {
-	return newIOConn(rwc{t.Reader, t.Writer}), nil
+	c := newIOConn(rwc{t.Reader, t.Writer})
+	c.idle = newIdleTracker(realClock{}, t.IdleTimeout)
+	return c, nil
+}
+
+// An idleTracker detects when a connection has become idle: no messages
+// have been read or written for a timeout, and no call is awaiting its
+// response.
+type idleTracker struct {
+	clock   clock
+	timeout time.Duration
+	timer   timer
+	expired chan struct{} // closed when the connection has become idle
+
+	mu      sync.Mutex
+	pending map[idleCall]bool // calls awaiting a response, in either direction
+	last    time.Time         // time of the last message
+}
+
+// An idleCall identifies a call tracked by an [idleTracker].
+type idleCall struct {
+	incoming bool // whether the call was read, rather than written
+	id       jsonrpc.ID
+}
+
+// newIdleTracker returns a tracker for the given timeout, or nil if the
+// timeout is not positive. The methods of a nil tracker are no-ops.
+func newIdleTracker(clk clock, timeout time.Duration) *idleTracker {
+	if timeout <= 0 {
+		return nil
+	}
+	t := &idleTracker{
+		clock:   clk,
+		timeout: timeout,
+		expired: make(chan struct{}),
+		pending: make(map[idleCall]bool),
+		last:    clk.Now(),
+	}
+	t.timer = clk.AfterFunc(timeout, t.expire)
+	return t
+}
+
+func (t *idleTracker) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// The timer may have been reset after it fired.
+	if len(t.pending) > 0 || t.clock.Now().Sub(t.last) < t.timeout {
+		return
+	}
+	select {
+	case <-t.expired:
+	default:
+		close(t.expired)
+	}
+}
+
+// observe records that msg was read (if incoming is set) or written.
+//
+// A call is no longer pending once its response is seen, or once a
+// "notifications/cancelled" notification for it is seen in the same
+// direction: the response to a cancelled call may never be sent, and the
+// caller no longer awaits it.
+func (t *idleTracker) observe(msg jsonrpc.Message, incoming bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch msg := msg.(type) {
+	case *jsonrpc.Request:
+		if msg.IsCall() {
+			t.pending[idleCall{incoming, msg.ID}] = true
+		} else if msg.Method == notificationCancelled {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if err := internaljson.Unmarshal(msg.Params, &params); err == nil {
+				if id, err := jsonrpc2.DecodeID(params.RequestID); err == nil {
+					delete(t.pending, idleCall{incoming, id})
+				}
+			}
+		}
+	case *jsonrpc.Response:
+		// A response travels in the opposite direction to its call.
+		delete(t.pending, idleCall{!incoming, msg.ID})
+	}
+	t.last = t.clock.Now()
+	t.update()
+}
+
+// forget records that the outgoing call msg, if it is one, will never be
+// answered, for example because it could not be written.
+func (t *idleTracker) forget(msg jsonrpc.Message) {
+	if t == nil {
+		return
+	}
+	if req, ok := msg.(*jsonrpc.Request); ok && req.IsCall() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.pending, idleCall{false, req.ID})
+		t.update()
+	}
+}
+
+// update stops or restarts the timer according to whether any calls are
+// pending. It must be called with t.mu held.
+func (t *idleTracker) update() {
+	if len(t.pending) > 0 {
+		t.timer.Stop()
+	} else {
+		t.timer.Reset(t.timeout - t.clock.Now().Sub(t.last))
+	}
+}
+
+// done returns a channel that is closed when the connection becomes idle.
+func (t *idleTracker) done() <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+	return t.expired
+}
+
+func (t *idleTracker) stop() {
+	if t != nil {
+		t.timer.Stop()
+	}
 }
 
 // An InMemoryTransport is a [Transport] that communicates over an in-memory
@@ -189,6 +432,7 @@ func connect[H handler, State any](ctx context.Context, t Transport, b binder[H,
 	bind := func(conn *jsonrpc2.Connection) jsonrpc2.Handler {
 		h = b.bind(mcpConn, conn, s, onClose)
 		preempter.conn = conn
+		preempter.handler = h
 		return jsonrpc2.HandlerFunc(h.handle)
 	}
 	// Transports may opt in to propagating cancellation of ctx into request
@@ -226,24 +470,66 @@ type cancellationPropagator interface {
 	propagateCancellation() bool
 }
 
+// A preemptiveHandler is a handler that asks for some methods to be handled
+// as soon as they are read, rather than being queued behind other requests.
+type preemptiveHandler interface {
+	handler
+	preemptive(method string) bool
+}
+
+// A RequestCancelledError is the cause of the cancellation of a handler's
+// context, as reported by [context.Cause], when the peer cancels the request
+// being handled with a "notifications/cancelled" notification.
+//
+// When the context of a call made with this SDK is cancelled, the reason sent
+// to the peer is the context's cause. So a caller can explain a cancellation
+// by cancelling with [context.WithCancelCause].
+//
+// RequestCancelledError wraps [context.Canceled].
+type RequestCancelledError struct {
+	// Reason is the reason for the cancellation given by the peer, if any.
+	Reason string
+}
+
+func (e *RequestCancelledError) Error() string {
+	if e.Reason == "" {
+		return "request cancelled by peer"
+	}
+	return "request cancelled by peer: " + e.Reason
+}
+
+func (e *RequestCancelledError) Unwrap() error { return context.Canceled }
+
 // A canceller is a jsonrpc2.Preempter that cancels in-flight requests on MCP
 // cancelled notifications.
+//
+// If the bound handler is a [preemptiveHandler], the canceller also handles
+// its preemptive methods directly.
 type canceller struct {
-	conn *jsonrpc2.Connection
+	conn    *jsonrpc2.Connection
+	handler handler
 }
 
 // Preempt implements [jsonrpc2.Preempter].
 func (c *canceller) Preempt(ctx context.Context, req *jsonrpc.Request) (result any, err error) {
 	if req.Method == notificationCancelled {
-		var params CancelledParams
+		// Decode the request ID directly, rather than via
+		// [CancelledParams.RequestID], to preserve large integer IDs.
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+			Reason    string          `json:"reason"`
+		}
 		if err := internaljson.Unmarshal(req.Params, &params); err != nil {
 			return nil, err
 		}
-		id, err := jsonrpc2.MakeID(params.RequestID)
+		id, err := jsonrpc2.DecodeID(params.RequestID)
 		if err != nil {
 			return nil, err
 		}
-		go c.conn.Cancel(id)
+		go c.conn.CancelCause(id, &RequestCancelledError{Reason: params.Reason})
+	}
+	if h, ok := c.handler.(preemptiveHandler); ok && h.preemptive(req.Method) {
+		return h.handle(ctx, req)
 	}
 	return nil, jsonrpc2.ErrNotHandled
 }
@@ -301,13 +587,99 @@ func cancelCall(ctx context.Context, conn *jsonrpc2.Connection, call *jsonrpc2.A
 	notifyCtx, cancelNotify := context.WithTimeout(context.WithoutCancel(ctx), notifyCancellationTimeout)
 	defer cancelNotify()
 	err := conn.Notify(notifyCtx, notificationCancelled, &CancelledParams{
-		Reason:    ctx.Err().Error(),
+		Reason:    context.Cause(ctx).Error(),
 		RequestID: call.ID().Raw(),
 	})
 	conn.Retire(call, ctx.Err())
 	return err
 }
 
+// cancelAll cancels all in-flight requests on conn, in both directions.
+//
+// Incoming requests have their contexts cancelled. Outgoing calls are retired
+// with an error wrapping [context.Canceled], and the peer is sent a
+// "notifications/cancelled" notification for each, as when the context of a
+// call is cancelled.
+func cancelAll(conn *jsonrpc2.Connection) error {
+	conn.CancelAll()
+	var errs []error
+	for _, id := range conn.RetireAll(fmt.Errorf("%w: cancelled by CancelAll", context.Canceled)) {
+		notifyCtx, cancelNotify := context.WithTimeout(context.Background(), notifyCancellationTimeout)
+		errs = append(errs, conn.Notify(notifyCtx, notificationCancelled, &CancelledParams{
+			Reason:    context.Canceled.Error(),
+			RequestID: id.Raw(),
+		}))
+		cancelNotify()
+	}
+	return errors.Join(errs...)
+}
+
+// Kinds of transport, as reported by [TransportKind].
+const (
+	TransportKindStdio          = "stdio"
+	TransportKindStreamableHTTP = "streamable-http"
+	TransportKindSSE            = "sse"
+	TransportKindInMemory       = "in-memory"
+	TransportKindIO             = "io"
+)
+
+// ConnectionInfo describes the connection of a server session, for
+// [ServerOptions.InitializeInterceptor].
+type ConnectionInfo struct {
+	// TransportKind is the kind of the session's transport, as reported by
+	// [TransportKind].
+	TransportKind string
+	// Conn is the network connection underlying the session, if any: for
+	// an [IOTransport] whose Reader is a [net.Conn], such as a unix socket
+	// connection, or for an [InMemoryTransport]. Use it to check the
+	// credentials of the peer.
+	Conn net.Conn
+	// Header is the header of the HTTP request carrying the initialization
+	// request, if any.
+	Header http.Header
+}
+
+// A netConner is a Connection over a network connection.
+type netConner interface {
+	netConn() net.Conn
+}
+
+// transportKindContextKey is the context key for the kind of transport of a
+// server session.
+type transportKindContextKey struct{}
+
+// TransportKind reports the kind of transport of the server session serving
+// the request, given the context passed to a request handler. For example,
+// a tool may stream partial results over streamable HTTP, but return a single
+// result over stdio.
+//
+// The result is one of the TransportKind constants, or "" for custom
+// transports or contexts not passed to a request handler. A
+// [LoggingTransport] reports the kind of the transport it wraps.
+func TransportKind(ctx context.Context) string {
+	kind, _ := ctx.Value(transportKindContextKey{}).(string)
+	return kind
+}
+
+// transportKind returns the kind of t for [TransportKind].
+func transportKind(t Transport) string {
+	switch t := t.(type) {
+	case *StdioTransport, *CommandTransport:
+		return TransportKindStdio
+	case *StreamableServerTransport, *StreamableClientTransport:
+		return TransportKindStreamableHTTP
+	case *SSEServerTransport, *SSEClientTransport:
+		return TransportKindSSE
+	case *InMemoryTransport:
+		return TransportKindInMemory
+	case *IOTransport:
+		return TransportKindIO
+	case *LoggingTransport:
+		return transportKind(t.Transport)
+	}
+	return ""
+}
+
 // A LoggingTransport is a [Transport] that delegates to another transport,
 // writing RPC logs to an io.Writer.
 type LoggingTransport struct {
@@ -440,6 +812,8 @@ type ioConn struct {
 	closeOnce sync.Once
 	closed    chan struct{}
 	closeErr  error
+
+	idle *idleTracker // nil unless an idle timeout is configured
 }
 
 type msgOrErr struct {
@@ -496,6 +870,17 @@ func newIOConn(rwc io.ReadWriteCloser) *ioConn {
 
 func (c *ioConn) SessionID() string { return "" }
 
+func (c *ioConn) netConn() net.Conn {
+	switch rw := c.rwc.(type) {
+	case net.Conn:
+		return rw
+	case rwc:
+		conn, _ := rw.rc.(net.Conn)
+		return conn
+	}
+	return nil
+}
+
 func (c *ioConn) sessionUpdated(state ServerSessionState) {
 	protocolVersion := ""
 	if state.InitializeParams != nil {
@@ -579,6 +964,14 @@ type msgBatch struct {
 }
 
 func (t *ioConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	msg, err := t.read(ctx)
+	if err == nil {
+		t.idle.observe(msg, true)
+	}
+	return msg, err
+}
+
+func (t *ioConn) read(ctx context.Context) (jsonrpc.Message, error) {
 	// As a matter of principle, enforce that reads on a closed context return an
 	// error.
 	select {
@@ -605,6 +998,10 @@ func (t *ioConn) Read(ctx context.Context) (jsonrpc.Message, error) {
 
 	case <-t.closed:
 		return nil, io.EOF
+
+	case <-t.idle.done():
+		// End the session as if the input were closed.
+		return nil, io.EOF
 	}
 
 	msgs, batch, err := readBatch(raw)
@@ -680,7 +1077,15 @@ func (t *ioConn) Write(ctx context.Context, msg jsonrpc.Message) error {
 
 	t.writeMu.Lock()
 	defer t.writeMu.Unlock()
+	t.idle.observe(msg, false)
+	err := t.write(msg)
+	if err != nil {
+		t.idle.forget(msg)
+	}
+	return err
+}
 
+func (t *ioConn) write(msg jsonrpc.Message) error {
 	// Batching support: if msg is a Response, it may have completed a batch, so
 	// check that first. Otherwise, it is a request or notification, and we may
 	// want to collect it into a batch before sending, if we're configured to use
@@ -723,6 +1128,7 @@ func (t *ioConn) Write(ctx context.Context, msg jsonrpc.Message) error {
 
 func (t *ioConn) Close() error {
 	t.closeOnce.Do(func() {
+		t.idle.stop()
 		t.closeErr = t.rwc.Close()
 		close(t.closed)
 	})
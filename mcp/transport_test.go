@@ -6,9 +6,16 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/internal/jsonrpc2"
 	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
@@ -147,3 +154,175 @@ func TestIOConnRead_EmptyMethod(t *testing.T) {
 		t.Errorf("ID = %v, want 5", req.ID.Raw())
 	}
 }
+
+func TestContextReader(t *testing.T) {
+	for _, withDeadline := range []bool{false, true} {
+		t.Run(fmt.Sprintf("deadline=%t", withDeadline), func(t *testing.T) {
+			msgs := make(chan jsonrpc.Message)
+			interrupt := make(chan struct{}, 1)
+			var reads atomic.Int32
+			r := &ContextReader{
+				ReadMessage: func() (jsonrpc.Message, error) {
+					reads.Add(1)
+					select {
+					case msg := <-msgs:
+						return msg, nil
+					case <-interrupt:
+						return nil, os.ErrDeadlineExceeded
+					}
+				},
+			}
+			if withDeadline {
+				r.SetReadDeadline = func(d time.Time) error {
+					if !d.IsZero() {
+						interrupt <- struct{}{}
+					}
+					return nil
+				}
+			}
+
+			// A cancelled Read returns promptly, even though ReadMessage is blocked.
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(10*time.Millisecond, cancel)
+			if _, err := r.Read(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Read after cancel: got %v, want context.Canceled", err)
+			}
+
+			// The next Read returns the next message.
+			want := &jsonrpc.Request{Method: "ping"}
+			go func() { msgs <- want }()
+			got, err := r.Read(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("Read returned %v, want %v", got, want)
+			}
+
+			// Without a deadline, the in-flight read was reused rather than
+			// started again.
+			wantReads := int32(1)
+			if withDeadline {
+				wantReads = 2
+			}
+			if got := reads.Load(); got != wantReads {
+				t.Errorf("ReadMessage called %d times, want %d", got, wantReads)
+			}
+		})
+	}
+}
+
+func TestIOTransportIdleTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		const idle = time.Second
+		server := NewServer(testImpl, nil)
+		AddTool(server, &Tool{Name: "slow"}, func(context.Context, *CallToolRequest, any) (*CallToolResult, any, error) {
+			time.Sleep(3 * idle)
+			return &CallToolResult{}, nil, nil
+		})
+		cr, sw := io.Pipe()
+		sr, cw := io.Pipe()
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- server.Run(ctx, &IOTransport{Reader: sr, Writer: sw, IdleTimeout: idle})
+		}()
+		cs, err := NewClient(testImpl, nil).Connect(ctx, &IOTransport{Reader: cr, Writer: cw}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+
+		// A call that lasts longer than the idle timeout keeps the session
+		// alive.
+		if _, err := cs.CallTool(ctx, &CallToolParams{Name: "slow"}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(idle / 2)
+		select {
+		case err := <-runErr:
+			t.Fatalf("Run returned %v before the idle timeout", err)
+		default:
+		}
+
+		// Once idle, the session ends gracefully.
+		time.Sleep(idle)
+		synctest.Wait()
+		select {
+		case err := <-runErr:
+			if err != nil {
+				t.Errorf("Run() = %v, want nil", err)
+			}
+		default:
+			t.Fatal("Run did not return after the idle timeout")
+		}
+	})
+}
+
+func TestIdleTrackerCancelledCalls(t *testing.T) {
+	const idle = time.Second
+	call := func(id int64) *jsonrpc.Request {
+		return &jsonrpc.Request{ID: jsonrpc2.Int64ID(id), Method: "tools/call"}
+	}
+	cancelled := func(id int64) *jsonrpc.Request {
+		return &jsonrpc.Request{Method: notificationCancelled, Params: json.RawMessage(fmt.Sprintf(`{"requestId":%d}`, id))}
+	}
+	expired := func(tr *idleTracker) bool {
+		select {
+		case <-tr.done():
+			return true
+		default:
+			return false
+		}
+	}
+
+	tests := []struct {
+		name     string
+		messages func(tr *idleTracker)
+	}{
+		{"incoming call cancelled", func(tr *idleTracker) {
+			tr.observe(call(1), true)
+			tr.observe(cancelled(1), true)
+		}},
+		{"outgoing call cancelled", func(tr *idleTracker) {
+			tr.observe(call(1), false)
+			tr.observe(cancelled(1), false)
+		}},
+		{"outgoing call not written", func(tr *idleTracker) {
+			tr.observe(call(1), false)
+			tr.forget(call(1))
+		}},
+		{"late response", func(tr *idleTracker) {
+			tr.observe(call(1), false)
+			tr.observe(cancelled(1), false)
+			tr.observe(call(2), false)
+			tr.observe(&jsonrpc.Response{ID: jsonrpc2.Int64ID(1)}, true)
+			tr.observe(&jsonrpc.Response{ID: jsonrpc2.Int64ID(2)}, true)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := newFakeClock()
+			tr := newIdleTracker(clk, idle)
+			test.messages(tr)
+			clk.Advance(idle / 2)
+			if expired(tr) {
+				t.Fatal("connection idle before the timeout")
+			}
+			clk.Advance(idle)
+			if !expired(tr) {
+				t.Error("connection not idle after the timeout")
+			}
+		})
+	}
+
+	// A cancellation in the wrong direction does not end the call.
+	clk := newFakeClock()
+	tr := newIdleTracker(clk, idle)
+	tr.observe(call(1), true)
+	tr.observe(cancelled(1), false)
+	clk.Advance(2 * idle)
+	if expired(tr) {
+		t.Error("connection idle with a call in progress")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcp
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+const sdkModulePath = "github.com/modelcontextprotocol/go-sdk"
+
+// sdkVersion returns the version of this module in the current binary, or
+// "devel" if it is unknown.
+var sdkVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	mod := info.Main
+	for _, dep := range info.Deps {
+		if dep.Path == sdkModulePath {
+			mod = *dep
+			break
+		}
+	}
+	if mod.Path != sdkModulePath || mod.Version == "" || mod.Version == "(devel)" {
+		return "devel"
+	}
+	return mod.Version
+})
+
+// clientInfoContextKey is the context key for the [Implementation] of the
+// client passed to [Transport.Connect] by [Client.Connect]. Client transports
+// that speak HTTP use it in their default User-Agent.
+type clientInfoContextKey struct{}
+
+// userAgent returns the User-Agent header for a client HTTP transport
+// connected with ctx. If ua is non-empty, it is returned unchanged.
+// Otherwise, the result identifies the SDK and, if known, the client
+// implementation, as in "my-client/1.0.0 mcp-go-sdk/v1.2.0".
+func userAgent(ctx context.Context, ua string) string {
+	if ua != "" {
+		return ua
+	}
+	sdk := "mcp-go-sdk/" + sdkVersion()
+	impl, _ := ctx.Value(clientInfoContextKey{}).(*Implementation)
+	if impl == nil || impl.Name == "" {
+		return sdk
+	}
+	product := userAgentToken(impl.Name)
+	if impl.Version != "" {
+		product += "/" + userAgentToken(impl.Version)
+	}
+	return product + " " + sdk
+}
+
+// userAgentToken replaces characters that are not allowed in a User-Agent
+// product token (RFC 9110, section 10.1.5) with '-'.
+func userAgentToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > ' ' && r < 0x7f && !strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return r
+		}
+		return '-'
+	}, s)
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mcptest provides utilities for testing MCP clients and servers.
+package mcptest
+
+import (
+	"context"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// A ScriptedServer is an MCP server that answers requests with canned
+// responses. It is a [mcp.Transport]: connect a client to it with
+// [mcp.Client.Connect].
+//
+// Use it in client tests to control exactly what the server returns, such as
+// the pages of a paginated "tools/list" result, without writing a server with
+// real features.
+type ScriptedServer struct {
+	server *mcp.Server
+}
+
+// NewScriptedServer returns a server that answers requests for each method in
+// responses by calling the corresponding function with the decoded request
+// params, such as a *[mcp.ListToolsParams] for "tools/list". The result must
+// have the type expected by the client for the method, such as a
+// *[mcp.ListToolsResult]. An error is returned to the client as a JSON-RPC
+// error; use a *[github.com/modelcontextprotocol/go-sdk/jsonrpc.Error] to
+// control its code.
+//
+// Requests for other methods are handled by an [mcp.Server] without any
+// features. In particular, the initialization handshake and "ping" work as
+// usual unless scripted. The server advertises the capabilities implied by
+// the scripted methods: for example, scripting any "tools/" method advertises
+// the tools capability.
+//
+// The functions may be called concurrently.
+func NewScriptedServer(responses map[string]func(mcp.Params) (mcp.Result, error)) *ScriptedServer {
+	caps := &mcp.ServerCapabilities{}
+	for method := range responses {
+		switch {
+		case strings.HasPrefix(method, "tools/"):
+			caps.Tools = &mcp.ToolCapabilities{}
+		case strings.HasPrefix(method, "prompts/"):
+			caps.Prompts = &mcp.PromptCapabilities{}
+		case strings.HasPrefix(method, "resources/"):
+			if caps.Resources == nil {
+				caps.Resources = &mcp.ResourceCapabilities{}
+			}
+			if method == "resources/subscribe" {
+				caps.Resources.Subscribe = true
+			}
+		case method == "completion/complete":
+			caps.Completions = &mcp.CompletionCapabilities{}
+		case method == "logging/setLevel":
+			caps.Logging = &mcp.LoggingCapabilities{}
+		}
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcptest", Version: "v1.0.0"}, &mcp.ServerOptions{
+		Capabilities:      caps,
+		ExactCapabilities: true,
+	})
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if respond, ok := responses[method]; ok {
+				return respond(req.GetParams())
+			}
+			return next(ctx, method, req)
+		}
+	})
+	return &ScriptedServer{server: server}
+}
+
+// Connect implements the [mcp.Transport] interface. Each call starts a new
+// session with the server.
+func (s *ScriptedServer) Connect(ctx context.Context) (mcp.Connection, error) {
+	ct, st := mcp.NewInMemoryTransports()
+	if _, err := s.server.Connect(ctx, st, nil); err != nil {
+		return nil, err
+	}
+	return ct.Connect(ctx)
+}
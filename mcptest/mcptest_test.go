@@ -0,0 +1,75 @@
+// Copyright 2026 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mcptest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/modelcontextprotocol/go-sdk/mcptest"
+)
+
+func TestScriptedServer(t *testing.T) {
+	ctx := context.Background()
+	pages := map[string]*mcp.ListToolsResult{
+		"":      {Tools: []*mcp.Tool{{Name: "a"}, {Name: "b"}}, NextCursor: "page2"},
+		"page2": {Tools: []*mcp.Tool{{Name: "c"}}},
+	}
+	server := mcptest.NewScriptedServer(map[string]func(mcp.Params) (mcp.Result, error){
+		"tools/list": func(p mcp.Params) (mcp.Result, error) {
+			res, ok := pages[p.(*mcp.ListToolsParams).Cursor]
+			if !ok {
+				return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: "bad cursor"}
+			}
+			return res, nil
+		},
+		"tools/call": func(p mcp.Params) (mcp.Result, error) {
+			return nil, &jsonrpc.Error{Code: 42, Message: "no calls for " + p.(*mcp.CallToolParamsRaw).Name}
+		},
+	})
+
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, server, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	if !cs.ServerSupportsTools() || cs.ServerSupportsPrompts() {
+		t.Errorf("capabilities = %+v, want tools only", cs.ServerCapabilities())
+	}
+	if err := cs.Ping(ctx, nil); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+
+	var names []string
+	for tool, err := range cs.Tools(ctx, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, tool.Name)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(names, want) {
+		t.Errorf("tools = %v, want %v", names, want)
+	}
+
+	_, err = cs.ListTools(ctx, &mcp.ListToolsParams{Cursor: "nope"})
+	var jerr *jsonrpc.Error
+	if !errors.As(err, &jerr) || jerr.Code != jsonrpc.CodeInvalidParams {
+		t.Errorf("ListTools with bad cursor: got %v, want invalid params error", err)
+	}
+	_, err = cs.CallTool(ctx, &mcp.CallToolParams{Name: "x"})
+	if !errors.As(err, &jerr) || jerr.Code != 42 {
+		t.Errorf("CallTool: got %v, want error with code 42", err)
+	}
+
+	// Unscripted methods are handled by an empty server.
+	if _, err := cs.GetPrompt(ctx, &mcp.GetPromptParams{Name: "p"}); err == nil {
+		t.Error("GetPrompt succeeded unexpectedly")
+	}
+}